@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+
+	"foil/helpers"
 )
 
 func init() {
@@ -16,7 +18,7 @@ var (
 		Short: "Display the list of foil authors",
 		Long:  ``,
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("Authors: Brian Vohaska <bvohaska@gmail.com>")
+			fmt.Fprintln(helpers.Messages, "Authors: Brian Vohaska <bvohaska@gmail.com>")
 		},
 	}
 )
@@ -1,13 +1,17 @@
 package commands
 
 import (
+	"crypto"
+	"crypto/ecdh"
 	"crypto/ecdsa"
-	"crypto/elliptic"
+	"crypto/ed25519"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"foil/cryptospecials"
+	"foil/helpers"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -16,17 +20,23 @@ func init() {
 
 	// Add rsa specific flags
 	ecCmd.PersistentFlags().BoolVarP(&ecGen, "gen", "", false, "generate an ECDSA private & public key")
-	ecCmd.PersistentFlags().BoolVarP(&ecExtract, "pub", "", false, "extract an ECDSA public key from an ECDSA private PEM")
+	ecCmd.PersistentFlags().BoolVarP(&ecExtract, "pub", "", false, "extract an ECDSA public key from an EC, Ed25519, or X25519 private key")
+	ecCmd.PersistentFlags().StringVarP(&curveString, "curve", "", "P-256", "generate the key on curve [P-256, P-384, P-521, ed25519, x25519]")
+	ecCmd.PersistentFlags().BoolVarP(&keyEncrypt, "encrypt", "", false, "protect the generated private key with a passphrase (encrypted PKCS#8, or OpenSSH with --ssh)")
+	ecCmd.PersistentFlags().StringVarP(&keyKDFString, "kdf", "", "pbkdf2", "use [pbkdf2, scrypt] to expand the passphrase of an encrypted private key")
+	ecCmd.PersistentFlags().BoolVarP(&keySSH, "ssh", "", false, "save the key in OpenSSH format; the public key is an authorized_keys line (not X25519)")
+	ecCmd.PersistentFlags().StringVarP(&sshComment, "comment", "", "", "use [string] as the comment of an OpenSSH key")
 }
 
 var (
-	ecExtract bool
-	ecGen     bool
+	ecExtract   bool
+	ecGen       bool
+	curveString string
 
 	ecCmd = &cobra.Command{
 		Use:               "ecgen [--text/out PATH] [operation]",
-		Short:             "Generate an ECDSA private key or extract a public key",
-		Long:              `Generate a P-256 ECDSA private key or extract the public key from a private PEM`,
+		Short:             "Generate an ECDSA, Ed25519, or X25519 private key or extract a public key",
+		Long:              `Generate an ECDSA private key on P-256 (default), P-384, or P-521, an Ed25519 signing key, or an X25519 key agreement key (--curve) or extract the public key from a private PEM. The public key of a generated key is saved beside it as [PATH].pub. Curve25519 keys are saved as PKCS#8 and PKIX PEMs. With --encrypt (or a passphrase flag) the private key is saved as encrypted PKCS#8. With --ssh the private key is saved in OpenSSH format and the public key as an authorized_keys line.`,
 		PersistentPreRunE: ecPreChecks,
 		RunE:              doECC,
 	}
@@ -94,52 +104,132 @@ func genECDSA() error {
 		privKey  *ecdsa.PrivateKey
 	)
 
-	// Define the elliptic curve; this can be changed to a flag
-	ec := elliptic.P256()
+	// Curve25519 keys are not ECDSA keys
+	switch strings.ToLower(curveString) {
+	case "ed25519", "x25519":
+		return genCurve25519()
+	}
 
-	// Generate an ECDSA private key using P-256
+	// Define the elliptic curve with --curve
+	ec, err := cryptospecials.EccCurveByName(curveString)
+	if err != nil {
+		return err
+	}
+
+	// Generate an ECDSA private key on the selected curve
 	privKey, err = cryptospecials.EccPrivKeyGen(ec)
 	if err != nil {
 		return err
 	}
 
-	// Save the ECDSA private and public key as as files and/or print to stdin
+	// Save an OpenSSH or encrypted private key and the public key as [PATH].pub
+	if keySSH {
+		return saveSSHKey(privKey)
+	} else if encryptNewKey() {
+		err = cryptospecials.EccKeySave(privKey, "", publicKeyOf(outputPath))
+		if err != nil {
+			return err
+		}
+		return saveEncryptedKey(privKey)
+	}
+
+	// Save the ECDSA private and public key as files (the public key as [PATH].pub) and/or print to stdin
 	if outputPath != "" {
-		err = cryptospecials.EccKeySave(privKey, outputPath, inputPath+".pub")
+		err = cryptospecials.EccKeySave(privKey, outputPath, outputPath+".pub")
 		if err != nil {
 			return err
 		}
 	}
 	// Print to StdOut if requested
 	if stdOutBool {
-		keyBytes, err = x509.MarshalECPrivateKey(privKey)
+		keyBytes, err = x509.MarshalPKCS8PrivateKey(privKey)
+		if err != nil {
+			return err
+		}
 		keyBytes = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes})
-		fmt.Printf("%s\n", keyBytes)
+		fmt.Fprintf(helpers.Messages, "%s\n", keyBytes)
 	}
 	return nil
 }
 
-func extractECDSAPub() error {
+// Generate an Ed25519 or X25519 private key; saved as PKCS#8 with the public key as PKIX
+func genCurve25519() error {
 
 	var (
-		err     error
-		privKey *ecdsa.PrivateKey
+		keyBytes []byte
+		err      error
+		privKey  crypto.PrivateKey
 	)
 
-	// Load an ECDSA private key
-	privKey, err = cryptospecials.EccPrivKeyLoad(inputPath)
+	if strings.ToLower(curveString) == "ed25519" {
+		privKey, err = cryptospecials.Ed25519KeyGen()
+	} else {
+		privKey, err = cryptospecials.X25519KeyGen()
+	}
 	if err != nil {
 		return err
 	}
 
-	/*
-	*  NOTE: This will overwrite the existing private key with the same key
-	*  Save the ECDSA pub key as a file and/or print to stdin
-	 */
-	err = cryptospecials.EccKeySave(privKey, inputPath, outputPath)
+	if keySSH {
+		return saveSSHKey(privKey)
+	} else if encryptNewKey() {
+		err = cryptospecials.Curve25519KeySave(privKey, "", publicKeyOf(outputPath))
+		if err != nil {
+			return err
+		}
+		return saveEncryptedKey(privKey)
+	}
+	if outputPath != "" {
+		err = cryptospecials.Curve25519KeySave(privKey, outputPath, outputPath+".pub")
+		if err != nil {
+			return err
+		}
+	}
+	if stdOutBool {
+		keyBytes, err = x509.MarshalPKCS8PrivateKey(privKey)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(helpers.Messages, "%s\n", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}))
+	}
+
+	return nil
+}
+
+func extractECDSAPub() error {
+
+	// Load an EC or Curve25519 private key; only its public key is saved
+	key, err := cryptospecials.LoadKey(inputPath)
+	if err != nil {
+		return err
+	}
+	privKey, err := key.PrivateKey()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Rewriting the private key would drop its encryption, so it is left alone
+	if keySSH {
+		return saveSSHPublicKey(privKey)
+	}
+	switch privKey := privKey.(type) {
+	case *ecdsa.PrivateKey:
+		return cryptospecials.EccKeySave(privKey, "", outputPath)
+	case ed25519.PrivateKey:
+		return cryptospecials.Curve25519KeySave(privKey, "", outputPath)
+	case *ecdh.PrivateKey:
+		return cryptospecials.Curve25519KeySave(privKey, "", outputPath)
+	}
+
+	return &cryptospecials.KeyMismatchError{Source: inputPath, Want: "EC, Ed25519, or X25519 private key", Got: key.Describe()}
+}
+
+// The public key of a generated key is saved beside it as [PATH].pub; nothing is saved without --out
+func publicKeyOf(privPath string) string {
+
+	if len(privPath) == 0 {
+		return ""
+	}
+
+	return privPath + ".pub"
 }
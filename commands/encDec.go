@@ -7,8 +7,9 @@
 *
 *
 *  Encryption/Decryption in this application (GCM) was not optimized for large files.
-*  There is no streaming file logic in the AESCore function.
-*  For larger files try replacing cliInputFileLogic io.ReadFile with something more efficent if possible.
+*  There is no streaming file logic in the AESCore function. For larger files use the
+*  --stream flag which seals the input in segments (see helpers/aesstream.go) with
+*  constant memory and no 64GB limit.
 *  NOTE: The iv/nonce will be generated at random for encryption and will be taken as the first 12 BYTES
 *  of the input file for decryption. The encryptor will not check to see if you have enought persistant
 *  storage space in which to sotre the d/encrypted output; make sure you have enough space. Note: The
@@ -19,9 +20,17 @@
 package commands
 
 import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"foil/cryptospecials"
 	"foil/helpers"
+	"io"
+	"io/ioutil"
+	"os"
 
 	"github.com/spf13/cobra"
 )
@@ -30,42 +39,79 @@ func init() {
 
 	// Define flags used by all sub commands
 	aesCmd.PersistentFlags().StringVarP(&keyString, "key", "k", "", "use [hex] as the KEY for AES-GCM")
-	aesCmd.PersistentFlags().StringVarP(&passwordString, "password", "p", "", "use [string] (--> PBKDF2) as  KEY for AES-GCM")
+	aesCmd.PersistentFlags().StringVarP(&passwordString, "password", "p", "", "use [string] (--> KDF) as  KEY for AES-GCM")
+	aesCmd.PersistentFlags().StringVarP(&keyFile, "key-file", "", "", "use the 256-bit KEY (raw or hex) in the file at PATH=[string]")
+	aesCmd.PersistentFlags().StringVarP(&kdfString, "kdf", "", "argon2id", "use [argon2id, scrypt, pbkdf2] to expand --password when encrypting")
+	aesCmd.PersistentFlags().Uint32VarP(&kdfParams.Iterations, "kdf-iterations", "", 0, "use [int] KDF iterations: PBKDF2 iterations, Argon2id time, scrypt N (0 = default)")
+	aesCmd.PersistentFlags().Uint32VarP(&kdfParams.Memory, "kdf-memory", "", 0, "use [int] KDF memory: Argon2id KiB, scrypt r (0 = default)")
+	aesCmd.PersistentFlags().Uint32VarP(&kdfParams.Parallelism, "kdf-parallelism", "", 0, "use [int] KDF parallelism: Argon2id threads, scrypt p (0 = default)")
+	aesCmd.PersistentFlags().StringVarP(&cipherString, "cipher", "", "aes-256-gcm", "use [aes-256-gcm, aes-256-gcm-siv, chacha20-poly1305, xchacha20-poly1305] as the AEAD when encrypting")
+	aesCmd.PersistentFlags().BoolVarP(&streamBool, "stream", "", false, "d/encrypt input in segments with constant memory (STREAM)")
+	aesCmd.PersistentFlags().IntVarP(&chunkSize, "chunk-size", "", helpers.StreamChunkSize, "use [int] BYTES of plaintext per segment with --stream")
 
 	// Define flags used by Encrypt/Decrypt sub commands
 	encryptCmd.PersistentFlags().StringVarP(&adataString, "adata", "", "", "use [string] as ADATA for AES-GCM")
+	encryptCmd.PersistentFlags().StringVarP(&adataFile, "adata-file", "", "", "use the contents of the file at PATH=[string] as ADATA")
+	encryptCmd.PersistentFlags().StringVarP(&adataHex, "adata-hex", "", "", "use [hex] as ADATA")
+	encryptCmd.PersistentFlags().StringVarP(&modeString, "mode", "", "", "alias of --cipher: gcm = aes-256-gcm, gcm-siv = aes-256-gcm-siv (nonce misuse-resistant, RFC 8452)")
+	encryptCmd.PersistentFlags().StringArrayVarP(&recipientPaths, "recipient", "", nil, "wrap a random data KEY for the RSA or EC public key at PATH=[string]; repeat for more recipients")
+	encryptCmd.PersistentFlags().StringVarP(&nonceString, "nonce", "", "", "use [hex] as the NONCE instead of a random one (not with --stream)")
 	decryptCmd.PersistentFlags().StringVarP(&adataString, "adata", "", "", "use [string] as ADATA for AES-GCM")
+	decryptCmd.PersistentFlags().StringVarP(&adataFile, "adata-file", "", "", "use the contents of the file at PATH=[string] as ADATA")
+	decryptCmd.PersistentFlags().StringVarP(&adataHex, "adata-hex", "", "", "use [hex] as ADATA")
+	decryptCmd.PersistentFlags().StringVarP(&privateKeyPath, "private-key", "", "", "unwrap the data KEY with the RSA or EC private key at PATH=[string]")
 
-	// Add encrypt and decrypt to aesCmd
+	// Add encrypt, decrypt, and inspect to aesCmd
 	aesCmd.AddCommand(encryptCmd)
 	aesCmd.AddCommand(decryptCmd)
+	aesCmd.AddCommand(inspectCmd)
 }
 
 var (
 	adataString    string
+	adataFile      string
+	adataHex       string
 	passwordString string
 	keyString      string
+	keyFile        string
+	recipientPaths []string
+	privateKeyPath string
+	streamBool     bool
+	chunkSize      int
+	kdfString      string
+	kdfParams      helpers.KDFParams
+	cipherString   string
+	modeString     string
+	nonceString    string
 
 	aesCmd = &cobra.Command{
 		Use:               "aes",
-		Short:             "Encrypt or decrypt input with AES-256-GCM",
-		Long:              ``,
+		Short:             "Encrypt or decrypt input with AES-256-GCM or (X)ChaCha20-Poly1305",
+		Long:              `Encrypt or decrypt input with an AEAD. AES-256-GCM is the default; --cipher selects AES-256-GCM-SIV (nonce misuse-resistant), ChaCha20-Poly1305, or XChaCha20-Poly1305 (192-bit random nonces) instead; 'enc --mode gcm-siv' is an alias of --cipher aes-256-gcm-siv. The cipher is recorded in the container header so decryption needs no --cipher flag.`,
 		PersistentPreRunE: aesPreChecks,
 	}
 
 	encryptCmd = &cobra.Command{
 		Use:   "enc [IN] [OUT]",
-		Short: "Encrypt input with AES-256-GCM",
+		Short: "Encrypt input with the AEAD selected by --cipher",
 		Long:  ``,
 		RunE:  enc,
 	}
 
 	decryptCmd = &cobra.Command{
 		Use:   "dec [KEY] [IN] [OUT]",
-		Short: "Decrypt input with AES-256-GCM",
+		Short: "Decrypt input with the AEAD named in the container header",
 		Long:  ``,
 		RunE:  dec,
 	}
+
+	inspectCmd = &cobra.Command{
+		Use:               "inspect [--in PATH]",
+		Short:             "Display the header of a foil container; no key required",
+		Long:              `Display the cipher, KDF, salt, nonce, and segment settings stored in the header of a foil container.`,
+		PersistentPreRunE: inspectPreChecks,
+		RunE:              inspect,
+	}
 )
 
 /*
@@ -79,13 +125,32 @@ func aesPreChecks(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Ensure the segment size is sane if streaming was selected
+	if streamBool && (chunkSize <= 0 || chunkSize > helpers.MaxStreamChunkSize) {
+		return fmt.Errorf("Error: --chunk-size must be between 1 and %d", helpers.MaxStreamChunkSize)
+	}
+
+	return nil
+}
+
+// Inspect only requires an input; no key material or output method is needed
+func inspectPreChecks(cmd *cobra.Command, args []string) error {
+
+	if len(stdInString) > 0 && len(inputPath) > 0 {
+		return errors.New("Error: Too many sources for input; select only one")
+	} else if len(stdInString) == 0 && len(inputPath) == 0 {
+		return errors.New("Error: Must specify an input method")
+	}
+
 	return nil
 }
 
 /*
 *  Perform all required boilerplate operations for AES-256-GCM encryption. This is the main
 *  functional component of the AES operations. symmetricBoilerPlate combines functions
-*  from helpers.go into a form where encryption and decryption can be performed.
+*  from helpers.go into a form where encryption and decryption can be performed. New
+*  ciphertext is always written as a foil container; input without a container header is
+*  decrypted using the legacy (IV || ciphertext) format.
  */
 func symmetricBoilerPlate(operation *string) error {
 
@@ -94,11 +159,57 @@ func symmetricBoilerPlate(operation *string) error {
 		key        []byte
 	)
 
-	// Determine where the input file will be read from. Set the IV from input.
-	iv, inputText, encSuccess := helpers.CliInputFileLogic(&stdInString, &inputPath, operation, Verbose)
-	if !encSuccess {
-		return errors.New("There was a file read or StdIn error. Terminating execution")
+	// Read the key from --key-file so that it never appears on the command line
+	if len(keyFile) > 0 {
+		key, err := helpers.ReadKeyFile(keyFile, Verbose)
+		if err != nil {
+			return err
+		}
+		keyString = hex.EncodeToString(key)
+	}
+
+	// Resolve --adata, --adata-file, or --adata-hex into the raw ADATA BYTES
+	adata, err := helpers.CliAdataLogic(&adataString, &adataFile, &adataHex, Verbose)
+	if err != nil {
+		return err
+	}
+	adataString = adata
+
+	// Open the input once; StdIn cannot be rewound after peeking at the header
+	input, err := helpers.CliStreamInputLogic(&stdInString, &inputPath, operation, Verbose)
+	if err != nil {
+		return err
 	}
+	defer input.Close()
+	src := bufio.NewReader(input)
+
+	// Containers describe themselves; everything else is treated as the legacy format
+	if *operation == "encrypt" {
+		return containerBoilerPlate(operation, src)
+	}
+	magic, _ := src.Peek(len(helpers.ContainerMagic))
+	if bytes.Equal(magic, helpers.ContainerMagic) {
+		return containerBoilerPlate(operation, src)
+	}
+	if Verbose {
+		fmt.Fprintln(helpers.Messages, "Input is not a foil container; using the legacy format")
+	}
+
+	// Hand off to the streaming logic if requested; the input is never read into memory
+	if streamBool {
+		return streamBoilerPlate(operation, src)
+	}
+
+	// Read the legacy input into memory. The IV/nonce is the first 12 BYTES.
+	inputText, err := ioutil.ReadAll(io.LimitReader(src, helpers.MaxFileSize+1))
+	if err != nil {
+		return fmt.Errorf("There was a file read or StdIn error. Terminating execution: %v", err)
+	} else if len(inputText) > helpers.MaxFileSize {
+		return errors.New("Error: Input larger than 64GB; use --stream")
+	} else if len(inputText) < 12 {
+		return errors.New("Error: Input is too short to contain an IV/nonce")
+	}
+	iv := inputText[:12]
 
 	// Determine whether to accept password, key (hex), or generate a random value
 	key, encSuccess = helpers.CliKeyLogic(&passwordString, &keyString, operation, Verbose)
@@ -121,6 +232,241 @@ func symmetricBoilerPlate(operation *string) error {
 	return nil
 }
 
+// Remove a partially written output file after a failure
+func discardOutput() {
+
+	if len(outputPath) > 0 && outputPath != helpers.StdIOPath {
+		os.Remove(outputPath)
+	}
+}
+
+// Build the header of a new container from the flags given to 'aes enc'
+func newContainerHeader() (*helpers.Header, error) {
+
+	var (
+		size int
+	)
+
+	if streamBool {
+		size = chunkSize
+	}
+
+	// --mode is an alias of --cipher for the AES ciphers; only one of them may be given
+	if len(modeString) > 0 {
+		if aesCmd.PersistentFlags().Changed("cipher") {
+			return nil, errors.New("Error: --mode is an alias of --cipher; give only one of them")
+		}
+		switch modeString {
+		case "gcm", "gcm-siv":
+			cipherString = "aes-256-" + modeString
+		default:
+			return nil, fmt.Errorf("Error: Unknown mode \"%s\"; choose gcm or gcm-siv", modeString)
+		}
+	}
+	cipherID, err := helpers.CipherFromName(cipherString)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := helpers.NewHeader(cipherID, size, Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	// An externally supplied nonce is only safe to repeat with GCM-SIV
+	if len(nonceString) > 0 {
+		nonce, err := hex.DecodeString(nonceString)
+		if err != nil {
+			return nil, errors.New("Error: --nonce is not valid hex")
+		}
+		err = header.SetNonce(nonce)
+		if err != nil {
+			return nil, err
+		}
+		if cipherID != helpers.CipherAES256GCMSIV {
+			fmt.Fprintln(helpers.Messages, "WARNING: Reusing a nonce with "+helpers.CipherName(cipherID)+" is catastrophic; consider --cipher aes-256-gcm-siv")
+		}
+	}
+	if len(passwordString) > 0 {
+		kdf, err := helpers.KDFFromName(kdfString)
+		if err != nil {
+			return nil, err
+		}
+		err = header.SetPasswordKDF(kdf, kdfParams, Verbose)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return header, nil
+}
+
+/*
+*  Perform all required boilerplate operations for a foil container. When encrypting, the
+*  header is built from the flags given. When decrypting, the header is read from src
+*  and decides the cipher, KDF, and segment settings.
+ */
+func containerBoilerPlate(operation *string, src io.Reader) error {
+
+	var (
+		encSuccess bool
+		err        error
+		key        []byte
+		rawHeader  []byte
+		header     *helpers.Header
+	)
+
+	if *operation == "encrypt" {
+		header, err = newContainerHeader()
+	} else {
+		header, rawHeader, err = helpers.ReadHeader(src)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Determine the key from recipients (envelope), the password (and header KDF), key (hex), or a random value
+	if len(recipientPaths) > 0 || len(privateKeyPath) > 0 || len(header.Recipients) > 0 {
+		key, err = envelopeKey(header, operation)
+		if err != nil {
+			return err
+		}
+	} else {
+		key, encSuccess = helpers.CliHeaderKeyLogic(header, &passwordString, &keyString, operation, Verbose)
+		if !encSuccess {
+			return errors.New("There was a password error. Terminating execution")
+		}
+	}
+
+	dst, err := helpers.CliStreamOutputLogic(&stdOutBool, &outputPath, operation, Verbose)
+	if err != nil {
+		return err
+	}
+	if *operation == "encrypt" {
+		err = helpers.ContainerEncrypt(key, header, &adataString, dst, src, Verbose)
+	} else {
+		err = helpers.ContainerDecrypt(key, header, rawHeader, &adataString, dst, src, Verbose)
+	}
+	if err != nil {
+		dst.Close()
+		discardOutput()
+		return fmt.Errorf("There was an AES error. Terminating execution: %v", err)
+	}
+
+	err = dst.Close()
+	if err != nil {
+		return fmt.Errorf("There was a file write or StdOut error. Terminating execution: %v", err)
+	}
+
+	return nil
+}
+
+/*
+*  Determine the key of an envelope container. When encrypting, a random data key is
+*  generated and wrapped for every --recipient public key. When decrypting, the data key
+*  is unwrapped from the header with the --private-key.
+ */
+func envelopeKey(header *helpers.Header, operation *string) ([]byte, error) {
+
+	if *operation == "encrypt" {
+		dataKey := make([]byte, 32)
+		err := helpers.GetAESRandomBytes(dataKey, Verbose)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range recipientPaths {
+			pubKey, err := loadPublicKey(path)
+			if err != nil {
+				return nil, err
+			}
+			err = header.AddRecipient(dataKey, pubKey, Verbose)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return dataKey, nil
+	}
+
+	if len(header.Recipients) == 0 {
+		return nil, errors.New("Error: The container has no recipients; decrypt with --key or --password")
+	} else if len(privateKeyPath) == 0 {
+		return nil, errors.New("Error: The container is encrypted for recipients; supply --private-key")
+	}
+	privKey, err := loadPrivateKey(privateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return header.OpenRecipients(privKey, Verbose)
+}
+
+// Load an RSA, EC, X25519, or Ed25519 public key (or the public half of a private key) with cryptospecials
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+
+	key, err := cryptospecials.LoadKey(path)
+	if err != nil {
+		return nil, err
+	}
+	if Verbose {
+		fmt.Fprintf(helpers.Messages, "Loaded an %s (%s) from %s\n", key.Describe(), key.Format, path)
+	}
+
+	return key.Public, nil
+}
+
+// Load an RSA, EC, X25519, or Ed25519 private key with cryptospecials
+func loadPrivateKey(path string) (crypto.PrivateKey, error) {
+
+	key, err := cryptospecials.LoadKey(path)
+	if err != nil {
+		return nil, err
+	}
+	if Verbose {
+		fmt.Fprintf(helpers.Messages, "Loaded an %s (%s) from %s\n", key.Describe(), key.Format, path)
+	}
+
+	return key.PrivateKey()
+}
+
+/*
+*  Perform all required boilerplate operations for legacy (headerless) streaming AES-256-GCM.
+*  The output is opened as a stream and handed to helpers.StreamCore along with src.
+ */
+func streamBoilerPlate(operation *string, src io.Reader) error {
+
+	var (
+		encSuccess bool
+		key        []byte
+	)
+
+	// Determine whether to accept password, key (hex), or generate a random value
+	key, encSuccess = helpers.CliKeyLogic(&passwordString, &keyString, operation, Verbose)
+	if !encSuccess {
+		return errors.New("There was a password error. Terminating execution")
+	}
+
+	// Open the output destination as a stream
+	dst, err := helpers.CliStreamOutputLogic(&stdOutBool, &outputPath, operation, Verbose)
+	if err != nil {
+		return err
+	}
+
+	// Perform the encryption or decryption operation segment by segment
+	err = helpers.StreamCore(key, &adataString, chunkSize, dst, src, operation, Verbose)
+	if err != nil {
+		dst.Close()
+		discardOutput()
+		return fmt.Errorf("There was an AES error. Terminating execution: %v", err)
+	}
+
+	err = dst.Close()
+	if err != nil {
+		return fmt.Errorf("There was a file write or StdOut error. Terminating execution: %v", err)
+	}
+
+	return nil
+}
+
 // Perform AES-256-GCM encryption
 func enc(cmd *cobra.Command, args []string) error {
 
@@ -132,16 +478,14 @@ func enc(cmd *cobra.Command, args []string) error {
 
 	// Provide Addtional flag checks
 	// Warn user if encrypting but not providing key material
-	if len(passwordString) == 0 && len(keyString) == 0 {
-		fmt.Println("WARNING: No key specified; A randomly generated key will be used")
+	if len(passwordString) == 0 && len(keyString) == 0 && len(keyFile) == 0 && len(recipientPaths) == 0 {
+		fmt.Fprintln(helpers.Messages, "WARNING: No key specified; A randomly generated key will be used")
 	}
 
-	symmetricBoilerPlate(&operation)
-
-	return nil
+	return symmetricBoilerPlate(&operation)
 }
 
-// Perform AES-256-GCM encryption
+// Perform AES-256-GCM decryption
 func dec(cmd *cobra.Command, args []string) error {
 
 	var (
@@ -151,11 +495,37 @@ func dec(cmd *cobra.Command, args []string) error {
 	operation = "decrypt"
 
 	// Provide Addtional flag checks
-	if len(passwordString) == 0 && len(keyString) == 0 {
+	if len(passwordString) == 0 && len(keyString) == 0 && len(keyFile) == 0 && len(privateKeyPath) == 0 {
 		return errors.New("Error: No key specified")
 	}
 
-	symmetricBoilerPlate(&operation)
+	return symmetricBoilerPlate(&operation)
+}
+
+// Display the header of a foil container
+func inspect(cmd *cobra.Command, args []string) error {
+
+	var (
+		operation string
+	)
+
+	// Treat the input as ciphertext; i.e. --textin is hex
+	operation = "decrypt"
+
+	src, err := helpers.CliStreamInputLogic(&stdInString, &inputPath, &operation, Verbose)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	header, rawHeader, err := helpers.ReadHeader(src)
+	if err != nil {
+		return err
+	}
+	if Verbose {
+		fmt.Fprintf(helpers.Messages, "Header (hex): %x\n", rawHeader)
+	}
+	fmt.Fprint(helpers.Messages, header.String())
 
 	return nil
 }
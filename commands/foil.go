@@ -7,11 +7,13 @@ import (
 func init() {
 	// List of all top-level flags used in Foil
 
-	FoilCmd.PersistentFlags().StringVarP(&inputPath, "in", "", "", "read input as file from PATH=[string]")
-	FoilCmd.PersistentFlags().StringVarP(&outputPath, "out", "", "", "save output as file located at PATH=[string]")
+	FoilCmd.PersistentFlags().StringVarP(&inputPath, "in", "", "", "read input as file from PATH=[string]; \"-\" reads raw BYTES from StdIn and writes messages to StdErr")
+	FoilCmd.PersistentFlags().StringVarP(&outputPath, "out", "", "", "save output as file located at PATH=[string]; \"-\" writes raw BYTES to StdOut and messages to StdErr")
 	FoilCmd.PersistentFlags().StringVarP(&stdInString, "textin", "", "", "read input from StdIn as [string]")
 	FoilCmd.PersistentFlags().BoolVarP(&Verbose, "verbose", "v", false, "display verbose output")
 	FoilCmd.PersistentFlags().BoolVarP(&stdOutBool, "textout", "", false, "display output on StdOut")
+	FoilCmd.PersistentFlags().StringVarP(&passphraseString, "passphrase", "", "", "use [string] as the passphrase of encrypted private key PEMs (prompted for when not given)")
+	FoilCmd.PersistentFlags().StringVarP(&passphraseFile, "passphrase-file", "", "", "read the passphrase of encrypted private key PEMs from the first line of the file at PATH=[string]")
 
 	// Add static  commands
 	FoilCmd.AddCommand(versionCmd)
@@ -23,6 +25,13 @@ func init() {
 	FoilCmd.AddCommand(ecCmd)
 	FoilCmd.AddCommand(vrfCmd)
 	FoilCmd.AddCommand(oprfCmd)
+	FoilCmd.AddCommand(sealCmd)
+	FoilCmd.AddCommand(openCmd)
+	FoilCmd.AddCommand(hpkeCmd)
+	FoilCmd.AddCommand(signCmd)
+	FoilCmd.AddCommand(verifyCmd)
+	FoilCmd.AddCommand(keyCmd)
+	FoilCmd.AddCommand(x509Cmd)
 
 	// Suppress Cobra internal error reporting in favor of Foil errors
 	FoilCmd.SilenceErrors = true
@@ -0,0 +1,285 @@
+package commands
+
+import (
+	"crypto/ecdh"
+	"errors"
+	"fmt"
+	"foil/cryptospecials"
+	"foil/helpers"
+	"foil/hpke"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+
+	// Define flags used by all hpke sub commands
+	hpkeCmd.PersistentFlags().StringVarP(&hpkeAEADString, "aead", "", "aes-256-gcm", "use [aes-128-gcm, aes-256-gcm, chacha20-poly1305] as the HPKE AEAD")
+	hpkeCmd.PersistentFlags().StringVarP(&hpkeKDFString, "kdf", "", "", "use [hkdf-sha256, hkdf-sha384, hkdf-sha512] as the HPKE KDF (default: the hash of the KEM)")
+	hpkeCmd.PersistentFlags().StringVarP(&hpkeInfoString, "info", "", "", "use [string] as the HPKE info (application context)")
+	hpkeCmd.PersistentFlags().StringVarP(&hpkeSenderPath, "sender-key", "", "", "use auth mode with the sender EC or X25519 key at PATH=[string]; private to seal, public to open")
+
+	// Define flags used by the seal/open sub commands
+	hpkeSealCmd.PersistentFlags().StringVarP(&hpkeRecipientPath, "recipient", "r", "", "encrypt to the EC or X25519 public key at PATH=[string]")
+	hpkeSealCmd.PersistentFlags().StringVarP(&adataString, "adata", "", "", "use [string] as ADATA")
+	hpkeSealCmd.PersistentFlags().StringVarP(&adataFile, "adata-file", "", "", "use the contents of the file at PATH=[string] as ADATA")
+	hpkeSealCmd.PersistentFlags().StringVarP(&adataHex, "adata-hex", "", "", "use [hex] as ADATA")
+	hpkeOpenCmd.PersistentFlags().StringVarP(&privateKeyPath, "private-key", "", "", "decrypt with the EC or X25519 private key at PATH=[string]")
+	hpkeOpenCmd.PersistentFlags().StringVarP(&adataString, "adata", "", "", "use [string] as ADATA")
+	hpkeOpenCmd.PersistentFlags().StringVarP(&adataFile, "adata-file", "", "", "use the contents of the file at PATH=[string] as ADATA")
+	hpkeOpenCmd.PersistentFlags().StringVarP(&adataHex, "adata-hex", "", "", "use [hex] as ADATA")
+
+	// Add seal and open to hpkeCmd
+	hpkeCmd.AddCommand(hpkeSealCmd)
+	hpkeCmd.AddCommand(hpkeOpenCmd)
+}
+
+var (
+	hpkeAEADString    string
+	hpkeKDFString     string
+	hpkeInfoString    string
+	hpkeSenderPath    string
+	hpkeRecipientPath string
+
+	hpkeCmd = &cobra.Command{
+		Use:   "hpke",
+		Short: "Encrypt or decrypt input with HPKE (RFC 9180) and EC or X25519 keys from 'foil ecgen'",
+		Long: "Encrypt or decrypt a single message with Hybrid Public Key Encryption (RFC 9180). The KEM" +
+			"\nis DHKEM on the curve of the key (P-256, P-384, P-521, or X25519); --kdf and --aead select the" +
+			"\nrest of the ciphersuite. The output is the encapsulated key (enc) followed by the" +
+			"\nciphertext, so any RFC 9180 implementation configured with the same ciphersuite, info," +
+			"\nand ADATA can open it. --sender-key selects auth mode.",
+	}
+
+	hpkeSealCmd = &cobra.Command{
+		Use:               "seal [--recipient PEM] [--in PATH] [--out PATH]",
+		Short:             "Encrypt input to an EC or X25519 public key; the output is enc || ciphertext",
+		Long:              ``,
+		PersistentPreRunE: hpkeSealPreChecks,
+		RunE:              hpkeSeal,
+	}
+
+	hpkeOpenCmd = &cobra.Command{
+		Use:               "open [--private-key PEM] [--in PATH] [--out PATH]",
+		Short:             "Decrypt the output of 'foil hpke seal' with the EC or X25519 private key",
+		Long:              ``,
+		PersistentPreRunE: hpkeOpenPreChecks,
+		RunE:              hpkeOpen,
+	}
+)
+
+// HPKE seal requires a recipient public key plus the standard input and output checks
+func hpkeSealPreChecks(cmd *cobra.Command, args []string) error {
+
+	err := stdChecks(0, 0, cmd, args)
+	if err != nil {
+		return err
+	}
+	if len(hpkeRecipientPath) == 0 {
+		return errors.New("Error: Specify a recipient public key (--recipient [path to PEM])")
+	}
+
+	return nil
+}
+
+// HPKE open requires a private key plus the standard input and output checks
+func hpkeOpenPreChecks(cmd *cobra.Command, args []string) error {
+
+	err := stdChecks(0, 0, cmd, args)
+	if err != nil {
+		return err
+	}
+	if len(privateKeyPath) == 0 {
+		return errors.New("Error: Specify a private key (--private-key [path to PEM])")
+	}
+
+	return nil
+}
+
+// Load an EC or X25519 public key (from 'foil ecgen') as an ecdh key
+func loadHPKEPublicKey(path string) (*ecdh.PublicKey, error) {
+
+	key, err := cryptospecials.LoadKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.ECDHPublic()
+}
+
+// Load an EC or X25519 private key (from 'foil ecgen') as an ecdh key
+func loadHPKEPrivateKey(path string) (*ecdh.PrivateKey, error) {
+
+	key, err := cryptospecials.LoadKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.ECDHPrivate()
+}
+
+// Build the ciphersuite for a curve from the --kdf and --aead flags
+func hpkeSuite(curve ecdh.Curve) (*hpke.Suite, error) {
+
+	kemID, err := hpke.KEMForCurve(curve)
+	if err != nil {
+		return nil, err
+	}
+	kdfID := map[uint16]uint16{
+		hpke.KEMP256HKDFSHA256:   hpke.KDFHKDFSHA256,
+		hpke.KEMP384HKDFSHA384:   hpke.KDFHKDFSHA384,
+		hpke.KEMP521HKDFSHA512:   hpke.KDFHKDFSHA512,
+		hpke.KEMX25519HKDFSHA256: hpke.KDFHKDFSHA256,
+	}[kemID]
+	if len(hpkeKDFString) > 0 {
+		kdfID, err = hpke.KDFFromName(hpkeKDFString)
+		if err != nil {
+			return nil, err
+		}
+	}
+	aeadID, err := hpke.AEADFromName(hpkeAEADString)
+	if err != nil {
+		return nil, err
+	}
+	suite, err := hpke.NewSuite(kemID, kdfID, aeadID)
+	if err != nil {
+		return nil, err
+	}
+	if Verbose {
+		fmt.Fprintln(helpers.Messages, "HPKE ciphersuite:", suite)
+	}
+
+	return suite, nil
+}
+
+/*
+*  Read the whole input and the ADATA. HPKE messages are single-shot so the input is held in
+*  memory; use 'foil seal' for large files.
+ */
+func hpkeInput(operation *string) ([]byte, []byte, error) {
+
+	adata, err := helpers.CliAdataLogic(&adataString, &adataFile, &adataHex, Verbose)
+	if err != nil {
+		return nil, nil, err
+	}
+	src, err := helpers.CliStreamInputLogic(&stdInString, &inputPath, operation, Verbose)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer src.Close()
+	input, err := ioutil.ReadAll(src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error: Reading input: %v", err)
+	}
+
+	return input, []byte(adata), nil
+}
+
+// Seal the input to the recipient in base mode, or in auth mode with --sender-key
+func hpkeSeal(cmd *cobra.Command, args []string) error {
+
+	var (
+		operation string
+		enc       []byte
+		ctx       *hpke.Context
+	)
+
+	operation = "encrypt"
+
+	pkR, err := loadHPKEPublicKey(hpkeRecipientPath)
+	if err != nil {
+		return err
+	}
+	suite, err := hpkeSuite(pkR.Curve())
+	if err != nil {
+		return err
+	}
+	plainText, adata, err := hpkeInput(&operation)
+	if err != nil {
+		return err
+	}
+
+	if len(hpkeSenderPath) > 0 {
+		skS, err := loadHPKEPrivateKey(hpkeSenderPath)
+		if err != nil {
+			return err
+		}
+		enc, ctx, err = suite.SetupAuthS(pkR, []byte(hpkeInfoString), skS)
+		if err != nil {
+			return err
+		}
+	} else {
+		enc, ctx, err = suite.SetupBaseS(pkR, []byte(hpkeInfoString))
+		if err != nil {
+			return err
+		}
+	}
+	if Verbose {
+		fmt.Fprintf(helpers.Messages, "HPKE encapsulated key (hex): %x\n", enc)
+	}
+	cipherText, err := ctx.Seal(adata, plainText)
+	if err != nil {
+		return err
+	}
+
+	if !helpers.CliOutputFileLogic(append(enc, cipherText...), &stdOutBool, &outputPath, &operation, Verbose) {
+		return errors.New("Error: Unable to write the HPKE output")
+	}
+
+	return nil
+}
+
+// Open enc || ciphertext with the private key; --sender-key must name the sender public key in auth mode
+func hpkeOpen(cmd *cobra.Command, args []string) error {
+
+	var (
+		operation string
+		ctx       *hpke.Context
+	)
+
+	operation = "decrypt"
+
+	skR, err := loadHPKEPrivateKey(privateKeyPath)
+	if err != nil {
+		return err
+	}
+	suite, err := hpkeSuite(skR.Curve())
+	if err != nil {
+		return err
+	}
+	input, adata, err := hpkeInput(&operation)
+	if err != nil {
+		return err
+	}
+	if len(input) < suite.EncSize() {
+		return errors.New("Error: The input is too short to hold an HPKE encapsulated key")
+	}
+	enc, cipherText := input[:suite.EncSize()], input[suite.EncSize():]
+
+	if len(hpkeSenderPath) > 0 {
+		pkS, err := loadHPKEPublicKey(hpkeSenderPath)
+		if err != nil {
+			return err
+		}
+		ctx, err = suite.SetupAuthR(enc, skR, []byte(hpkeInfoString), pkS)
+		if err != nil {
+			return err
+		}
+	} else {
+		ctx, err = suite.SetupBaseR(enc, skR, []byte(hpkeInfoString))
+		if err != nil {
+			return err
+		}
+	}
+	plainText, err := ctx.Open(adata, cipherText)
+	if err != nil {
+		return err
+	}
+
+	if !helpers.CliOutputFileLogic(plainText, &stdOutBool, &outputPath, &operation, Verbose) {
+		return errors.New("Error: Unable to write the HPKE output")
+	}
+
+	return nil
+}
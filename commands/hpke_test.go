@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"foil/cryptospecials"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+/*
+*  Seal to P-256 and P-521 keys written by 'foil ecgen' in base and auth mode and ensure
+*  that the output opens with the matching keys but not with the wrong sender or info.
+ */
+func TestHPKESealOpen(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "foil-hpke")
+	if err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() {
+		inputPath, outputPath, privateKeyPath, hpkeRecipientPath = "", "", "", ""
+		hpkeSenderPath, hpkeInfoString, hpkeAEADString, adataString = "", "", "aes-256-gcm", ""
+	}()
+	Verbose, stdInString, stdOutBool, adataFile, adataHex = false, "", false, "", ""
+
+	input := []byte("Attack at dawn!")
+	ioutil.WriteFile(dir+"/plain", input, 0600)
+
+	for _, ec := range []elliptic.Curve{elliptic.P256(), elliptic.P521()} {
+		keys := map[string]string{}
+		for _, who := range []string{"recipient", "sender", "other"} {
+			priv, pub := dir+"/"+who+".pem", dir+"/"+who+"_pub.pem"
+			ecKey, _ := cryptospecials.EccPrivKeyGen(ec)
+			cryptospecials.EccKeySave(ecKey, priv, pub)
+			keys[who], keys[who+"_pub"] = priv, pub
+		}
+
+		for _, auth := range []bool{false, true} {
+			hpkeSenderPath, hpkeInfoString, hpkeAEADString, adataString = "", "foil test", "chacha20-poly1305", "adata"
+			if auth {
+				hpkeSenderPath = keys["sender"]
+			}
+			inputPath, outputPath, hpkeRecipientPath = dir+"/plain", dir+"/sealed", keys["recipient_pub"]
+			err = hpkeSeal(nil, nil)
+			if err != nil {
+				t.Fatalf("FAIL - hpkeSeal (%s, auth %v): %v", ec.Params().Name, auth, err)
+			}
+
+			if auth {
+				hpkeSenderPath = keys["sender_pub"]
+			}
+			inputPath, outputPath, privateKeyPath = dir+"/sealed", dir+"/opened", keys["recipient"]
+			err = hpkeOpen(nil, nil)
+			opened, _ := ioutil.ReadFile(dir + "/opened")
+			if err != nil || !bytes.Equal(opened, input) {
+				t.Errorf("FAIL - hpkeOpen (%s, auth %v): %v", ec.Params().Name, auth, err)
+			}
+
+			hpkeInfoString = "other"
+			if hpkeOpen(nil, nil) == nil {
+				t.Errorf("FAIL - Opened with the wrong info (%s, auth %v)", ec.Params().Name, auth)
+			}
+			hpkeInfoString = "foil test"
+			if auth {
+				hpkeSenderPath = keys["other_pub"]
+				if hpkeOpen(nil, nil) == nil {
+					t.Errorf("FAIL - Opened with the wrong sender (%s)", ec.Params().Name)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"foil/cryptospecials"
+	"foil/helpers"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+
+	// Define flags used by the convert sub command
+	keyConvertCmd.PersistentFlags().StringVarP(&keyToString, "to", "", cryptospecials.KeyEncodingPEM, "write the key as [pem, der, jwk, jwks, openssh]")
+	keyConvertCmd.PersistentFlags().BoolVarP(&keyPublicOnly, "public", "", false, "write only the public key of a private key")
+	keyConvertCmd.PersistentFlags().BoolVarP(&keyEncrypt, "encrypt", "", false, "protect the private key with a passphrase (encrypted PKCS#8 or OpenSSH; --to pem or openssh only)")
+	keyConvertCmd.PersistentFlags().StringVarP(&keyKDFString, "kdf", "", "pbkdf2", "use [pbkdf2, scrypt] to expand the passphrase of an encrypted private key")
+	keyConvertCmd.PersistentFlags().StringVarP(&sshComment, "comment", "", "", "use [string] as the comment of an OpenSSH key")
+
+	// Add convert and info to keyCmd
+	keyCmd.AddCommand(keyConvertCmd)
+	keyCmd.AddCommand(keyInfoCmd)
+}
+
+var (
+	keyToString   string
+	keyPublicOnly bool
+
+	keyCmd = &cobra.Command{
+		Use:   "key",
+		Short: "Convert or inspect RSA, EC, Ed25519, and X25519 keys",
+		Long: "Work with keys from 'foil rsagen', 'foil ecgen', OpenSSL, ssh-keygen, or a JWK. Keys are read" +
+			"\nfrom PKCS#1, PKCS#8, SEC1, PKIX, OpenSSH, or JWK inputs; the key type is found from the contents.",
+	}
+
+	keyConvertCmd = &cobra.Command{
+		Use:   "convert [--in KEY] [--to FORMAT] [--text/out PATH]",
+		Short: "Convert a key to PEM, DER, JWK, a JWK Set, or OpenSSH",
+		Long: "Convert a key to PKCS#8 or PKIX (--to pem or der), a JWK, or a JWK Set (--to jwk or jwks)," +
+			"\nor an OpenSSH private key or authorized_keys line (--to openssh)." +
+			"\nThe kid of a JWK is its RFC 7638 thumbprint. Private keys stay private unless --public is" +
+			"\ngiven and are saved readable by their owner only (0600).",
+		PersistentPreRunE: keyConvertPreChecks,
+		RunE:              keyConvert,
+	}
+
+	keyInfoCmd = &cobra.Command{
+		Use:   "info [--in KEY]",
+		Short: "Show the algorithm, size, public key, and fingerprints of a key",
+		Long: "Show what a key is: private or public, the algorithm, the RSA size or EC curve, the modulus" +
+			"\nor public point, and its fingerprints. The SPKI SHA-256 fingerprint is the SHA-256 of the" +
+			"\nDER public key (as 'openssl pkey -pubout -outform DER | sha256sum' prints) and is the" +
+			"\nfingerprint that tags 'foil vrf' output.",
+		PersistentPreRunE: keyInfoPreChecks,
+		RunE:              keyInfo,
+	}
+)
+
+// Convert requires the standard input and output checks; --encrypt only applies to private PEMs
+func keyConvertPreChecks(cmd *cobra.Command, args []string) error {
+
+	err := stdChecks(0, 0, cmd, args)
+	if err != nil {
+		return err
+	}
+	if keyEncrypt && keyToString != cryptospecials.KeyEncodingPEM && keyToString != cryptospecials.KeyEncodingOpenSSH {
+		return errors.New("Error: --encrypt is only available with --to pem or --to openssh")
+	} else if keyEncrypt && keyPublicOnly {
+		return errors.New("Error: --encrypt protects private keys; it can not be used with --public")
+	}
+
+	return nil
+}
+
+// Info prints to StdOut so it only checks the input flags
+func keyInfoPreChecks(cmd *cobra.Command, args []string) error {
+
+	if len(args) > 0 {
+		return errors.New("Error: Too many arguments")
+	} else if len(stdInString) > 0 && len(inputPath) > 0 {
+		return errors.New("Error: Too many sources for input; select only one")
+	} else if len(stdInString) == 0 && len(inputPath) == 0 {
+		return errors.New("Error: Must specify an input method")
+	} else if len(outputPath) > 0 {
+		return errors.New("Error: Key information is written to StdOut; omit --out")
+	}
+
+	return nil
+}
+
+// Read the key from --in or --textin with the cryptospecials loader
+func loadInputKey() (*cryptospecials.Key, error) {
+
+	if len(stdInString) > 0 {
+		return cryptospecials.ParseKey([]byte(stdInString), "StdIn")
+	}
+
+	return cryptospecials.LoadKey(inputPath)
+}
+
+// Convert the input key and write it to --out or StdOut
+func keyConvert(cmd *cobra.Command, args []string) error {
+
+	var (
+		encoded    []byte
+		passphrase []byte
+	)
+
+	key, err := loadInputKey()
+	if err != nil {
+		return err
+	}
+	if Verbose {
+		fmt.Fprintf(helpers.Messages, "Read an %s (%s)\n", key.Describe(), key.Format)
+	}
+	out := key.Private
+	if out == nil || keyPublicOnly {
+		out = key.Public
+	}
+	if keyEncrypt && key.Private == nil {
+		return errors.New("Error: --encrypt protects private keys; the input is a public key")
+	}
+
+	// Files are written by cryptospecials so that private keys are saved as 0600
+	savePath := outputPath
+	if savePath == helpers.StdIOPath {
+		savePath = ""
+	}
+	if keyEncrypt {
+		passphrase, err = newKeyPassphrase()
+		if err != nil {
+			return err
+		}
+	}
+	if keyToString == cryptospecials.KeyEncodingOpenSSH {
+		privPath, pubPath := savePath, ""
+		if !cryptospecials.IsPrivateKey(out) {
+			privPath, pubPath = "", savePath
+		}
+		privBytes, pubBytes, err := cryptospecials.OpenSSHKeySave(out, sshComment, passphrase, privPath, pubPath)
+		if err != nil {
+			return err
+		}
+		encoded = pubBytes
+		if privBytes != nil {
+			encoded = privBytes
+		}
+		if Verbose {
+			printSSHFingerprint(out)
+		}
+	} else if keyEncrypt {
+		encoded, err = cryptospecials.EncryptedKeySave(out, passphrase, keyKDFString, savePath)
+		if err != nil {
+			return err
+		}
+	} else {
+		encoded, err = cryptospecials.EncodedKeySave(out, keyToString, savePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if stdOutBool {
+		if keyToString == cryptospecials.KeyEncodingDER {
+			fmt.Fprintf(helpers.Messages, "Key (hex): %x\n", encoded)
+		} else {
+			fmt.Fprintf(helpers.Messages, "%s", encoded)
+		}
+	} else if outputPath == helpers.StdIOPath {
+		_, err = helpers.StdOut.Write(encoded)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Describe a key as (label, value) pairs for 'foil key info'
+func describeKey(key *cryptospecials.Key) ([][2]string, error) {
+
+	kind := "public key"
+	if key.Private != nil {
+		kind = "private key"
+	}
+	info := [][2]string{
+		{"Key", key.Describe()},
+		{"Type", kind},
+		{"Format", key.Format},
+		{"Algorithm", key.Algorithm},
+	}
+
+	switch pubKey := key.Public.(type) {
+	case *rsa.PublicKey:
+		info = append(info,
+			[2]string{"Size", fmt.Sprintf("%d bits", pubKey.N.BitLen())},
+			[2]string{"Modulus (hex)", fmt.Sprintf("%x", pubKey.N)},
+			[2]string{"Public exponent", fmt.Sprintf("%d", pubKey.E)})
+	case *ecdsa.PublicKey:
+		ecdhKey, err := pubKey.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		info = append(info,
+			[2]string{"Curve", pubKey.Curve.Params().Name},
+			[2]string{"Public point (hex)", fmt.Sprintf("%x", ecdhKey.Bytes())})
+	case ed25519.PublicKey:
+		info = append(info, [2]string{"Public key (hex)", fmt.Sprintf("%x", []byte(pubKey))})
+	case *ecdh.PublicKey:
+		info = append(info, [2]string{"Public key (hex)", fmt.Sprintf("%x", pubKey.Bytes())})
+	}
+
+	fingerprint, err := key.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	thumbprint, err := cryptospecials.JWKThumbprint(key.Public)
+	if err != nil {
+		return nil, err
+	}
+	info = append(info,
+		[2]string{"SPKI SHA-256", fingerprint},
+		[2]string{"JWK thumbprint", thumbprint})
+	if sshFingerprint, err := cryptospecials.SSHFingerprint(key.Public); err == nil {
+		info = append(info, [2]string{"SSH fingerprint", sshFingerprint})
+	}
+
+	return info, nil
+}
+
+// Print what the input key is
+func keyInfo(cmd *cobra.Command, args []string) error {
+
+	key, err := loadInputKey()
+	if err != nil {
+		return err
+	}
+	info, err := describeKey(key)
+	if err != nil {
+		return err
+	}
+	for _, line := range info {
+		fmt.Fprintf(helpers.Messages, "%-20s %s\n", line[0]+":", line[1])
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"foil/cryptospecials"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Convert an RSA key to a JWK, back to PEM, and to a public JWK Set
+func TestKeyConvertCmd(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "foil-key")
+	if err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() {
+		inputPath, outputPath, keyToString = "", "", cryptospecials.KeyEncodingPEM
+		keyPublicOnly, keyEncrypt = false, false
+	}()
+	Verbose, stdInString, stdOutBool = false, "", false
+
+	rsaKey, _ := cryptospecials.RSAKeyGen(2048)
+	rsaPath := dir + "/rsa.pem"
+	cryptospecials.RSAKeySave(rsaKey, false, false, &rsaPath, false)
+
+	inputPath, outputPath, keyToString = rsaPath, dir+"/rsa.jwk", cryptospecials.KeyEncodingJWK
+	if err = keyConvert(nil, nil); err != nil {
+		t.Fatalf("FAIL - keyConvert (jwk) - %v", err)
+	}
+	inputPath, outputPath, keyToString = dir+"/rsa.jwk", dir+"/rsa2.pem", cryptospecials.KeyEncodingPEM
+	if err = keyConvert(nil, nil); err != nil {
+		t.Fatalf("FAIL - keyConvert (pem) - %v", err)
+	}
+	loadKey, err := cryptospecials.RSAPrivKeyLoad(&outputPath, false)
+	if err != nil || !loadKey.Equal(rsaKey) {
+		t.Errorf("FAIL - The converted RSA key does not match - %v", err)
+	}
+
+	inputPath, outputPath, keyToString, keyPublicOnly = rsaPath, dir+"/rsa.jwks", cryptospecials.KeyEncodingJWKS, true
+	if err = keyConvert(nil, nil); err != nil {
+		t.Fatalf("FAIL - keyConvert (public jwks) - %v", err)
+	}
+	key, err := cryptospecials.LoadKey(outputPath)
+	if err != nil || key.Private != nil || !rsaKey.PublicKey.Equal(key.Public) {
+		t.Errorf("FAIL - The public JWK Set does not hold the public key - %v", err)
+	}
+
+	keyToString, keyEncrypt, keyPublicOnly = cryptospecials.KeyEncodingJWK, true, false
+	if keyConvertPreChecks(keyConvertCmd, nil) == nil {
+		t.Errorf("FAIL - --encrypt was accepted with --to jwk")
+	}
+}
+
+// Key info must describe the key and VRF output must be tagged with the same SPKI fingerprint
+func TestKeyInfoCmd(t *testing.T) {
+
+	defer func() { inputPath, alphaString, vrfKeyFingerprint = "", "", "" }()
+	Verbose, stdInString, stdOutBool, outputPath = false, "", false, ""
+
+	key, err := cryptospecials.LoadKey("../cryptospecials/testdata/openssh_ecdsa.pem")
+	if err != nil {
+		t.Fatalf("FAIL - LoadKey - %v", err)
+	}
+	info, err := describeKey(key)
+	if err != nil {
+		t.Fatalf("FAIL - describeKey - %v", err)
+	}
+	want := map[string]string{
+		"Type":            "private key",
+		"Curve":           "P-384",
+		"SPKI SHA-256":    "605868b797474c52b11cec1f5bf2e867d5e0dd21499ac6a43792915b2bb71296",
+		"SSH fingerprint": "SHA256:L0WP/jXSn9rGQcDin0k+IXpZ6gp4PrPN9XoO8eR7Evs",
+	}
+	for _, line := range info {
+		if value, ok := want[line[0]]; ok {
+			if line[1] != value {
+				t.Errorf("FAIL - %s is %s; want %s", line[0], line[1], value)
+			}
+			delete(want, line[0])
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("FAIL - describeKey did not report %v", want)
+	}
+
+	inputPath, alphaString = "../cryptospecials/testdata/openssh_ecdsa.pem", "LegitString"
+	err = genEccVrf(&cryptospecials.ECCVRF{})
+	if err != nil || vrfKeyFingerprint != "605868b797474c52b11cec1f5bf2e867d5e0dd21499ac6a43792915b2bb71296" {
+		t.Errorf("FAIL - genEccVrf tagged the proof with %q - %v", vrfKeyFingerprint, err)
+	}
+	if keyInfoPreChecks(keyInfoCmd, nil) != nil {
+		t.Errorf("FAIL - keyInfoPreChecks rejected --in")
+	}
+}
@@ -2,11 +2,11 @@ package commands
 
 import (
 	"crypto/elliptic"
-	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"foil/cryptospecials"
+	"foil/helpers"
 	"hash"
 	"math/big"
 
@@ -18,9 +18,7 @@ func init() {
 	oprfCmd.PersistentFlags().BoolVarP(&mask, "mask", "", false, "mask a string using the ECC-OPRF")
 	oprfCmd.PersistentFlags().BoolVarP(&salt, "salt", "", false, "salt a masked value using the ECC-OPRF")
 	oprfCmd.PersistentFlags().BoolVarP(&unmask, "unmask", "", false, "unmask a salted value using the ECC-OPRF")
-	//oprfCmd.PersistentFlags().BoolVarP(&curveP256, "p256", "", false, "use P-256 as the elliptic curve for ECC-OPRF")
-	//oprfCmd.PersistentFlags().BoolVarP(&curveP384, "p384", "", false, "use P-384 as the elliptic curve for ECC-OPRF")
-	//oprfCmd.PersistentFlags().BoolVarP(&curveP521, "p521", "", false, "use P-521 as the elliptic curve for ECC-OPRF")
+	oprfCmd.PersistentFlags().StringVarP(&curveString, "curve", "", "P-256", "use [P-256, P-384, P-521] as the elliptic curve for ECC-OPRF")
 	//oprfCmd.PersistentFlags().BoolVarP(&curve25519, "c25519", "", false, "use Curve25519 as the elliptic curve for ECC-OPRF")
 	oprfCmd.PersistentFlags().StringVarP(&xString, "x", "", "", "use [hex] as x-coordinate for ECC-OPRF operation (mask, salt, unmask)")
 	oprfCmd.PersistentFlags().StringVarP(&yString, "y", "", "", "use [hex] as y-coordinate for ECC-OPRF operation (mask, salt, unmask)")
@@ -41,7 +39,9 @@ var (
 		Use:   "oprf",
 		Short: "Perform an EC-OPRF action",
 		Long: "Foil can perform [mask], [salt], and [unmask] operations for it's internal" +
-			" ECC-OPERF based on: https://eprint.iacr.org/2017/111.",
+			" ECC-OPERF based on: https://eprint.iacr.org/2017/111. With --out - the resulting" +
+			" point is written to StdOut as raw uncompressed SEC1 BYTES (0x04 || x || y) and all" +
+			" other output is written to StdErr.",
 		PersistentPreRunE: oprfPreCheck,
 		RunE:              doOprf,
 	}
@@ -58,9 +58,16 @@ func oprfPreCheck(cmd *cobra.Command, args []string) error {
 		return errors.New("Error: specify only one OPRF operation")
 	}
 	// Ensure initial OPRF input is provided
-	if mask == true && stdInString == "" {
-		return errors.New("Error: specify OPRF input")
+	if mask == true && stdInString == "" && inputPath == "" {
+		return errors.New("Error: specify OPRF input (--textin [string] or --in [path]; \"-\" for StdIn)")
+	} else if len(stdInString) > 0 && len(inputPath) > 0 {
+		return errors.New("Error: Too many sources for input; select only one")
 	}
+	// Only raw output (--out -) may be requested; the resulting point is written as SEC1 BYTES
+	if (len(outputPath) > 0 && outputPath != helpers.StdIOPath) || stdOutBool {
+		return errors.New("Error: Output direction not supported in OPRF - Output will be sent to StdOut")
+	}
+	pipeChecks()
 	// If salting or unmasking, ensure an elliptic curve point (x,y) is provided
 	if salt == true || unmask == true {
 		// Ensure that a masked or salted elliptic curve point is provided
@@ -70,7 +77,7 @@ func oprfPreCheck(cmd *cobra.Command, args []string) error {
 		// If not secret salt value is provided, warn the suer that one will be generated
 		if salt == true {
 			if saltString == "" {
-				fmt.Println("Warning: No salt value given; generating a random salt")
+				fmt.Fprintln(helpers.Messages, "Warning: No salt value given; generating a random salt")
 			}
 			// Ensure that an r_inv value is provided
 		} else if unmask == true {
@@ -84,7 +91,8 @@ func oprfPreCheck(cmd *cobra.Command, args []string) error {
 }
 
 /*
-* doOprf currently only supports P256 but minor changes can be made to support P384 and P521
+*  doOprf runs one step of the OPRF on the curve selected by --curve (P-256 by default). All
+*  parties must use the same curve; the hash matches the curve (SHA-256/384/512).
  */
 func doOprf(cmd *cobra.Command, args []string) error {
 
@@ -104,9 +112,12 @@ func doOprf(cmd *cobra.Command, args []string) error {
 	// Sill salts with zero values
 	sOut = new(big.Int)
 	rInv = new(big.Int)
-	// Parameters that need to be abstracted away if supporting more curves
-	ec = elliptic.P256()
-	h = sha256.New()
+	// Select the elliptic curve and the matching hash
+	ec, err = cryptospecials.EccCurveByName(curveString)
+	if err != nil {
+		return err
+	}
+	h = cryptospecials.EccHashForCurve(ec)
 
 	// Decode StdIn(x,y) from [hex] into [bytes]; Check to ensure (x,y) is on the curve
 	if !mask {
@@ -125,16 +136,24 @@ func doOprf(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Perform OPRF Masking
+	// Perform OPRF Masking; the input is --textin or read from --in (a file or "-" for StdIn)
 	if mask {
-		pt, rInv, err = elem.Mask(stdInString, h, ec, Verbose)
+		input := stdInString
+		if len(inputPath) > 0 {
+			swap, err = helpers.ReadInput(inputPath)
+			if err != nil {
+				return err
+			}
+			input = string(swap)
+		}
+		pt, rInv, err = elem.Mask(input, h, ec, Verbose)
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Masked x-coordinate (hex): %x\n", pt.X)
-		fmt.Printf("Masked y-coordinate (hex): %x\n", pt.Y)
-		fmt.Printf("SECRET - r inverse  (hex): %x\n", rInv)
+		fmt.Fprintf(helpers.Messages, "Masked x-coordinate (hex): %x\n", pt.X)
+		fmt.Fprintf(helpers.Messages, "Masked y-coordinate (hex): %x\n", pt.Y)
+		fmt.Fprintf(helpers.Messages, "SECRET - r inverse  (hex): %x\n", rInv)
 	}
 	// Perform OPRF Salting
 	if salt {
@@ -155,12 +174,12 @@ func doOprf(cmd *cobra.Command, args []string) error {
 		}
 		// Check to determine if s == sOut
 		if saltString == "" {
-			fmt.Printf("SECRET - new s generated (hex): %x\n", sOut)
-			fmt.Printf("SECRET - s given (hex)        : %x\n", s)
+			fmt.Fprintf(helpers.Messages, "SECRET - new s generated (hex): %x\n", sOut)
+			fmt.Fprintf(helpers.Messages, "SECRET - s given (hex)        : %x\n", s)
 		}
 
-		fmt.Printf("Salted x-coordinate (hex): %x\n", pt.X)
-		fmt.Printf("Salted y-coordinate (hex): %x\n", pt.Y)
+		fmt.Fprintf(helpers.Messages, "Salted x-coordinate (hex): %x\n", pt.X)
+		fmt.Fprintf(helpers.Messages, "Salted y-coordinate (hex): %x\n", pt.Y)
 	}
 	// Perform OPRF unmasking
 	if unmask {
@@ -176,8 +195,21 @@ func doOprf(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
-		fmt.Printf("Unmasked x-coordinate (hex): %x\n", pt.X)
-		fmt.Printf("Unmasked y-coordinate (hex): %x\n", pt.Y)
+		fmt.Fprintf(helpers.Messages, "Unmasked x-coordinate (hex): %x\n", pt.X)
+		fmt.Fprintf(helpers.Messages, "Unmasked y-coordinate (hex): %x\n", pt.Y)
+	}
+
+	// Write the resulting point as raw uncompressed SEC1 BYTES for use in a pipeline
+	if outputPath == helpers.StdIOPath {
+		byteLen := (ec.Params().BitSize + 7) / 8
+		swap = make([]byte, 1+2*byteLen)
+		swap[0] = 4
+		pt.X.FillBytes(swap[1 : 1+byteLen])
+		pt.Y.FillBytes(swap[1+byteLen:])
+		_, err = helpers.StdOut.Write(swap)
+		if err != nil {
+			return fmt.Errorf("Error: Writing output to StdOut: %v", err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"foil/cryptospecials"
+	"io/ioutil"
+	"os"
+
+	"golang.org/x/term"
+)
+
+func init() {
+
+	// Every private key loader in cryptospecials asks for passphrases through keyPassphrase
+	cryptospecials.PassphraseFunc = keyPassphrase
+}
+
+var (
+	passphraseString string
+	passphraseFile   string
+
+	errNoTerminal = errors.New("Error: No terminal to prompt for a passphrase; use --passphrase or --passphrase-file")
+
+	// Passphrases entered at the prompt, by key path, so that each key is only asked for once
+	promptedPassphrases = map[string][]byte{}
+)
+
+/*
+*  Read the passphrase from --passphrase or the first line of --passphrase-file. The second
+*  return value is false when neither flag is set.
+ */
+func passphraseFromFlags() ([]byte, bool, error) {
+
+	if len(passphraseString) > 0 && len(passphraseFile) > 0 {
+		return nil, false, errors.New("Error: Too many sources for the passphrase; select only one")
+	} else if len(passphraseString) > 0 {
+		return []byte(passphraseString), true, nil
+	} else if len(passphraseFile) > 0 {
+		contents, err := ioutil.ReadFile(passphraseFile)
+		if err != nil {
+			return nil, false, err
+		}
+		line := bytes.SplitN(contents, []byte("\n"), 2)[0]
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) == 0 {
+			return nil, false, fmt.Errorf("Error: The passphrase file %s is empty", passphraseFile)
+		}
+		return line, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// Supply the passphrase of an encrypted private key PEM from the flags or a prompt
+func keyPassphrase(path string) ([]byte, error) {
+
+	passphrase, ok, err := passphraseFromFlags()
+	if err != nil || ok {
+		return passphrase, err
+	}
+	if passphrase, ok = promptedPassphrases[path]; ok {
+		return passphrase, nil
+	}
+	passphrase, err = promptPassphrase(fmt.Sprintf("Enter the passphrase for %s: ", path))
+	if err == errNoTerminal {
+		return nil, fmt.Errorf("%w; use --passphrase or --passphrase-file", cryptospecials.ErrPassphraseRequired)
+	} else if err != nil {
+		return nil, err
+	}
+	promptedPassphrases[path] = passphrase
+
+	return passphrase, nil
+}
+
+// Choose the passphrase for a new private key; the prompt asks twice so that typos are caught
+func newKeyPassphrase() ([]byte, error) {
+
+	passphrase, ok, err := passphraseFromFlags()
+	if err != nil || ok {
+		return passphrase, err
+	}
+	passphrase, err = promptPassphrase("Enter a passphrase for the new private key: ")
+	if err != nil {
+		return nil, err
+	}
+	confirm, err := promptPassphrase("Enter the passphrase again: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, confirm) {
+		return nil, errors.New("Error: The passphrases do not match")
+	}
+
+	return passphrase, nil
+}
+
+/*
+*  Prompt on the terminal (not StdIn or StdOut, which may carry keys or data) and read a
+*  passphrase without echoing it.
+ */
+func promptPassphrase(prompt string) ([]byte, error) {
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, errNoTerminal
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	passphrase, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return nil, fmt.Errorf("Error: Reading the passphrase: %v", err)
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("Error: The passphrase must not be empty")
+	}
+
+	return passphrase, nil
+}
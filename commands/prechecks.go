@@ -2,6 +2,7 @@ package commands
 
 import (
 	"errors"
+	"foil/helpers"
 
 	"github.com/spf13/cobra"
 )
@@ -27,7 +28,17 @@ func stdChecks(min int, max int, cmd *cobra.Command, args []string) error {
 		return errors.New("Error: Too many sources for input; select only one")
 	} else if len(outputPath) > 0 && stdOutBool {
 		return errors.New("Error: Too many directions for output; select only one")
-	} else if len(passwordString) > 0 && len(keyString) > 0 {
+	}
+	keySources := 0
+	for _, source := range []string{passwordString, keyString, keyFile, privateKeyPath} {
+		if len(source) > 0 {
+			keySources++
+		}
+	}
+	if len(recipientPaths) > 0 {
+		keySources++
+	}
+	if keySources > 1 {
 		return errors.New("Error: Too many directions for keys; select only one")
 	}
 
@@ -38,6 +49,20 @@ func stdChecks(min int, max int, cmd *cobra.Command, args []string) error {
 		return errors.New("Error: Must specify an input method")
 	}
 
+	pipeChecks()
+
 	// If success, return nil
 	return nil
 }
+
+/*
+*  When input is read from StdIn (--in -) or output is written to StdOut (--out -) as raw BYTES,
+*  send every human readable message to StdErr so that foil can sit in a pipeline. --textout
+*  output is itself a message so it stays on StdOut.
+ */
+func pipeChecks() {
+
+	if outputPath == helpers.StdIOPath || (inputPath == helpers.StdIOPath && !stdOutBool) {
+		helpers.PipeMessagesToStdErr()
+	}
+}
@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"crypto"
 	"crypto/rsa"
 	"errors"
+	"fmt"
 	"foil/cryptospecials"
+	"foil/helpers"
 
 	"github.com/spf13/cobra"
 )
@@ -13,18 +16,26 @@ func init() {
 	// Add rsa specific flags
 	rsaCmd.PersistentFlags().IntVarP(&sizeRSA, "size", "", 0, "generate an RSA private key of length [size] bits")
 	rsaCmd.PersistentFlags().BoolVarP(&rsaGen, "gen", "", false, "generate an RSA private key with --size [int]")
-	rsaCmd.PersistentFlags().BoolVarP(&rsaExtract, "pub", "", false, "extract an RSA public key from an RSA private key PEM")
+	rsaCmd.PersistentFlags().BoolVarP(&rsaExtract, "pub", "", false, "extract an RSA public key from an RSA private key")
+	rsaCmd.PersistentFlags().BoolVarP(&keyEncrypt, "encrypt", "", false, "protect the generated private key with a passphrase (encrypted PKCS#8, or OpenSSH with --ssh)")
+	rsaCmd.PersistentFlags().StringVarP(&keyKDFString, "kdf", "", "pbkdf2", "use [pbkdf2, scrypt] to expand the passphrase of an encrypted private key")
+	rsaCmd.PersistentFlags().BoolVarP(&keySSH, "ssh", "", false, "save the key in OpenSSH format; the public key is an authorized_keys line")
+	rsaCmd.PersistentFlags().StringVarP(&sshComment, "comment", "", "", "use [string] as the comment of an OpenSSH key")
 }
 
 var (
-	rsaExtract bool
-	rsaGen     bool
-	sizeRSA    int
+	rsaExtract   bool
+	rsaGen       bool
+	sizeRSA      int
+	keyEncrypt   bool
+	keyKDFString string
+	keySSH       bool
+	sshComment   string
 
 	rsaCmd = &cobra.Command{
 		Use:               "rsagen [--in PATH] [--text/out PATH] [operation]",
 		Short:             "Generate an RSA private key or extract a public key",
-		Long:              `Generate an RSA private key with key size [size] or Extract the public key from a private PEM`,
+		Long:              `Generate an RSA private key with key size [size] or Extract the public key from a private key. With --encrypt (or a passphrase flag) the private key is saved as encrypted PKCS#8. With --ssh the private key is saved in OpenSSH format and the public key as an authorized_keys line ([PATH].pub when generating).`,
 		PersistentPreRunE: rsaPreChecks,
 		RunE:              doRSA,
 	}
@@ -96,7 +107,12 @@ func genRSA() error {
 		return err
 	}
 
-	// Save the RSA private key as a file and/or print to stdin
+	// Save the RSA private key (encrypted with a passphrase if requested) as a file and/or print to stdin
+	if keySSH {
+		return saveSSHKey(privKey)
+	} else if encryptNewKey() {
+		return saveEncryptedKey(privKey)
+	}
 	err = cryptospecials.RSAKeySave(privKey, false, stdOutBool, &outputPath, Verbose)
 	if err != nil {
 		return err
@@ -119,6 +135,9 @@ func extractRSAPub() error {
 	}
 
 	// Save the RSA pub key as a file and/or print to stdin
+	if keySSH {
+		return saveSSHPublicKey(privKey)
+	}
 	err = cryptospecials.RSAKeySave(privKey, true, stdOutBool, &outputPath, Verbose)
 	if err != nil {
 		return err
@@ -126,3 +145,81 @@ func extractRSAPub() error {
 
 	return nil
 }
+
+// Generated private keys are encrypted with --encrypt or whenever a passphrase flag is given
+func encryptNewKey() bool {
+	return keyEncrypt || len(passphraseString) > 0 || len(passphraseFile) > 0
+}
+
+// Save a generated private key as encrypted PKCS#8 at --out and/or print it with --textout
+func saveEncryptedKey(privKey crypto.PrivateKey) error {
+
+	passphrase, err := newKeyPassphrase()
+	if err != nil {
+		return err
+	}
+	pemBytes, err := cryptospecials.EncryptedKeySave(privKey, passphrase, keyKDFString, outputPath)
+	if err != nil {
+		return err
+	}
+	if stdOutBool {
+		fmt.Fprintf(helpers.Messages, "%s\n", pemBytes)
+	}
+
+	return nil
+}
+
+/*
+*  Save a generated private key in OpenSSH format at --out with its authorized_keys line as
+*  [PATH].pub, as ssh-keygen does, and/or print both with --textout. The key is encrypted
+*  by OpenSSH (not PKCS#8) when a passphrase is requested.
+ */
+func saveSSHKey(privKey crypto.PrivateKey) error {
+
+	var (
+		passphrase []byte
+		err        error
+	)
+
+	if encryptNewKey() {
+		passphrase, err = newKeyPassphrase()
+		if err != nil {
+			return err
+		}
+	}
+	privBytes, pubBytes, err := cryptospecials.OpenSSHKeySave(privKey, sshComment, passphrase, outputPath, publicKeyOf(outputPath))
+	if err != nil {
+		return err
+	}
+	if stdOutBool {
+		fmt.Fprintf(helpers.Messages, "%s\n%s\n", privBytes, pubBytes)
+	}
+
+	return printSSHFingerprint(privKey)
+}
+
+// Save the authorized_keys line of a private key at --out and/or print it with --textout
+func saveSSHPublicKey(privKey crypto.PrivateKey) error {
+
+	_, pubBytes, err := cryptospecials.OpenSSHKeySave(privKey, sshComment, nil, "", outputPath)
+	if err != nil {
+		return err
+	}
+	if stdOutBool {
+		fmt.Fprintf(helpers.Messages, "%s", pubBytes)
+	}
+
+	return printSSHFingerprint(privKey)
+}
+
+// Show the key fingerprint the way 'ssh-keygen -l' does
+func printSSHFingerprint(key interface{}) error {
+
+	fingerprint, err := cryptospecials.SSHFingerprint(key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(helpers.Messages, "SSH fingerprint:", fingerprint)
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"errors"
+	"foil/helpers"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+
+	// Add seal/open specific flags
+	sealCmd.PersistentFlags().StringArrayVarP(&recipientPaths, "recipient", "r", nil, "encrypt to the RSA or EC public key at PATH=[string]; repeat for more recipients")
+	openCmd.PersistentFlags().StringVarP(&privateKeyPath, "private-key", "", "", "decrypt with the RSA or EC private key at PATH=[string]")
+}
+
+var (
+	sealCmd = &cobra.Command{
+		Use:   "seal [--recipient PEM]... [--in PATH] [--out PATH]",
+		Short: "Encrypt input to one or more RSA or EC public keys",
+		Long: "Encrypt input once with a random data key and wrap the data key for every --recipient" +
+			"\npublic key (RSA-OAEP, or ECDH on P-256/P-384/P-521 with HKDF-SHA256). Any one of the" +
+			"\nmatching private keys from 'foil rsagen' or 'foil ecgen' can open the output.",
+		PersistentPreRunE: sealPreChecks,
+		RunE:              doSeal,
+	}
+
+	openCmd = &cobra.Command{
+		Use:               "open [--private-key PEM] [--in PATH] [--out PATH]",
+		Short:             "Decrypt input sealed to a public key with the matching private key",
+		Long:              `Decrypt the output of 'foil seal' (or 'foil aes enc --recipient') with any one of the recipients' RSA or EC private keys.`,
+		PersistentPreRunE: openPreChecks,
+		RunE:              doOpen,
+	}
+)
+
+// Seal requires at least one recipient plus the standard input and output checks
+func sealPreChecks(cmd *cobra.Command, args []string) error {
+
+	err := stdChecks(0, 0, cmd, args)
+	if err != nil {
+		return err
+	}
+	if len(recipientPaths) == 0 {
+		return errors.New("Error: Specify at least one recipient public key (--recipient [path to PEM])")
+	}
+
+	return nil
+}
+
+// Open requires a private key plus the standard input and output checks
+func openPreChecks(cmd *cobra.Command, args []string) error {
+
+	err := stdChecks(0, 0, cmd, args)
+	if err != nil {
+		return err
+	}
+	if len(privateKeyPath) == 0 {
+		return errors.New("Error: Specify a private key (--private-key [path to PEM])")
+	}
+
+	return nil
+}
+
+/*
+*  Seal the input as a foil container with STREAM segments so that files of any size can be
+*  sealed in constant memory. The container logic is shared with 'foil aes'.
+ */
+func doSeal(cmd *cobra.Command, args []string) error {
+
+	var (
+		operation string
+	)
+
+	operation = "encrypt"
+	streamBool, chunkSize = true, helpers.StreamChunkSize
+
+	src, err := helpers.CliStreamInputLogic(&stdInString, &inputPath, &operation, Verbose)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return containerBoilerPlate(&operation, src)
+}
+
+// Open a sealed foil container; the header holds the wrapped data keys and segment size
+func doOpen(cmd *cobra.Command, args []string) error {
+
+	var (
+		operation string
+	)
+
+	operation = "decrypt"
+
+	src, err := helpers.CliStreamInputLogic(&stdInString, &inputPath, &operation, Verbose)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return containerBoilerPlate(&operation, src)
+}
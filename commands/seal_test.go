@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"foil/cryptospecials"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+/*
+*  Seal a file to RSA, P-256, and P-384 recipients (PEMs as written by 'foil rsagen' and
+*  'foil ecgen') and ensure that each private key opens it while an unrelated key does not.
+ */
+func TestSealOpen(t *testing.T) {
+
+	var (
+		privPaths []string
+	)
+
+	dir, err := ioutil.TempDir("", "foil-seal")
+	if err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() { inputPath, outputPath, recipientPaths, privateKeyPath = "", "", nil, "" }()
+	Verbose, stdInString, stdOutBool = false, "", false
+
+	rsaPriv, rsaPub := dir+"/rsa.pem", dir+"/rsa_pub.pem"
+	rsaKey, _ := cryptospecials.RSAKeyGen(2048)
+	cryptospecials.RSAKeySave(rsaKey, false, false, &rsaPriv, false)
+	cryptospecials.RSAKeySave(rsaKey, true, false, &rsaPub, false)
+	privPaths = append(privPaths, rsaPriv)
+	recipientPaths = []string{rsaPub}
+	for _, ec := range []elliptic.Curve{elliptic.P256(), elliptic.P384()} {
+		priv, pub := dir+"/"+ec.Params().Name+".pem", dir+"/"+ec.Params().Name+"_pub.pem"
+		ecKey, _ := cryptospecials.EccPrivKeyGen(ec)
+		cryptospecials.EccKeySave(ecKey, priv, pub)
+		privPaths = append(privPaths, priv)
+		recipientPaths = append(recipientPaths, pub)
+	}
+
+	input := bytes.Repeat([]byte("Attack at dawn! "), 10000)
+	ioutil.WriteFile(dir+"/plain", input, 0600)
+	inputPath, outputPath = dir+"/plain", dir+"/sealed"
+	err = doSeal(nil, nil)
+	if err != nil {
+		t.Fatalf("FAIL - doSeal: %v", err)
+	}
+	recipientPaths = nil
+
+	for _, priv := range privPaths {
+		inputPath, outputPath, privateKeyPath = dir+"/sealed", dir+"/opened", priv
+		err = doOpen(nil, nil)
+		if err != nil {
+			t.Errorf("FAIL - doOpen (%s): %v", priv, err)
+			continue
+		}
+		opened, _ := ioutil.ReadFile(dir + "/opened")
+		if !bytes.Equal(opened, input) {
+			t.Errorf("FAIL - plaintext before and after seal/open are not equivalent (%s)", priv)
+		}
+	}
+
+	otherKey, _ := cryptospecials.EccPrivKeyGen(elliptic.P256())
+	cryptospecials.EccKeySave(otherKey, dir+"/other.pem", dir+"/other_pub.pem")
+	inputPath, outputPath, privateKeyPath = dir+"/sealed", dir+"/opened", dir+"/other.pem"
+	err = doOpen(nil, nil)
+	if err == nil {
+		t.Errorf("FAIL - A key that is not a recipient opened the sealed file")
+	}
+}
@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"foil/helpers"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+
+	// Define flags used by both sign and verify
+	for _, cmd := range []*cobra.Command{signCmd, verifyCmd} {
+		cmd.PersistentFlags().StringVarP(&sigScheme, "scheme", "", "", "use [ecdsa, rsa-pss, rsa-pkcs1v15, ed25519] (default: ecdsa for EC, rsa-pss for RSA, ed25519 for Ed25519 keys)")
+		cmd.PersistentFlags().StringVarP(&sigHash, "hash", "", "", "use [sha256, sha384, sha512] as the message hash (default: SHA-256, or the hash of the EC curve)")
+		cmd.PersistentFlags().StringVarP(&sigFormat, "format", "", helpers.SigFormatDER, "encode the signature as [der, raw, base64]; raw is r || s for ECDSA")
+	}
+
+	// Define flags used by the sign/verify commands
+	signCmd.PersistentFlags().StringVarP(&privateKeyPath, "private-key", "", "", "sign with the RSA, EC, or Ed25519 private key at PATH=[string]")
+	verifyCmd.PersistentFlags().StringVarP(&publicKeyPath, "public-key", "", "", "verify with the RSA, EC, or Ed25519 public key at PATH=[string]")
+	verifyCmd.PersistentFlags().StringVarP(&signaturePath, "signature", "", "", "read the detached signature from the file at PATH=[string]")
+}
+
+var (
+	sigScheme     string
+	sigHash       string
+	sigFormat     string
+	publicKeyPath string
+	signaturePath string
+
+	signCmd = &cobra.Command{
+		Use:   "sign [--private-key PEM] [--in PATH] [--out PATH]",
+		Short: "Make a detached signature over input with an RSA, EC, or Ed25519 private key",
+		Long: "Sign input with a private key from 'foil rsagen' or 'foil ecgen'. ECDSA (P-256, P-384, P-521)," +
+			"\nRSA-PSS, RSA PKCS#1 v1.5, and Ed25519 are supported. The signature is written in DER form" +
+			"\n(what OpenSSL writes), as raw BYTES (r || s for ECDSA), or as base64 with --format.",
+		PersistentPreRunE: signPreChecks,
+		RunE:              doSign,
+	}
+
+	verifyCmd = &cobra.Command{
+		Use:   "verify [--public-key PEM] [--signature PATH] [--in PATH]",
+		Short: "Verify a detached signature over input with an RSA, EC, or Ed25519 public key",
+		Long: "Verify the output of 'foil sign' (or any signature with the same scheme, hash, and format)." +
+			"\nfoil exits with an error when the signature is not valid.",
+		PersistentPreRunE: verifyPreChecks,
+		RunE:              doVerify,
+	}
+)
+
+// Sign requires a private key plus the standard input and output checks
+func signPreChecks(cmd *cobra.Command, args []string) error {
+
+	err := stdChecks(0, 0, cmd, args)
+	if err != nil {
+		return err
+	}
+	if len(privateKeyPath) == 0 {
+		return errors.New("Error: Specify a private key (--private-key [path to PEM])")
+	}
+
+	return nil
+}
+
+// Verify writes no output so it checks the input, public key, and signature flags itself
+func verifyPreChecks(cmd *cobra.Command, args []string) error {
+
+	if len(args) > 0 {
+		return errors.New("Error: Too many arguments")
+	} else if len(stdInString) > 0 && len(inputPath) > 0 {
+		return errors.New("Error: Too many sources for input; select only one")
+	} else if len(stdInString) == 0 && len(inputPath) == 0 {
+		return errors.New("Error: Must specify an input method")
+	} else if len(publicKeyPath) == 0 {
+		return errors.New("Error: Specify a public key (--public-key [path to PEM])")
+	} else if len(signaturePath) == 0 {
+		return errors.New("Error: Specify a signature (--signature [path to file])")
+	}
+
+	return nil
+}
+
+// Select the scheme and hash for a key from the --scheme and --hash flags
+func signatureParams(key interface{}) (string, crypto.Hash, error) {
+
+	var err error
+
+	scheme := sigScheme
+	if len(scheme) == 0 {
+		scheme, err = helpers.DefaultSignatureScheme(key)
+		if err != nil {
+			return "", 0, err
+		}
+	}
+	h, err := helpers.SignatureHash(key, scheme, sigHash)
+	if err != nil {
+		return "", 0, err
+	}
+	if Verbose {
+		if h == 0 {
+			fmt.Fprintln(helpers.Messages, "Signature scheme:", scheme)
+		} else {
+			fmt.Fprintf(helpers.Messages, "Signature scheme: %s with %s\n", scheme, h)
+		}
+	}
+
+	return scheme, h, nil
+}
+
+// Sign the input (streamed unless the scheme is Ed25519) and write the encoded signature
+func doSign(cmd *cobra.Command, args []string) error {
+
+	var (
+		operation string
+	)
+
+	operation = "encrypt"
+
+	privKey, err := loadPrivateKey(privateKeyPath)
+	if err != nil {
+		return err
+	}
+	scheme, h, err := signatureParams(privKey)
+	if err != nil {
+		return err
+	}
+	src, err := helpers.CliStreamInputLogic(&stdInString, &inputPath, &operation, Verbose)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	sig, err := helpers.Sign(privKey, scheme, h, src, Verbose)
+	if err != nil {
+		return err
+	}
+	sig, err = helpers.EncodeSignature(sig, privKey, sigFormat)
+	if err != nil {
+		return err
+	}
+
+	if stdOutBool {
+		if sigFormat == helpers.SigFormatBase64 {
+			fmt.Fprintln(helpers.Messages, "Signature (base64):", string(sig))
+		} else {
+			fmt.Fprintf(helpers.Messages, "Signature (hex): %x\n", sig)
+		}
+		return nil
+	}
+	if !helpers.CliOutputFileLogic(sig, &stdOutBool, &outputPath, &operation, Verbose) {
+		return errors.New("Error: Unable to write the signature")
+	}
+
+	return nil
+}
+
+// Verify the detached signature over the input; an invalid signature is returned as an error
+func doVerify(cmd *cobra.Command, args []string) error {
+
+	var (
+		operation string
+	)
+
+	operation = "encrypt"
+
+	pubKey, err := loadPublicKey(publicKeyPath)
+	if err != nil {
+		return err
+	}
+	scheme, h, err := signatureParams(pubKey)
+	if err != nil {
+		return err
+	}
+	encoded, err := ioutil.ReadFile(signaturePath)
+	if err != nil {
+		return err
+	}
+	sig, err := helpers.DecodeSignature(encoded, pubKey, sigFormat)
+	if err != nil {
+		return err
+	}
+	src, err := helpers.CliStreamInputLogic(&stdInString, &inputPath, &operation, Verbose)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	err = helpers.Verify(pubKey, scheme, h, src, sig, Verbose)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(helpers.Messages, "The signature is valid")
+
+	return nil
+}
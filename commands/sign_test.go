@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"crypto/elliptic"
+	"foil/cryptospecials"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Sign a file with EC, Ed25519, and RSA keys in every format and verify it from the public key PEMs
+func TestSignVerifyCmd(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "foil-sign")
+	if err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() {
+		inputPath, outputPath, privateKeyPath, publicKeyPath, signaturePath = "", "", "", "", ""
+		sigScheme, sigHash, sigFormat = "", "", "der"
+	}()
+	Verbose, stdInString, stdOutBool = false, "", false
+
+	ioutil.WriteFile(dir+"/plain", []byte("Attack at dawn!"), 0600)
+	ecKey, _ := cryptospecials.EccPrivKeyGen(elliptic.P384())
+	cryptospecials.EccKeySave(ecKey, dir+"/ec.pem", dir+"/ec_pub.pem")
+	edKey, _ := cryptospecials.Ed25519KeyGen()
+	cryptospecials.Curve25519KeySave(edKey, dir+"/ed.pem", dir+"/ed_pub.pem")
+	rsaKey, _ := cryptospecials.RSAKeyGen(2048)
+	rsaPath := dir + "/rsa.pem"
+	cryptospecials.RSAKeySave(rsaKey, false, false, &rsaPath, false)
+	rsaPath = dir + "/rsa_pub.pem"
+	cryptospecials.RSAKeySave(rsaKey, true, false, &rsaPath, false)
+
+	tests := []struct {
+		key    string
+		scheme string
+	}{
+		{"ec", ""},
+		{"ed", ""},
+		{"rsa", ""},
+		{"rsa", "rsa-pkcs1v15"},
+	}
+	for _, test := range tests {
+		for _, format := range []string{"der", "raw", "base64"} {
+			sigScheme, sigFormat = test.scheme, format
+			inputPath, outputPath, privateKeyPath = dir+"/plain", dir+"/sig", dir+"/"+test.key+".pem"
+			err = doSign(nil, nil)
+			if err != nil {
+				t.Fatalf("FAIL - doSign (%s %s, %s): %v", test.key, test.scheme, format, err)
+			}
+
+			outputPath, publicKeyPath, signaturePath = "", dir+"/"+test.key+"_pub.pem", dir+"/sig"
+			err = doVerify(nil, nil)
+			if err != nil {
+				t.Errorf("FAIL - doVerify (%s %s, %s): %v", test.key, test.scheme, format, err)
+			}
+
+			stdInString, inputPath = "Attack at dusk!", ""
+			if doVerify(nil, nil) == nil {
+				t.Errorf("FAIL - Verified a modified message (%s %s, %s)", test.key, test.scheme, format)
+			}
+			stdInString = ""
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+
+	"foil/helpers"
 )
 
 func init() {
@@ -16,7 +18,7 @@ var (
 		Short: "Display this binary's version of foil",
 		Long:  `Foil has versions just like everything else. Yolo.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("Version 0.2a")
+			fmt.Fprintln(helpers.Messages, "Version 0.2a")
 		},
 	}
 )
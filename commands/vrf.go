@@ -1,16 +1,22 @@
 package commands
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rsa"
-	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"foil/cryptospecials"
+	"foil/helpers"
+	"io/ioutil"
 	"math/big"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
 )
@@ -23,6 +29,10 @@ func init() {
 	vrfCmd.PersistentFlags().StringVarP(&alphaString, "alpha", "", "", "use [string] as VRF input")
 	vrfCmd.PersistentFlags().StringVarP(&betaString, "beta", "", "", "use [string] as H(proof) - Beta")
 	vrfCmd.PersistentFlags().StringVarP(&proofString, "proof", "", "", "use [hex] as VRF proof for validation")
+	vrfCmd.PersistentFlags().StringVarP(&vrfSuite, "suite", "", "", "use the RFC 9381 suite ["+strings.Join(append(cryptospecials.ECVRFSuites(), cryptospecials.RSAFDHVRFSuites()...), ", ")+"] instead of the --rsa/--ecc VRFs of the NSEC5 paper")
+	vrfCmd.PersistentFlags().StringVarP(&proofFilePath, "proof-file", "", "", "gen: also write the proof, beta, alpha, suite, and key fingerprint to PATH=[string]; ver: read them from PATH=[string]")
+	vrfCmd.PersistentFlags().StringVarP(&batchPath, "batch", "", "", "gen: evaluate the VRF over every line (or JSON record with \"alpha\") of PATH=[string]; ver: verify every proof file line of PATH=[string]")
+	vrfGenCmd.Flags().StringVarP(&proofFormat, "proof-format", "", cryptospecials.VRFFormatJSON, "write --proof-file as ["+cryptospecials.VRFFormatJSON+", "+cryptospecials.VRFFormatCBOR+"]")
 
 	// Add VRF generate and verify as sub commands of vrf
 	vrfCmd.AddCommand(vrfGenCmd)
@@ -35,26 +45,39 @@ var (
 	alphaString string
 	betaString  string
 	proofString string
+	vrfSuite    string
+
+	// VRF envelope (see cryptospecials.VRFEnvelope) written by gen and read by ver
+	proofFilePath        string
+	proofFormat          string
+	proofFileFingerprint string
+
+	// Lines of alphas (gen) or of JSON VRF envelopes (ver) for --batch
+	batchPath string
+
+	// SPKI SHA-256 fingerprint of the key loaded by the last VRF operation; tags VRF output
+	vrfKeyFingerprint string
 
 	vrfCmd = &cobra.Command{
-		Use:               "vrf",
-		Short:             "Perform a VRF action",
-		Long:              `Foil can perform RSA and ECC VRF operations defined in: https://eprint.iacr.org/2017/099.pdf.`,
+		Use:   "vrf",
+		Short: "Perform a VRF action",
+		Long: "Foil can perform RSA and ECC VRF operations defined in: https://eprint.iacr.org/2017/099.pdf," +
+			"\nand the RFC 9381 ECVRF and RSA-FDH-VRF (--suite) whose proofs interoperate with other RFC 9381 libraries.",
 		PersistentPreRunE: vrfPreChecks,
 	}
 
 	vrfGenCmd = &cobra.Command{
-		Use:     "gen [--rsa/ecc] [--in private PEM]",
+		Use:     "gen [--rsa/ecc/suite] [--in private PEM]",
 		Short:   "Generate a VRF proof and data",
-		Long:    `Generate a VRF proof and data; [TYPE] is ECC or RSA. PEM file must be unencrypted.`,
+		Long:    `Generate a VRF proof and data; [TYPE] is ECC or RSA. An encrypted (PKCS#8) PEM is decrypted with --passphrase or a prompt. The output is tagged with the SPKI SHA-256 fingerprint of the key (see 'foil key info'). With --suite the proof is an RFC 9381 proof (pi) for the suite; --rsa --suite RSA-FDH-VRF-SHA256 (or SHA384, SHA512) selects the RFC 9381 RSA VRF. With --out - the raw BYTES of beta are written to StdOut and the proof is written to StdErr.`,
 		PreRunE: vrfGenChecks,
 		RunE:    doGenVRF,
 	}
 
 	vrfVerCmd = &cobra.Command{
-		Use:     "ver [--rsa/ecc] [--in public PEM]",
-		Short:   "Verify an RSA-VRF proof and data",
-		Long:    `Verify a VRF proof and data; [TYPE] is ECC or RSA.`,
+		Use:     "ver [--rsa/ecc/suite] [--in public PEM]",
+		Short:   "Verify a VRF proof and data",
+		Long:    `Verify a VRF proof and data; [TYPE] is ECC or RSA. With --suite the proof is an RFC 9381 proof (pi) for the suite.`,
 		PreRunE: vrfVerChecks,
 		RunE:    doVerVRF,
 	}
@@ -67,18 +90,27 @@ func vrfPreChecks(cmd *cobra.Command, args []string) error {
 	if len(stdInString) > 0 {
 		return errors.New("Error: Reading from StdIn is not permitted when using VRFs - Must read from file")
 	}
-	// Inform the user that output defaults to StdOut; specifying output direction does nothing
-	if len(outputPath) > 0 || stdOutBool {
+	// Inform the user that output defaults to StdOut; only raw output (--out -) or a batch file may be requested
+	if (len(outputPath) > 0 && outputPath != helpers.StdIOPath && len(batchPath) == 0) || stdOutBool {
 		return errors.New("Error: Dutput direction not supported in VRF - Ouput will be sent to StdOut")
 	}
+	pipeChecks()
 	// Check to ensure that required flags are set; ensure the user is aware of VRF and PEM types
-	if typeRSA == false && typeECC == false {
-		return errors.New("Error: Specify the type of VRF to be used: RSA or ECC")
+	if len(vrfSuite) > 0 && typeECC {
+		return errors.New("Error: --suite selects the type of VRF; omit --ecc")
+	} else if len(vrfSuite) > 0 && typeRSA && !cryptospecials.IsRSAFDHVRFSuite(vrfSuite) {
+		return fmt.Errorf("Error: --rsa takes an RSA-FDH-VRF suite [%s]", strings.Join(cryptospecials.RSAFDHVRFSuites(), ", "))
+	} else if typeRSA == false && typeECC == false && len(vrfSuite) == 0 && !(cmd == vrfVerCmd && (len(proofFilePath) > 0 || len(batchPath) > 0)) {
+		return errors.New("Error: Specify the type of VRF to be used: RSA, ECC, or an RFC 9381 suite (--suite)")
 	}
 
 	if inputPath == "" {
 		return errors.New("Error: Specify an input PEM (--in [path to file])")
 	}
+	// A batch file holds the alphas (and the proofs and betas) of every record
+	if len(batchPath) > 0 && (len(alphaString) > 0 || len(betaString) > 0 || len(proofString) > 0 || len(proofFilePath) > 0) {
+		return errors.New("Error: --batch supplies alpha, beta, and proof; omit --alpha, --beta, --proof, and --proof-file")
+	}
 
 	return nil
 }
@@ -86,6 +118,9 @@ func vrfPreChecks(cmd *cobra.Command, args []string) error {
 // Perform checks for flags pertaining specifically to VRF generation
 func vrfGenChecks(cmd *cobra.Command, args []string) error {
 
+	if len(batchPath) > 0 {
+		return nil
+	}
 	// Ensure that alpha is provided
 	if alphaString == "" {
 		return errors.New("Error: Specify alpha (VRF input)")
@@ -96,6 +131,18 @@ func vrfGenChecks(cmd *cobra.Command, args []string) error {
 // Perform checks for flags pertaining specifically to VRF verification
 func vrfVerChecks(cmd *cobra.Command, args []string) error {
 
+	if len(batchPath) > 0 {
+		if len(outputPath) > 0 {
+			return errors.New("Error: VRF batch verification prints its report; omit --out")
+		}
+		return nil
+	}
+	// A proof file supplies the type of VRF, alpha, beta, and proof
+	if len(proofFilePath) > 0 {
+		if err := loadProofFile(); err != nil {
+			return err
+		}
+	}
 	// Ensure that alpha, beta, proof are provided
 	if alphaString == "" {
 		return errors.New("Error: Specify alpha (VRF input)")
@@ -106,13 +153,16 @@ func vrfVerChecks(cmd *cobra.Command, args []string) error {
 	if proofString == "" {
 		return errors.New("Error: Specify VRF proof (hex)")
 	}
+	if outputPath == helpers.StdIOPath {
+		return errors.New("Error: VRF verification has no raw output; omit --out")
+	}
 	if len(proofString) < 64 && typeECC == true {
 		return errors.New("Error: Specify VRF proof - (x, y, c, s) - as \"[hex], [hex], [hex], [hex]\"")
 	}
 	return nil
 }
 
-//Using user input, choose which type of VRF output to generate
+// Using user input, choose which type of VRF output to generate
 func doGenVRF(cmd *cobra.Command, args []string) error {
 
 	var (
@@ -121,21 +171,53 @@ func doGenVRF(cmd *cobra.Command, args []string) error {
 		beta  []byte
 	)
 
-	if typeRSA {
+	if len(batchPath) > 0 {
+		return genVrfBatch()
+	}
+
+	if len(vrfSuite) > 0 {
+		proof, beta, err = genSuiteVrf()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(helpers.Messages, "%s Proof (hex): %x\n", vrfSuite, proof)
+		fmt.Fprintf(helpers.Messages, "%s Beta  (hex): %x\n", vrfSuite, beta)
+	} else if typeRSA {
 		proof, beta, err = genRsaVrf()
 		if err != nil {
 			return err
 		}
-		fmt.Printf("RSA-VRF Proof           (hex): %x\n", proof)
-		fmt.Printf("RSA-VRF Beta - H(Proof) (hex): %x\n", beta)
+		fmt.Fprintf(helpers.Messages, "RSA-VRF Proof           (hex): %x\n", proof)
+		fmt.Fprintf(helpers.Messages, "RSA-VRF Beta - H(Proof) (hex): %x\n", beta)
 	} else if typeECC {
 		eccVrf := cryptospecials.ECCVRF{}
 		err = genEccVrf(&eccVrf)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("EC-VRF Proof - x, y, c, s (hex): %x, %x, %x, %x\n", eccVrf.EccProof.X, eccVrf.EccProof.Y, eccVrf.EccProof.C, eccVrf.EccProof.S)
-		fmt.Printf("EC-VRF Beta H(Proof) (hex): %x\n", eccVrf.Beta)
+		fmt.Fprintf(helpers.Messages, "EC-VRF Proof - x, y, c, s (hex): %x, %x, %x, %x\n", eccVrf.EccProof.X, eccVrf.EccProof.Y, eccVrf.EccProof.C, eccVrf.EccProof.S)
+		fmt.Fprintf(helpers.Messages, "EC-VRF Beta H(Proof) (hex): %x\n", eccVrf.Beta)
+		beta = eccVrf.Beta
+		proof, err = eccVrf.EccProof.MarshalBinary()
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(helpers.Messages, "VRF key fingerprint (SPKI SHA-256): %s\n", vrfKeyFingerprint)
+
+	if len(proofFilePath) > 0 {
+		err = saveProofFile(proof, beta)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The VRF output (beta) is written as raw BYTES for use in a pipeline
+	if outputPath == helpers.StdIOPath {
+		_, err = helpers.StdOut.Write(beta)
+		if err != nil {
+			return fmt.Errorf("Error: Writing beta to StdOut: %v", err)
+		}
 	}
 
 	return nil
@@ -149,23 +231,23 @@ func doVerVRF(cmd *cobra.Command, args []string) error {
 		err      error
 	)
 
+	if len(batchPath) > 0 {
+		return verVrfBatch()
+	}
+
 	if betaString == "" {
 		return errors.New("Error: Specify beta H(proof)")
 	}
 
-	if typeRSA {
-		validVRF, err = verRsaVrf()
+	if len(vrfSuite) > 0 {
+		validVRF, err = verSuiteVrf()
 		if err != nil {
 			return err
 		}
-		/*
-		*  Inform the user about the VRF validity. There is currently no standard for VRF
-		*  output. Printing to StdIn in the meantime.adataString
-		 */
-		if validVRF {
-			fmt.Printf("VRF Proof & Beta are valid\n")
-		} else {
-			fmt.Printf("VRF Proof & Beta are NOT valid\n")
+	} else if typeRSA {
+		validVRF, err = verRsaVrf()
+		if err != nil {
+			return err
 		}
 	} else if typeECC {
 		var eccVrf *cryptospecials.ECCVRF
@@ -174,16 +256,98 @@ func doVerVRF(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("Error: %v; Have you specified the VRF proof (x, y, c, s) as \"[hex], [hex], [hex], [hex]\"?", err)
 		}
-		if validVRF {
-			fmt.Printf("VRF Proof & Beta are valid\n")
-		} else {
-			fmt.Printf("VRF Proof & Beta are NOT valid\n")
-		}
+	}
+	fmt.Fprintf(helpers.Messages, "VRF key fingerprint (SPKI SHA-256): %s\n", vrfKeyFingerprint)
+
+	// A proof file names the key of the prover; a proof checked with another key is an error
+	if len(proofFileFingerprint) > 0 && !strings.EqualFold(proofFileFingerprint, vrfKeyFingerprint) {
+		return fmt.Errorf("Error: The proof file is for the key %s, not %s", proofFileFingerprint, vrfKeyFingerprint)
+	}
+
+	/*
+	*  Inform the user about the VRF validity. There is currently no standard for VRF
+	*  output. Printing to StdIn in the meantime.adataString
+	 */
+	if validVRF {
+		fmt.Fprintf(helpers.Messages, "VRF Proof & Beta are valid\n")
+	} else {
+		fmt.Fprintf(helpers.Messages, "VRF Proof & Beta are NOT valid\n")
 	}
 
 	return nil
 }
 
+// suiteVRF is the VRF of an RFC 9381 suite: ECVRF or RSAFDHVRF
+type suiteVRF interface {
+	Generate(alpha []byte, privKey crypto.PrivateKey, verbose bool) ([]byte, []byte, error)
+	Verify(alpha []byte, beta []byte, proof []byte, pubKey crypto.PublicKey, verbose bool) (bool, error)
+}
+
+// newSuiteVRF returns the VRF of an RFC 9381 suite
+func newSuiteVRF(suite string) suiteVRF {
+
+	if cryptospecials.IsRSAFDHVRFSuite(suite) {
+		return cryptospecials.RSAFDHVRF{Suite: suite}
+	}
+
+	return cryptospecials.ECVRF{Suite: suite}
+}
+
+/*
+*  Perform boilerplate operation needed to generate an RFC 9381 VRF output given:
+*  (1) an alpha (might be shared only with the generator and verifier),
+*  (2) a private key for the suite (--suite)
+ */
+func genSuiteVrf() ([]byte, []byte, error) {
+
+	vrfData := newSuiteVRF(vrfSuite)
+
+	key, err := cryptospecials.LoadKey(inputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if key.Private == nil {
+		return nil, nil, errors.New("Error: VRF generation requires a private key; the input is a public key")
+	}
+	vrfKeyFingerprint, err = key.Fingerprint()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return vrfData.Generate([]byte(alphaString), key.Private, Verbose)
+}
+
+/*
+*  Perform boilerplate operation needed to verify an RFC 9381 VRF output given:
+*  (1) an alpha (might be shared only with the generator and verifier),
+*  (2) beta (hex)
+*  (3) proof (hex)
+*  (4) the public (or private) key of the generator for the suite (--suite)
+ */
+func verSuiteVrf() (bool, error) {
+
+	vrfData := newSuiteVRF(vrfSuite)
+
+	proof, err := hex.DecodeString(strings.TrimSpace(proofString))
+	if err != nil {
+		return false, fmt.Errorf("Error: The proof is not hex: %v", err)
+	}
+	beta, err := hex.DecodeString(strings.TrimSpace(betaString))
+	if err != nil {
+		return false, fmt.Errorf("Error: Beta is not hex: %v", err)
+	}
+	key, err := cryptospecials.LoadKey(inputPath)
+	if err != nil {
+		return false, err
+	}
+	vrfKeyFingerprint, err = key.Fingerprint()
+	if err != nil {
+		return false, err
+	}
+
+	return vrfData.Verify([]byte(alphaString), beta, proof, key.Public, Verbose)
+}
+
 /*
 *  Perform boilerplate operation needed to generate a VRF output given:
 *  (1) an alpha (might be shared only with the generator and verifier),
@@ -203,6 +367,10 @@ func genRsaVrf() ([]byte, []byte, error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	vrfKeyFingerprint, err = cryptospecials.SPKIFingerprint(vrfData.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Generate a VRF proof and beta (H(proof))
 	vrfData.Proof, vrfData.Beta, err = vrfData.Generate(vrfData.Alpha, vrfData.PrivateKey, Verbose)
@@ -215,10 +383,10 @@ func genRsaVrf() ([]byte, []byte, error) {
 }
 
 /*
-* NOTE: Currently only supporting P-256
 *  Perform boilerplate operation needed to generate a VRF output given:
 *  (1) an alpha (might be shared only with the generator and verifier),
-*  (2) EC private key (ECDSA PEM format)
+*  (2) EC private key (ECDSA PEM format) on P-256, P-384, or P-521
+*  The curve is read from the PEM and the hash matches the curve (SHA-256/384/512).
  */
 func genEccVrf(eccVrf *cryptospecials.ECCVRF) error {
 
@@ -227,20 +395,26 @@ func genEccVrf(eccVrf *cryptospecials.ECCVRF) error {
 		privKey *ecdsa.PrivateKey
 		err     error
 	)
-	// Define the elliptic curve to be P-256
-	ec = elliptic.P256()
 
-	// Load a private key
+	// Load a private key; the elliptic curve is named in the PEM
 	privKey, err = cryptospecials.EccPrivKeyLoad(inputPath)
 	if err != nil {
 		return err
 	}
-	eccVrf.EccProof, eccVrf.Beta, err = eccVrf.Generate(sha256.New(), ec, privKey, []byte(alphaString), Verbose)
+	vrfKeyFingerprint, err = cryptospecials.SPKIFingerprint(privKey)
 	if err != nil {
 		return err
 	}
+	ec = privKey.Curve
 	if Verbose {
-		fmt.Println("EC-VRF Proof: ", eccVrf.EccProof)
+		fmt.Fprintln(helpers.Messages, "EC-VRF curve:", ec.Params().Name)
+	}
+	eccVrf.EccProof, eccVrf.Beta, err = eccVrf.Generate(cryptospecials.EccHashForCurve(ec), ec, privKey, []byte(alphaString), Verbose)
+	if err != nil {
+		return err
+	}
+	if Verbose {
+		fmt.Fprintln(helpers.Messages, "EC-VRF Proof: ", eccVrf.EccProof)
 	}
 	return nil
 }
@@ -277,6 +451,10 @@ func verRsaVrf() (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	vrfKeyFingerprint, err = cryptospecials.SPKIFingerprint(pubKey)
+	if err != nil {
+		return false, err
+	}
 	//vrfData.PublicKey = pubKey //broken // TODO: Fix assignment SegFault
 
 	// Verify that the proof, beta, and alpha are valid // &vrfData.PublicKey changed to pubKey
@@ -289,12 +467,11 @@ func verRsaVrf() (bool, error) {
 }
 
 /*
-* NOTE: Currently only supporting P-256
 *  Perform boilerplate operation needed to verify a VRF output given:
 *  (1) an alpha (might be shared only with the generator and verifier),
 *  (2) beta (public)
 *  (3) proof (public)
-*  (4) EC public key (ECDSA PEM format)
+*  (4) EC public key (ECDSA PEM format) on P-256, P-384, or P-521
  */
 func verEccVrf(eccVrf *cryptospecials.ECCVRF) (bool, error) {
 
@@ -305,13 +482,16 @@ func verEccVrf(eccVrf *cryptospecials.ECCVRF) (bool, error) {
 		err    error
 	)
 
-	// Define the elliptic curve to be P-256
-	ec = elliptic.P256()
-	// Load the public key
+	// Load the public key; the elliptic curve is named in the PEM
 	pubKey, err = cryptospecials.EccPubKeyLoad(inputPath)
 	if err != nil {
 		return false, err
 	}
+	vrfKeyFingerprint, err = cryptospecials.SPKIFingerprint(pubKey)
+	if err != nil {
+		return false, err
+	}
+	ec = pubKey.Curve
 
 	/*
 	* Parse VRF Proof string "[hex], [hex], [hex], [hex]"
@@ -324,7 +504,7 @@ func verEccVrf(eccVrf *cryptospecials.ECCVRF) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	valid, err = eccVrf.Verify(sha256.New(), pubKey, ec, []byte(alphaString), eccVrf.Beta, &eccVrf.EccProof, Verbose)
+	valid, err = eccVrf.Verify(cryptospecials.EccHashForCurve(ec), pubKey, ec, []byte(alphaString), eccVrf.Beta, &eccVrf.EccProof, Verbose)
 	if err != nil {
 		return false, err
 	}
@@ -335,51 +515,417 @@ func verEccVrf(eccVrf *cryptospecials.ECCVRF) (bool, error) {
 func uglyStringParse(eccVrf *cryptospecials.ECCVRF, rawData string) (err error) {
 
 	var (
-		swap        []byte
 		splitString []string
 	)
 
-	splitString = strings.Split(proofString, ",")
+	splitString = strings.Split(rawData, ",")
 	if Verbose {
-		fmt.Println("SplitString: ", splitString)
+		fmt.Fprintln(helpers.Messages, "SplitString: ", splitString)
+	}
+	if len(splitString) != 4 {
+		return fmt.Errorf("Error: The proof has %d comma separated values, not 4 (x, y, c, s)", len(splitString))
 	}
-	swap, err = hex.DecodeString(strings.Replace(splitString[0], " ", "", -1))
+	eccVrf.EccProof.X, err = parseHexInt(splitString[0])
 	if err != nil {
 		return err
 	}
-	if Verbose {
-		fmt.Println(strings.Replace(splitString[0], " ", "", -1))
+	eccVrf.EccProof.Y, err = parseHexInt(splitString[1])
+	if err != nil {
+		return err
 	}
-	eccVrf.EccProof.X = new(big.Int).SetBytes(swap)
-	swap, err = hex.DecodeString(strings.Replace(splitString[1], " ", "", -1))
+	eccVrf.EccProof.C, err = parseHexInt(splitString[2])
 	if err != nil {
 		return err
 	}
-	if Verbose {
-		fmt.Println(strings.Replace(splitString[1], " ", "", -1))
+	eccVrf.EccProof.S, err = parseHexInt(splitString[3])
+	if err != nil {
+		return err
 	}
-	eccVrf.EccProof.Y = new(big.Int).SetBytes(swap)
-	swap, err = hex.DecodeString(strings.Replace(splitString[2], " ", "", -1))
+
+	eccVrf.Beta, err = hex.DecodeString(betaString)
 	if err != nil {
 		return err
 	}
+
+	return nil
+}
+
+/*
+*  parseHexInt reads one comma separated proof value. The values are printed with %x which
+*  drops leading zeros, so the hex may have an odd length.
+ */
+func parseHexInt(hexString string) (*big.Int, error) {
+
+	hexString = strings.Replace(hexString, " ", "", -1)
 	if Verbose {
-		fmt.Println(strings.Replace(splitString[2], " ", "", -1))
+		fmt.Fprintln(helpers.Messages, hexString)
+	}
+	value, ok := new(big.Int).SetString(hexString, 16)
+	if !ok || strings.HasPrefix(hexString, "-") || strings.HasPrefix(hexString, "+") {
+		return nil, fmt.Errorf("Error: %q is not a hex value", hexString)
+	}
+
+	return value, nil
+}
+
+// vrfSuiteName is the name of the VRF in a proof file: the RFC 9381 suite or a foil VRF
+func vrfSuiteName() string {
+
+	if typeECC {
+		return cryptospecials.SuiteFoilECCVRF
+	}
+	for _, name := range append(cryptospecials.ECVRFSuites(), cryptospecials.RSAFDHVRFSuites()...) {
+		if strings.EqualFold(name, vrfSuite) {
+			return name
+		}
 	}
-	eccVrf.EccProof.C = new(big.Int).SetBytes(swap)
-	swap, err = hex.DecodeString(strings.Replace(splitString[3], " ", "", -1))
+
+	return cryptospecials.SuiteFoilRSAVRF
+}
+
+// saveProofFile writes the VRF envelope of a generated proof to --proof-file
+func saveProofFile(proof []byte, beta []byte) error {
+
+	envelope, err := cryptospecials.EncodeVRFEnvelope(cryptospecials.VRFEnvelope{
+		Suite:          vrfSuiteName(),
+		KeyFingerprint: vrfKeyFingerprint,
+		Alpha:          []byte(alphaString),
+		Beta:           beta,
+		Proof:          proof,
+	}, proofFormat)
 	if err != nil {
 		return err
 	}
+	err = ioutil.WriteFile(proofFilePath, envelope, 0644)
+	if err != nil {
+		return fmt.Errorf("Error: Writing the proof file: %v", err)
+	}
 	if Verbose {
-		fmt.Println(strings.Replace(splitString[3], " ", "", -1))
+		fmt.Fprintf(helpers.Messages, "VRF proof file (%s): %s\n", proofFormat, proofFilePath)
 	}
-	eccVrf.EccProof.S = new(big.Int).SetBytes(swap)
 
-	eccVrf.Beta, err = hex.DecodeString(betaString)
+	return nil
+}
+
+/*
+*  loadProofFile reads the VRF envelope of --proof-file into the VRF flags. The suite of the
+*  envelope selects the VRF; --alpha, --rsa, --ecc, and --suite may be given but must agree.
+ */
+func loadProofFile() error {
+
+	data, err := helpers.ReadInput(proofFilePath)
 	if err != nil {
 		return err
 	}
+	envelope, err := cryptospecials.DecodeVRFEnvelope(data)
+	if err != nil {
+		return err
+	}
+	if len(proofString) > 0 || len(betaString) > 0 {
+		return errors.New("Error: --proof-file supplies the proof and beta; omit --proof and --beta")
+	}
+	if len(alphaString) > 0 && alphaString != string(envelope.Alpha) {
+		return errors.New("Error: --alpha is not the alpha of the proof file")
+	}
+
+	conflict := fmt.Errorf("Error: The proof file is a %s proof; the VRF flags name another VRF", envelope.Suite)
+	switch envelope.Suite {
+	case cryptospecials.SuiteFoilRSAVRF:
+		if typeECC || len(vrfSuite) > 0 {
+			return conflict
+		}
+		typeRSA = true
+		proofString = hex.EncodeToString(envelope.Proof)
+	case cryptospecials.SuiteFoilECCVRF:
+		if typeRSA || len(vrfSuite) > 0 {
+			return conflict
+		}
+		typeECC = true
+		var eccProof cryptospecials.Proof
+		if err = eccProof.UnmarshalBinary(envelope.Proof); err != nil {
+			return err
+		}
+		proofString = fmt.Sprintf("%x, %x, %x, %x", eccProof.X, eccProof.Y, eccProof.C, eccProof.S)
+	default:
+		if typeECC || (typeRSA && !cryptospecials.IsRSAFDHVRFSuite(envelope.Suite)) ||
+			(len(vrfSuite) > 0 && !strings.EqualFold(vrfSuite, envelope.Suite)) {
+			return conflict
+		}
+		vrfSuite = envelope.Suite
+		proofString = hex.EncodeToString(envelope.Proof)
+	}
+	alphaString = string(envelope.Alpha)
+	betaString = hex.EncodeToString(envelope.Beta)
+	proofFileFingerprint = envelope.KeyFingerprint
+
+	return nil
+}
+
+// vrfBatchRecord is an alpha (gen) or a VRF envelope (ver) read from a line of --batch
+type vrfBatchRecord struct {
+	line     int
+	alpha    []byte
+	envelope cryptospecials.VRFEnvelope
+	err      error
+}
+
+/*
+*  readVrfBatch reads the non-blank lines of --batch. For gen a line is an alpha, or a JSON
+*  record (a line that starts with '{') whose "alpha" member is the alpha (which may be
+*  empty); other members are ignored. For ver a line is a JSON VRF envelope. A line that can not be read is a record
+*  with an error.
+ */
+func readVrfBatch(envelopes bool) ([]vrfBatchRecord, error) {
+
+	var records []vrfBatchRecord
+
+	data, err := helpers.ReadInput(batchPath)
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		record := vrfBatchRecord{line: i + 1}
+		if envelopes {
+			record.envelope, record.err = cryptospecials.DecodeVRFEnvelope([]byte(line))
+		} else if strings.HasPrefix(strings.TrimSpace(line), "{") {
+			var fields struct {
+				Alpha *string `json:"alpha"`
+			}
+			if record.err = json.Unmarshal([]byte(line), &fields); record.err == nil && fields.Alpha == nil {
+				record.err = errors.New("Error: The record has no \"alpha\"")
+			} else if record.err == nil {
+				record.alpha = []byte(*fields.Alpha)
+			}
+		} else {
+			record.alpha = []byte(line)
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("Error: %s has no VRF records", batchPath)
+	}
+
+	return records, nil
+}
+
+// runVrfBatch calls work for 0 ... count-1 on one goroutine per CPU
+func runVrfBatch(count int, work func(i int)) {
+
+	var wg sync.WaitGroup
+
+	jobs := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				work(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+/*
+*  vrfBatchGenerator returns the VRF selected by --rsa, --ecc, or --suite for the private key;
+*  ECCVRF proofs are returned in their binary encoding. It is safe for concurrent use.
+ */
+func vrfBatchGenerator(key *cryptospecials.Key) (func(alpha []byte) ([]byte, []byte, error), error) {
+
+	if key.Private == nil {
+		return nil, errors.New("Error: VRF generation requires a private key; the input is a public key")
+	}
+	if len(vrfSuite) > 0 {
+		vrf := newSuiteVRF(vrfSuite)
+		return func(alpha []byte) ([]byte, []byte, error) {
+			return vrf.Generate(alpha, key.Private, false)
+		}, nil
+	}
+	if typeRSA {
+		privKey, err := key.RSAPrivate()
+		if err != nil {
+			return nil, err
+		}
+		return func(alpha []byte) ([]byte, []byte, error) {
+			return cryptospecials.RSAVRF{}.Generate(alpha, privKey, false)
+		}, nil
+	}
+	privKey, err := key.ECPrivate()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(alpha []byte) ([]byte, []byte, error) {
+		eccProof, beta, err := cryptospecials.ECCVRF{}.Generate(cryptospecials.EccHashForCurve(privKey.Curve), privKey.Curve, privKey, alpha, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		proof, err := eccProof.MarshalBinary()
+		return proof, beta, err
+	}, nil
+}
+
+/*
+*  genVrfBatch evaluates the VRF over every record of --batch and writes one JSON VRF envelope
+*  per line, in the order of the records, to --out (StdOut by default). A record that can not be
+*  read or evaluated stops the batch.
+ */
+func genVrfBatch() error {
+
+	records, err := readVrfBatch(false)
+	if err != nil {
+		return err
+	}
+	key, err := cryptospecials.LoadKey(inputPath)
+	if err != nil {
+		return err
+	}
+	vrfKeyFingerprint, err = key.Fingerprint()
+	if err != nil {
+		return err
+	}
+	generate, err := vrfBatchGenerator(key)
+	if err != nil {
+		return err
+	}
+	suite := vrfSuiteName()
+
+	lines := make([][]byte, len(records))
+	runVrfBatch(len(records), func(i int) {
+		if records[i].err != nil {
+			return
+		}
+		envelope := cryptospecials.VRFEnvelope{Suite: suite, KeyFingerprint: vrfKeyFingerprint, Alpha: records[i].alpha}
+		envelope.Proof, envelope.Beta, records[i].err = generate(records[i].alpha)
+		if records[i].err == nil {
+			lines[i], records[i].err = json.Marshal(envelope)
+		}
+	})
+
+	var output bytes.Buffer
+	for i, record := range records {
+		if record.err != nil {
+			return fmt.Errorf("Error: %s line %d: %v", batchPath, record.line, record.err)
+		}
+		output.Write(lines[i])
+		output.WriteByte('\n')
+	}
+	if len(outputPath) == 0 || outputPath == helpers.StdIOPath {
+		_, err = helpers.StdOut.Write(output.Bytes())
+		return err
+	}
+	err = ioutil.WriteFile(outputPath, output.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("Error: Writing the batch output: %v", err)
+	}
+	fmt.Fprintf(helpers.Messages, "VRF key fingerprint (SPKI SHA-256): %s\n", vrfKeyFingerprint)
+	fmt.Fprintf(helpers.Messages, "%d VRF proofs written to %s\n", len(records), outputPath)
+
+	return nil
+}
+
+// vrfFlagsAllow reports whether --rsa, --ecc, and --suite (when given) select the VRF of suite
+func vrfFlagsAllow(suite string) bool {
+
+	switch {
+	case typeECC:
+		return suite == cryptospecials.SuiteFoilECCVRF
+	case len(vrfSuite) > 0:
+		return strings.EqualFold(suite, vrfSuite)
+	case typeRSA:
+		return suite == cryptospecials.SuiteFoilRSAVRF || cryptospecials.IsRSAFDHVRFSuite(suite)
+	}
+
+	return true
+}
+
+// verifyVrfEnvelope verifies one VRF envelope with the key whose SPKI SHA-256 is fingerprint
+func verifyVrfEnvelope(key *cryptospecials.Key, fingerprint string, envelope cryptospecials.VRFEnvelope) (bool, error) {
+
+	if !vrfFlagsAllow(envelope.Suite) {
+		return false, fmt.Errorf("Error: The record is a %s proof; the VRF flags name another VRF", envelope.Suite)
+	}
+	if len(envelope.KeyFingerprint) > 0 && !strings.EqualFold(envelope.KeyFingerprint, fingerprint) {
+		return false, fmt.Errorf("Error: The record is for the key %s", envelope.KeyFingerprint)
+	}
+
+	switch envelope.Suite {
+	case cryptospecials.SuiteFoilRSAVRF:
+		pubKey, err := key.RSAPublic()
+		if err != nil {
+			return false, err
+		}
+		return cryptospecials.RSAVRF{}.Verify(envelope.Alpha, envelope.Beta, envelope.Proof, pubKey, false)
+	case cryptospecials.SuiteFoilECCVRF:
+		pubKey, err := key.ECPublic()
+		if err != nil {
+			return false, err
+		}
+		var eccProof cryptospecials.Proof
+		if err = eccProof.UnmarshalBinary(envelope.Proof); err != nil {
+			return false, err
+		}
+		return cryptospecials.ECCVRF{}.Verify(cryptospecials.EccHashForCurve(pubKey.Curve), pubKey, pubKey.Curve, envelope.Alpha, envelope.Beta, &eccProof, false)
+	}
+
+	return newSuiteVRF(envelope.Suite).Verify(envelope.Alpha, envelope.Beta, envelope.Proof, key.Public, false)
+}
+
+/*
+*  verVrfBatch verifies every VRF envelope of --batch with the key of --in and prints the result
+*  of each line and a summary. Unless every proof is valid an error is returned, so foil exits
+*  with a non-zero status.
+ */
+func verVrfBatch() error {
+
+	var valid, invalid, failed int
+
+	records, err := readVrfBatch(true)
+	if err != nil {
+		return err
+	}
+	key, err := cryptospecials.LoadKey(inputPath)
+	if err != nil {
+		return err
+	}
+	vrfKeyFingerprint, err = key.Fingerprint()
+	if err != nil {
+		return err
+	}
+
+	results := make([]bool, len(records))
+	runVrfBatch(len(records), func(i int) {
+		if records[i].err == nil {
+			results[i], records[i].err = verifyVrfEnvelope(key, vrfKeyFingerprint, records[i].envelope)
+		}
+	})
+
+	fmt.Fprintf(helpers.Messages, "VRF key fingerprint (SPKI SHA-256): %s\n", vrfKeyFingerprint)
+	for i, record := range records {
+		switch {
+		case record.err != nil:
+			failed++
+			fmt.Fprintf(helpers.Messages, "Line %d: %v\n", record.line, record.err)
+		case results[i]:
+			valid++
+			fmt.Fprintf(helpers.Messages, "Line %d: VRF Proof & Beta are valid\n", record.line)
+		default:
+			invalid++
+			fmt.Fprintf(helpers.Messages, "Line %d: VRF Proof & Beta are NOT valid\n", record.line)
+		}
+	}
+	fmt.Fprintf(helpers.Messages, "%d VRF records: %d valid, %d NOT valid, %d errors\n", len(records), valid, invalid, failed)
+	if valid != len(records) {
+		return fmt.Errorf("Error: %d of %d VRF records are not valid", invalid+failed, len(records))
+	}
 
 	return nil
 }
@@ -1,10 +1,13 @@
 package commands
 
 import (
+	"crypto/elliptic"
 	"encoding/hex"
 	"fmt"
 	"foil/cryptospecials"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -78,24 +81,24 @@ func TestRsaVrfGenVer(t *testing.T) {
 
 	/*
 	*  NOTE: These values are for proofString and betaString generated with a
-	*  SPECIFIC pem. Change proofString and betaString if using another.
+	*  SPECIFIC pem (cryptospecials/testdata/rsa2048.pem). Change proofString and
+	*  betaString if using another.
 	*
 	* 	These variables are global to Commands package
 	 */
 	alphaString = "LegitString"
-	proofString = "8506a33f87e68efb8dc490cf3ad89ef1ba8465085afe89024708ed9c42a95c4" +
-		"64682727a4f956323146e6a2cfc42935fb42e1e51c11121c5cadb1c1a6fb76" +
-		"3dd0757ba6eaef5808451933da5bba4928a94c80f1776197f40622f795eaf0" +
-		"c2772cbf69d5d17748642e92068dd7406800efe885e63aea08f4e8d5777a10" +
-		"c7f5c35357ba80bc455fc6f1c482462905ae557c7fa59e0e28da20ccbf7122" +
-		"20215662e19d09b8af16d1cdc2c259989c2997893b40e888b8c6c6c22ce58f" +
-		"d7ed0635ee1d5d587fd78c1b75dff20916e637f63bf8da11a5fb2d1aad7766" +
-		"ccd3077f4170111aacbf5fdd1f27059294e66cf77ce7ef88306ea00f0b1c94" +
-		"4abacbbb2eee0e1"
-	betaString = "5feabe59598852cce72ef186261196378bacc571975f8d26e6f91106cae2e02b"
+	proofString = "31a299dc15f76e46fd3306907684ae63e961128aef871685edce14c3b8d98f26" +
+		"8af52ec2428caf8b72a63eb0220d553831ef39cc20f57c4d459602396187971f" +
+		"b9c55ec3449a64d664834bfcdf689355b3cfb6945e8bcc30df810e9cd3d42146" +
+		"92cd3e4b6bacee1b7b80c78f140fea35a178dbd5ef35f93e9881b06868ab81f3" +
+		"43b0bc5eb882a2b18eb11b22bd2b98092d35f133c98d4ae336faf7179ee9ecb0" +
+		"97a9ed5a066bbbb9c0a79e97d1726a029bf6e5535ca1569935fd594334231ae3" +
+		"17da1c9798bea74d40d689cefec8e634b285cdf2066c6e5fc1a8c57f97830a84" +
+		"4070ea58934cf711ccb45cc1e1ecee0748de9fdbebc52ffb2f63e28a2553e2e1"
+	betaString = "b2a80244372ed38511a678ce03f894df3a81b9c7c68f78c7076f5fa5c5e052f3"
 
 	// genRsa takes in alphaString, pathPriv, Verbose
-	inputPath = "testPrivKey.pem"
+	inputPath = "../cryptospecials/testdata/rsa2048.pem"
 	proof, beta, err = genRsaVrf()
 	if err != nil {
 		t.Errorf("FAIL - %v", err)
@@ -107,7 +110,7 @@ func TestRsaVrfGenVer(t *testing.T) {
 		t.Errorf("FAIL - Beta generation failure")
 	}
 
-	inputPath = "testPubKey.pem"
+	inputPath = "../cryptospecials/testdata/rsa2048.pem.pub"
 	validity, err = verRsaVrf()
 	if err != nil {
 		t.Errorf("FAIL - %v", err)
@@ -154,3 +157,272 @@ func TestECCVrfGenVer(t *testing.T) {
 		t.Errorf("FAIL - VRF verification failure")
 	}
 }
+
+// The RFC 9381 suite must reproduce the RFC 9381 example 10 proof from a key file and verify it
+func TestECVRFSuiteGenVer(t *testing.T) {
+
+	defer func() {
+		inputPath, alphaString, betaString, proofString, vrfSuite, vrfKeyFingerprint = "", "", "", "", "", ""
+	}()
+	Verbose, typeRSA, typeECC = false, false, false
+
+	inputPath, alphaString, vrfSuite = "../cryptospecials/testdata/rfc9381_p256.pem", "sample", cryptospecials.SuiteECVRFP256SHA256TAI
+	if err := vrfPreChecks(vrfGenCmd, nil); err != nil {
+		t.Fatalf("FAIL - vrfPreChecks - %v", err)
+	}
+	proof, beta, err := genSuiteVrf()
+	if err != nil {
+		t.Fatalf("FAIL - genECVRF - %v", err)
+	}
+	if hex.EncodeToString(proof) != "035b5c726e8c0e2c488a107c600578ee75cb702343c153cb1eb8dec77f4b5071b4a53f0a46f018bc2c56e58d383f2305e0975972c26feea0eb122fe7893c15af376b33edf7de17c6ea056d4d82de6bc02f" {
+		t.Errorf("FAIL - The proof is not the RFC 9381 proof: %x", proof)
+	}
+
+	proofString, betaString = hex.EncodeToString(proof), hex.EncodeToString(beta)
+	valid, err := verSuiteVrf()
+	if err != nil || !valid {
+		t.Errorf("FAIL - The proof did not verify - %v", err)
+	}
+	alphaString = "Sample"
+	if valid, _ = verSuiteVrf(); valid {
+		t.Errorf("FAIL - The proof verified for another alpha")
+	}
+
+	typeECC = true
+	if vrfPreChecks(vrfGenCmd, nil) == nil {
+		t.Errorf("FAIL - --suite was accepted with --ecc")
+	}
+	typeECC = false
+}
+
+// The Ed25519 key of RFC 9381 appendix B.4, example 16 (RFC 8032 test 1)
+func TestECVRFEdwardsSuiteGenVer(t *testing.T) {
+
+	defer func() {
+		inputPath, alphaString, betaString, proofString, vrfSuite, vrfKeyFingerprint = "", "", "", "", "", ""
+	}()
+	Verbose, typeRSA, typeECC = false, false, false
+
+	inputPath, vrfSuite = "../cryptospecials/testdata/rfc9381_ed25519.pem", cryptospecials.SuiteECVRFEdwards25519SHA512ELL2
+	if err := vrfPreChecks(vrfGenCmd, nil); err != nil {
+		t.Fatalf("FAIL - vrfPreChecks - %v", err)
+	}
+	proof, beta, err := genSuiteVrf()
+	if err != nil {
+		t.Fatalf("FAIL - genECVRF - %v", err)
+	}
+	if hex.EncodeToString(proof) != "7d9c633ffeee27349264cf5c667579fc583b4bda63ab71d001f89c10003ab46f14adf9a3cd8b8412d9038531e865c341cafa73589b023d14311c331a9ad15ff2fb37831e00f0acaa6d73bc9997b06501" {
+		t.Errorf("FAIL - The proof is not the RFC 9381 proof: %x", proof)
+	}
+
+	proofString, betaString = hex.EncodeToString(proof), hex.EncodeToString(beta)
+	valid, err := verSuiteVrf()
+	if err != nil || !valid {
+		t.Errorf("FAIL - The proof did not verify - %v", err)
+	}
+
+	// An ELL2 proof is not a TAI proof, and P-256 keys are not Ed25519 keys
+	vrfSuite = cryptospecials.SuiteECVRFEdwards25519SHA512TAI
+	if valid, _ = verSuiteVrf(); valid {
+		t.Errorf("FAIL - The ELL2 proof verified as a TAI proof")
+	}
+	inputPath = "../cryptospecials/testdata/rfc9381_p256.pem"
+	if _, _, err = genSuiteVrf(); err == nil {
+		t.Errorf("FAIL - %s accepted a P-256 key", vrfSuite)
+	}
+}
+
+// --rsa --suite selects RSA-FDH-VRF; the proof must match TestRSAFDHVRFVectors in cryptospecials
+func TestRSAFDHVRFSuiteGenVer(t *testing.T) {
+
+	defer func() {
+		inputPath, alphaString, betaString, proofString, vrfSuite, vrfKeyFingerprint = "", "", "", "", "", ""
+		typeRSA = false
+	}()
+	Verbose, typeRSA, typeECC = false, true, false
+
+	inputPath, alphaString, vrfSuite = "../cryptospecials/testdata/rsa2048.pem", "sample", "rsa-fdh-vrf-sha256"
+	if err := vrfPreChecks(vrfGenCmd, nil); err != nil {
+		t.Fatalf("FAIL - vrfPreChecks - %v", err)
+	}
+	proof, beta, err := genSuiteVrf()
+	if err != nil {
+		t.Fatalf("FAIL - genSuiteVrf - %v", err)
+	}
+	if hex.EncodeToString(beta) != "72f8728919db9c209aa90c6f75d36593fe9e73c9c49590b7f2de83dafcc40d5c" {
+		t.Errorf("FAIL - Beta is %x", beta)
+	}
+
+	inputPath = "../cryptospecials/testdata/rsa2048.pem.pub"
+	proofString, betaString = hex.EncodeToString(proof), hex.EncodeToString(beta)
+	valid, err := verSuiteVrf()
+	if err != nil || !valid {
+		t.Errorf("FAIL - The proof did not verify - %v", err)
+	}
+
+	// --rsa only takes RSA-FDH-VRF suites
+	vrfSuite = cryptospecials.SuiteECVRFP256SHA256TAI
+	if vrfPreChecks(vrfGenCmd, nil) == nil {
+		t.Errorf("FAIL - --rsa was accepted with an ECVRF suite")
+	}
+}
+
+// A proof file written by gen must verify with only --in and --proof-file
+func TestVRFProofFile(t *testing.T) {
+
+	defer func() {
+		inputPath, alphaString, betaString, proofString, vrfSuite, vrfKeyFingerprint = "", "", "", "", "", ""
+		proofFilePath, proofFormat, proofFileFingerprint = "", "", ""
+		typeRSA, typeECC = false, false
+	}()
+	Verbose = false
+	dir := t.TempDir()
+
+	for _, test := range []struct {
+		name, suite, format, priv, pub string
+		rsa, ecc                       bool
+	}{
+		{"ECCVRF", "", cryptospecials.VRFFormatJSON,
+			"../cryptospecials/testdata/rfc9381_p256.pem", "../cryptospecials/testdata/rfc9381_p256.pem", false, true},
+		{"RSA-FDH-VRF", cryptospecials.SuiteRSAFDHVRFSHA512, cryptospecials.VRFFormatCBOR,
+			"../cryptospecials/testdata/rsa2048.pem", "../cryptospecials/testdata/rsa2048.pem.pub", true, false},
+		{"ECVRF", cryptospecials.SuiteECVRFEdwards25519SHA512TAI, cryptospecials.VRFFormatCBOR,
+			"../cryptospecials/testdata/rfc9381_ed25519.pem", "../cryptospecials/testdata/rfc9381_ed25519.pem", false, false},
+	} {
+		inputPath, alphaString, betaString, proofString = test.priv, "LegitString", "", ""
+		vrfSuite, typeRSA, typeECC = test.suite, test.rsa, test.ecc
+		proofFilePath, proofFormat, proofFileFingerprint = dir+"/"+test.name, test.format, ""
+		if err := vrfPreChecks(vrfGenCmd, nil); err != nil {
+			t.Fatalf("FAIL - %s vrfPreChecks - %v", test.name, err)
+		}
+		if err := doGenVRF(vrfGenCmd, nil); err != nil {
+			t.Fatalf("FAIL - %s gen - %v", test.name, err)
+		}
+
+		// ver learns the VRF, alpha, beta, and proof from the file
+		inputPath, alphaString, vrfSuite, typeRSA, typeECC = test.pub, "", "", false, false
+		if err := vrfPreChecks(vrfVerCmd, nil); err != nil {
+			t.Fatalf("FAIL - %s vrfPreChecks - %v", test.name, err)
+		}
+		if err := vrfVerChecks(vrfVerCmd, nil); err != nil {
+			t.Fatalf("FAIL - %s vrfVerChecks - %v", test.name, err)
+		}
+		if err := doVerVRF(vrfVerCmd, nil); err != nil {
+			t.Errorf("FAIL - %s ver - %v", test.name, err)
+		}
+		if alphaString != "LegitString" || proofFileFingerprint != vrfKeyFingerprint {
+			t.Errorf("FAIL - %s proof file: alpha %q, fingerprint %s", test.name, alphaString, proofFileFingerprint)
+		}
+
+		// Another alpha, or another key, is refused
+		alphaString, betaString, proofString = "Another", "", ""
+		if vrfVerChecks(vrfVerCmd, nil) == nil {
+			t.Errorf("FAIL - %s proof file accepted with another --alpha", test.name)
+		}
+	}
+
+	otherKey, _ := cryptospecials.EccPrivKeyGen(elliptic.P256())
+	if err := cryptospecials.EccKeySave(otherKey, dir+"/other.pem", dir+"/other.pem.pub"); err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+	alphaString, betaString, proofString, vrfSuite, typeRSA, typeECC = "", "", "", "", false, false
+	inputPath, proofFilePath = dir+"/other.pem.pub", dir+"/ECCVRF"
+	if err := vrfVerChecks(vrfVerCmd, nil); err != nil {
+		t.Fatalf("FAIL - vrfVerChecks - %v", err)
+	}
+	if err := doVerVRF(vrfVerCmd, nil); err == nil {
+		t.Errorf("FAIL - A proof file was checked with another key")
+	}
+}
+
+// Proofs without four values are errors, not panics
+func TestUglyStringParse(t *testing.T) {
+
+	defer func() { betaString = "" }()
+	betaString = "00"
+
+	for _, rawData := range []string{"", "01", "01, 02, 03", "01, 02, 03, 04, 05"} {
+		if uglyStringParse(new(cryptospecials.ECCVRF), rawData) == nil {
+			t.Errorf("FAIL - %q was parsed", rawData)
+		}
+	}
+	if err := uglyStringParse(new(cryptospecials.ECCVRF), "01, 02, 03, 04"); err != nil {
+		t.Errorf("FAIL - %v", err)
+	}
+}
+
+// A batch of alphas must give one proof per record that verifies; a changed record must fail
+func TestVRFBatch(t *testing.T) {
+
+	defer func() {
+		inputPath, outputPath, batchPath, vrfSuite, vrfKeyFingerprint = "", "", "", "", ""
+		typeRSA, typeECC = false, false
+	}()
+	Verbose, typeRSA, typeECC = false, false, false
+	dir := t.TempDir()
+	alphas, proofs := dir+"/alphas.txt", dir+"/proofs.jsonl"
+	if err := ioutil.WriteFile(alphas, []byte("sample\n\n{\"id\": 2, \"alpha\": \"LegitString\"}\r\nlast\n{\"alpha\": \"\"}\n"), 0644); err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+
+	// RSA-FDH-VRF-SHA256 of "sample" is the proof of TestRSAFDHVRFVectors in cryptospecials
+	inputPath, outputPath, batchPath, vrfSuite = "../cryptospecials/testdata/rsa2048.pem", proofs, alphas, cryptospecials.SuiteRSAFDHVRFSHA256
+	if err := vrfPreChecks(vrfGenCmd, nil); err != nil {
+		t.Fatalf("FAIL - vrfPreChecks - %v", err)
+	}
+	if err := doGenVRF(vrfGenCmd, nil); err != nil {
+		t.Fatalf("FAIL - gen --batch - %v", err)
+	}
+	output, _ := ioutil.ReadFile(proofs)
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("FAIL - The batch gave %d records, not 4", len(lines))
+	}
+	first, err := cryptospecials.DecodeVRFEnvelope([]byte(lines[0]))
+	if err != nil || string(first.Alpha) != "sample" || hex.EncodeToString(first.Beta) != "72f8728919db9c209aa90c6f75d36593fe9e73c9c49590b7f2de83dafcc40d5c" {
+		t.Errorf("FAIL - The first record is %v - %v", first, err)
+	}
+	if second, _ := cryptospecials.DecodeVRFEnvelope([]byte(lines[1])); string(second.Alpha) != "LegitString" {
+		t.Errorf("FAIL - The JSON record gave alpha %q", second.Alpha)
+	}
+	if fourth, err := cryptospecials.DecodeVRFEnvelope([]byte(lines[3])); err != nil || len(fourth.Alpha) != 0 {
+		t.Errorf("FAIL - The JSON record with an empty alpha gave %q - %v", fourth.Alpha, err)
+	}
+
+	inputPath, outputPath, batchPath, vrfSuite = "../cryptospecials/testdata/rsa2048.pem.pub", "", proofs, ""
+	if err = vrfPreChecks(vrfVerCmd, nil); err != nil {
+		t.Fatalf("FAIL - vrfPreChecks - %v", err)
+	}
+	if err = doVerVRF(vrfVerCmd, nil); err != nil {
+		t.Errorf("FAIL - ver --batch - %v", err)
+	}
+	outputPath = dir + "/report.txt"
+	if vrfVerChecks(vrfVerCmd, nil) == nil {
+		t.Errorf("FAIL - ver --batch was accepted with --out")
+	}
+	outputPath = ""
+	typeECC = true
+	if err = doVerVRF(vrfVerCmd, nil); err == nil {
+		t.Errorf("FAIL - RSA-FDH-VRF records verified as --ecc")
+	}
+	typeECC = false
+
+	// A changed alpha is NOT valid, so the batch fails
+	lines[2] = strings.Replace(lines[2], "\"alpha\":\"bGFzdA==\"", "\"alpha\":\"TGFzdA==\"", 1)
+	ioutil.WriteFile(proofs, []byte(strings.Join(lines, "\n")), 0644)
+	if err = doVerVRF(vrfVerCmd, nil); err == nil {
+		t.Errorf("FAIL - A batch with a changed alpha verified")
+	}
+
+	// A JSON record without "alpha" stops gen
+	ioutil.WriteFile(alphas, []byte("one\n{\"id\": 2}\n"), 0644)
+	inputPath, outputPath, batchPath, typeECC = "../cryptospecials/testdata/rfc9381_p256.pem", proofs, alphas, true
+	if err = doGenVRF(vrfGenCmd, nil); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("FAIL - A record without alpha gave %v", err)
+	}
+	alphaString = "sample"
+	if vrfPreChecks(vrfGenCmd, nil) == nil {
+		t.Errorf("FAIL - --batch was accepted with --alpha")
+	}
+	alphaString = ""
+}
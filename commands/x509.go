@@ -0,0 +1,375 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"foil/cryptospecials"
+	"foil/helpers"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+
+	// Define flags used by both csr and selfsign
+	for _, cmd := range []*cobra.Command{x509CSRCmd, x509SelfSignCmd} {
+		cmd.PersistentFlags().StringVarP(&x509Subject, "subject", "", "", "use [string] as the subject, e.g. \"CN=example.com,O=Example\" or \"/CN=example.com/O=Example\"")
+		cmd.PersistentFlags().StringSliceVarP(&x509SANs, "san", "", nil, "add a subject alternative name [DNS:, IP:, email:, URI:]; may be repeated or comma separated")
+		cmd.PersistentFlags().StringSliceVarP(&x509KeyUsage, "key-usage", "", nil, "add a key usage, e.g. [digitalSignature, keyEncipherment, keyCertSign, serverAuth, clientAuth]")
+	}
+
+	// Define flags used by the selfsign sub command
+	x509SelfSignCmd.PersistentFlags().IntVarP(&x509Days, "days", "", 365, "make the certificate valid for [int] days")
+	x509SelfSignCmd.PersistentFlags().StringVarP(&x509NotBefore, "not-before", "", "", "make the certificate valid from [RFC 3339 time or YYYY-MM-DD] (default: now)")
+	x509SelfSignCmd.PersistentFlags().BoolVarP(&x509CA, "ca", "", false, "make a CA certificate (basicConstraints CA:TRUE)")
+
+	// Add csr, selfsign, and show to x509Cmd
+	x509Cmd.AddCommand(x509CSRCmd)
+	x509Cmd.AddCommand(x509SelfSignCmd)
+	x509Cmd.AddCommand(x509ShowCmd)
+}
+
+var (
+	x509Subject   string
+	x509SANs      []string
+	x509KeyUsage  []string
+	x509Days      int
+	x509NotBefore string
+	x509CA        bool
+
+	x509Cmd = &cobra.Command{
+		Use:   "x509",
+		Short: "Make CSRs and self-signed certificates, or show certificates",
+		Long: "Make PKCS#10 certificate signing requests and self-signed X.509 certificates with an RSA, EC," +
+			"\nor Ed25519 private key from 'foil rsagen', 'foil ecgen', OpenSSL, ssh-keygen, or a JWK, and" +
+			"\nshow what a certificate or CSR holds.",
+	}
+
+	x509CSRCmd = &cobra.Command{
+		Use:   "csr [--in KEY] [--subject NAME] [--san NAME] [--text/out PATH]",
+		Short: "Make a certificate signing request (PKCS#10) signed by a private key",
+		Long: "Make a CSR for the private key read with --in or --textin. The subject, SANs, and key usages" +
+			"\nare requested; the CSR is written as a \"CERTIFICATE REQUEST\" PEM.",
+		PersistentPreRunE: x509PreChecks,
+		RunE:              x509CSR,
+	}
+
+	x509SelfSignCmd = &cobra.Command{
+		Use:   "selfsign [--in KEY] [--subject NAME] [--san NAME] [--days DAYS] [--text/out PATH]",
+		Short: "Make a self-signed X.509 certificate for a private key",
+		Long: "Make a certificate for the private key read with --in or --textin, signed by the same key." +
+			"\nThe serial number is random. Without --key-usage a CA (--ca) may sign certificates and CRLs" +
+			"\nand other certificates may sign (and encipher keys with RSA). The certificate is written" +
+			"\nas a \"CERTIFICATE\" PEM.",
+		PersistentPreRunE: x509PreChecks,
+		RunE:              x509SelfSign,
+	}
+
+	x509ShowCmd = &cobra.Command{
+		Use:   "show [--in CERT]",
+		Short: "Show the subject, validity, SANs, key, and fingerprints of certificates and CSRs",
+		Long: "Show every certificate (e.g. of a chain) or CSR in a PEM file, or a single DER certificate" +
+			"\nor CSR. The signatures of self-signed certificates and CSRs are checked.",
+		PersistentPreRunE: keyInfoPreChecks,
+		RunE:              x509Show,
+	}
+)
+
+// CSRs and certificates need the standard input and output checks and a name for the key
+func x509PreChecks(cmd *cobra.Command, args []string) error {
+
+	err := stdChecks(0, 0, cmd, args)
+	if err != nil {
+		return err
+	}
+	if len(x509Subject) == 0 && len(x509SANs) == 0 {
+		return errors.New("Error: Specify a subject (--subject) or at least one SAN (--san)")
+	} else if x509Days <= 0 {
+		return errors.New("Error: --days must be at least 1")
+	}
+
+	return nil
+}
+
+// Build the certificate options from the subject, SAN, key usage, and validity flags
+func x509Options() (*cryptospecials.CertificateOptions, error) {
+
+	var (
+		opts cryptospecials.CertificateOptions
+		err  error
+	)
+
+	opts.Subject, err = cryptospecials.ParseSubject(x509Subject)
+	if err != nil {
+		return nil, err
+	}
+	err = opts.AddSANs(x509SANs)
+	if err != nil {
+		return nil, err
+	}
+	err = opts.ParseKeyUsage(x509KeyUsage)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.NotBefore = time.Now().UTC().Truncate(time.Second)
+	if len(x509NotBefore) > 0 {
+		opts.NotBefore, err = time.Parse(time.RFC3339, x509NotBefore)
+		if err != nil {
+			opts.NotBefore, err = time.Parse("2006-01-02", x509NotBefore)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error: --not-before %q is not an RFC 3339 time or YYYY-MM-DD", x509NotBefore)
+		}
+	}
+	opts.NotAfter = opts.NotBefore.AddDate(0, 0, x509Days)
+	opts.IsCA = x509CA
+
+	return &opts, nil
+}
+
+// Read the private key and build the options for csr and selfsign
+func x509KeyAndOptions() (*cryptospecials.Key, *cryptospecials.CertificateOptions, error) {
+
+	key, err := loadInputKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	if key.Private == nil {
+		return nil, nil, errors.New("Error: A private key is required to sign; the input is a public key")
+	}
+	opts, err := x509Options()
+	if err != nil {
+		return nil, nil, err
+	}
+	if Verbose {
+		fmt.Fprintf(helpers.Messages, "Signing with an %s (%s)\n", key.Describe(), key.Format)
+	}
+
+	return key, opts, nil
+}
+
+// Write a DER certificate or CSR as PEM to --out or StdOut
+func x509Output(der []byte, blockType string) error {
+
+	var (
+		operation string
+	)
+
+	operation = "decrypt"
+
+	encoded := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if stdOutBool {
+		fmt.Fprintf(helpers.Messages, "%s", encoded)
+		return nil
+	}
+	if !helpers.CliOutputFileLogic(encoded, &stdOutBool, &outputPath, &operation, Verbose) {
+		return fmt.Errorf("Error: Unable to write the %s", strings.ToLower(blockType))
+	}
+
+	return nil
+}
+
+// Make a CSR for the input key
+func x509CSR(cmd *cobra.Command, args []string) error {
+
+	key, opts, err := x509KeyAndOptions()
+	if err != nil {
+		return err
+	}
+	der, err := cryptospecials.CreateCSR(key.Private, opts)
+	if err != nil {
+		return err
+	}
+
+	return x509Output(der, "CERTIFICATE REQUEST")
+}
+
+// Make a self-signed certificate for the input key
+func x509SelfSign(cmd *cobra.Command, args []string) error {
+
+	key, opts, err := x509KeyAndOptions()
+	if err != nil {
+		return err
+	}
+	der, err := cryptospecials.CreateSelfSigned(key.Private, opts)
+	if err != nil {
+		return err
+	}
+	if Verbose {
+		fmt.Fprintf(helpers.Messages, "Valid from %s to %s\n", opts.NotBefore.Format(time.RFC3339), opts.NotAfter.Format(time.RFC3339))
+	}
+
+	return x509Output(der, "CERTIFICATE")
+}
+
+// Describe the SANs of a certificate or CSR, e.g. "DNS:example.com, IP:127.0.0.1"
+func describeSANs(dnsNames []string, ips []string, emails []string, uris []string) string {
+
+	var sans []string
+
+	for _, names := range []struct {
+		prefix string
+		values []string
+	}{{"DNS:", dnsNames}, {"IP:", ips}, {"email:", emails}, {"URI:", uris}} {
+		for _, value := range names.values {
+			sans = append(sans, names.prefix+value)
+		}
+	}
+
+	return strings.Join(sans, ", ")
+}
+
+// Describe the public key of a certificate or CSR
+func describeCertificateKey(pubKey interface{}) ([][2]string, error) {
+
+	key, err := cryptospecials.CertificateKey(pubKey, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := key.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	return [][2]string{
+		{"Public key", strings.TrimSuffix(key.Describe(), " public key")},
+		{"SPKI SHA-256", fingerprint},
+	}, nil
+}
+
+// Describe a certificate as (label, value) pairs for 'foil x509 show'
+func describeCertificate(cert *x509.Certificate) ([][2]string, error) {
+
+	var ips, uris []string
+
+	for _, ip := range cert.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+	}
+	info := [][2]string{
+		{"Subject", cert.Subject.String()},
+		{"Issuer", cert.Issuer.String()},
+		{"Serial", fmt.Sprintf("%x", cert.SerialNumber)},
+		{"Not before", cert.NotBefore.UTC().Format(time.RFC3339)},
+		{"Not after", cert.NotAfter.UTC().Format(time.RFC3339)},
+	}
+	if sans := describeSANs(cert.DNSNames, ips, cert.EmailAddresses, uris); len(sans) > 0 {
+		info = append(info, [2]string{"SANs", sans})
+	}
+	if usages := cryptospecials.KeyUsageNames(cert.KeyUsage, cert.ExtKeyUsage); len(usages) > 0 {
+		info = append(info, [2]string{"Key usage", strings.Join(usages, ", ")})
+	}
+	if cert.BasicConstraintsValid {
+		info = append(info, [2]string{"CA", fmt.Sprintf("%t", cert.IsCA)})
+	}
+	info = append(info, [2]string{"Signature", cert.SignatureAlgorithm.String()})
+	if cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil {
+		info = append(info, [2]string{"Self-signed", "yes (signature is valid)"})
+	}
+
+	keyInfo, err := describeCertificateKey(cert.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(cert.Raw)
+	info = append(info, keyInfo...)
+
+	return append(info, [2]string{"SHA-256", fmt.Sprintf("%x", digest)}), nil
+}
+
+// Describe a CSR as (label, value) pairs for 'foil x509 show'
+func describeCSR(csr *x509.CertificateRequest) ([][2]string, error) {
+
+	var ips, uris []string
+
+	for _, ip := range csr.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	for _, uri := range csr.URIs {
+		uris = append(uris, uri.String())
+	}
+	info := [][2]string{{"Subject", csr.Subject.String()}}
+	if sans := describeSANs(csr.DNSNames, ips, csr.EmailAddresses, uris); len(sans) > 0 {
+		info = append(info, [2]string{"SANs", sans})
+	}
+	usage, extUsage, err := cryptospecials.CSRKeyUsage(csr)
+	if err != nil {
+		return nil, err
+	}
+	if usages := cryptospecials.KeyUsageNames(usage, extUsage); len(usages) > 0 {
+		info = append(info, [2]string{"Key usage", strings.Join(usages, ", ")})
+	}
+	info = append(info, [2]string{"Signature", csr.SignatureAlgorithm.String()})
+	if csr.CheckSignature() == nil {
+		info = append(info, [2]string{"Signature check", "the signature is valid"})
+	} else {
+		info = append(info, [2]string{"Signature check", "the signature is NOT valid"})
+	}
+
+	keyInfo, err := describeCertificateKey(csr.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(info, keyInfo...), nil
+}
+
+// Print every certificate and CSR in the input
+func x509Show(cmd *cobra.Command, args []string) error {
+
+	var (
+		data   []byte
+		source string
+		err    error
+	)
+
+	if len(stdInString) > 0 {
+		data, source = []byte(stdInString), "StdIn"
+	} else {
+		data, err = helpers.ReadInput(inputPath)
+		if err != nil {
+			return err
+		}
+		source = inputPath
+	}
+	certs, csrs, err := cryptospecials.ParseCertificates(data, source)
+	if err != nil {
+		return err
+	}
+
+	total, shown := len(certs)+len(csrs), 0
+	show := func(kind string, info [][2]string) {
+		if shown > 0 {
+			fmt.Fprintln(helpers.Messages)
+		}
+		shown++
+		fmt.Fprintf(helpers.Messages, "%s %d of %d\n", kind, shown, total)
+		for _, line := range info {
+			fmt.Fprintf(helpers.Messages, "  %-18s %s\n", line[0]+":", line[1])
+		}
+	}
+	for _, cert := range certs {
+		info, err := describeCertificate(cert)
+		if err != nil {
+			return err
+		}
+		show("Certificate", info)
+	}
+	for _, csr := range csrs {
+		info, err := describeCSR(csr)
+		if err != nil {
+			return err
+		}
+		show("CSR", info)
+	}
+
+	return nil
+}
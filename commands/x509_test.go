@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"crypto/elliptic"
+	"foil/cryptospecials"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Make a CSR and a self-signed certificate with an EC key and show both
+func TestX509Cmd(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "foil-x509")
+	if err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() {
+		inputPath, outputPath, x509Subject, x509NotBefore = "", "", "", ""
+		x509SANs, x509KeyUsage, x509Days, x509CA = nil, nil, 365, false
+	}()
+	Verbose, stdInString, stdOutBool = false, "", false
+
+	ecKey, _ := cryptospecials.EccPrivKeyGen(elliptic.P256())
+	keyPath := dir + "/ec.pem"
+	if err = cryptospecials.EccKeySave(ecKey, keyPath, keyPath+".pub"); err != nil {
+		t.Fatalf("FAIL - EccKeySave - %v", err)
+	}
+
+	inputPath, outputPath = keyPath, dir+"/ec.csr"
+	x509Subject, x509SANs, x509KeyUsage, x509Days = "/CN=foil.example/O=Foil", []string{"foil.example", "127.0.0.1"}, []string{"serverAuth"}, 30
+	if err = x509PreChecks(x509CSRCmd, nil); err != nil {
+		t.Fatalf("FAIL - x509PreChecks - %v", err)
+	}
+	if err = x509CSR(nil, nil); err != nil {
+		t.Fatalf("FAIL - x509CSR - %v", err)
+	}
+
+	outputPath, x509NotBefore, x509CA = dir+"/ec.crt", "2020-01-01", true
+	if err = x509SelfSign(nil, nil); err != nil {
+		t.Fatalf("FAIL - x509SelfSign - %v", err)
+	}
+
+	// Both must be read back and shown, the CSR and certificate from a single file
+	csrPEM, _ := ioutil.ReadFile(dir + "/ec.csr")
+	certPEM, _ := ioutil.ReadFile(dir + "/ec.crt")
+	certs, csrs, err := cryptospecials.ParseCertificates(append(certPEM, csrPEM...), "both")
+	if err != nil || len(certs) != 1 || len(csrs) != 1 {
+		t.Fatalf("FAIL - ParseCertificates - %v", err)
+	}
+	cert := certs[0]
+	if cert.Subject.CommonName != "foil.example" || !cert.IsCA || cert.NotBefore.Year() != 2020 || cert.NotAfter.Sub(cert.NotBefore).Hours() != 30*24 {
+		t.Errorf("FAIL - The certificate does not match the flags: %v %v %v", cert.Subject, cert.NotBefore, cert.NotAfter)
+	}
+	if !ecKey.PublicKey.Equal(cert.PublicKey) || !ecKey.PublicKey.Equal(csrs[0].PublicKey) {
+		t.Errorf("FAIL - The certificate or CSR does not hold the public key")
+	}
+	inputPath, outputPath = dir+"/ec.crt", ""
+	if err = x509Show(nil, nil); err != nil {
+		t.Errorf("FAIL - x509Show - %v", err)
+	}
+
+	// A public key can not sign
+	inputPath, outputPath = keyPath+".pub", dir+"/pub.csr"
+	if err = x509CSR(nil, nil); err == nil {
+		t.Errorf("FAIL - x509CSR accepted a public key")
+	}
+}
@@ -14,7 +14,11 @@ import (
 	"crypto/elliptic"
 	"fmt"
 	"hash"
+	"io/ioutil"
 	"math/big"
+	"os"
+
+	"foil/helpers"
 )
 
 // big.Int representation of Zero & One
@@ -24,6 +28,16 @@ var (
 	one   = new(big.Int).SetInt64(int64(1))
 )
 
+// readSource reads a key file; a sourcePath of "-" reads from StdIn
+func readSource(sourcePath string) ([]byte, error) {
+
+	if sourcePath == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+
+	return ioutil.ReadFile(sourcePath)
+}
+
 //ECPoint is an exportable struct
 type ECPoint struct {
 	X *big.Int
@@ -95,17 +109,17 @@ func Hash2curve(data []byte, h hash.Hash, ec *elliptic.CurveParams, curveType in
 	}
 
 	if verbose {
-		fmt.Printf("******\n\nHash2Curve\n\n******")
-		fmt.Println("Length of xByte:", len(xByte))
-		fmt.Println("P:", ec.P)
-		fmt.Println("N:", ec.N)
-		fmt.Println("B:", ec.B)
-
-		fmt.Printf("Number of Try & Increment iterations: %d\n", counter)
-		fmt.Println("x-xoordinate           :", pt.X)
-		fmt.Println("y-coordinate           :", pt.Y)
-		fmt.Println("x-coordinate bit length:", pt.X.BitLen())
-		fmt.Println("y-coordinate bit length:", pt.Y.BitLen())
+		fmt.Fprintf(helpers.Messages, "******\n\nHash2Curve\n\n******")
+		fmt.Fprintln(helpers.Messages, "Length of xByte:", len(xByte))
+		fmt.Fprintln(helpers.Messages, "P:", ec.P)
+		fmt.Fprintln(helpers.Messages, "N:", ec.N)
+		fmt.Fprintln(helpers.Messages, "B:", ec.B)
+
+		fmt.Fprintf(helpers.Messages, "Number of Try & Increment iterations: %d\n", counter)
+		fmt.Fprintln(helpers.Messages, "x-xoordinate           :", pt.X)
+		fmt.Fprintln(helpers.Messages, "y-coordinate           :", pt.Y)
+		fmt.Fprintln(helpers.Messages, "x-coordinate bit length:", pt.X.BitLen())
+		fmt.Fprintln(helpers.Messages, "y-coordinate bit length:", pt.Y.BitLen())
 	}
 
 	// Double check that the point (x,y) is on the provided elliptic curve
@@ -0,0 +1,162 @@
+/*
+*	This package contains mechanisms that will allow for VRF and OPRF calculations.
+*
+*	OPRF: https://eprint.iacr.org/2017/111
+*
+*	RSA-VRF: https://eprint.iacr.org/2017/099.pdf
+*
+*		-Brian
+ */
+
+package cryptospecials
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+//Ed25519KeyGen is an exportable function
+// Ed25519KeyGen generates a random Ed25519 (RFC 8032) signing key
+func Ed25519KeyGen() (privKey ed25519.PrivateKey, err error) {
+
+	_, privKey, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return privKey, nil
+}
+
+//X25519KeyGen is an exportable function
+// X25519KeyGen generates a random X25519 (RFC 7748) key agreement key
+func X25519KeyGen() (privKey *ecdh.PrivateKey, err error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+//Curve25519KeySave is an exportable function
+/*
+*  Curve25519KeySave saves an ed25519.PrivateKey or an X25519 *ecdh.PrivateKey as a PKCS#8
+*  "PRIVATE KEY" PEM at savePriv and its public key as a PKIX "PUBLIC KEY" PEM at savePub.
+*  Either path may be empty to skip that file.
+ */
+func Curve25519KeySave(privKey crypto.PrivateKey, savePriv string, savePub string) (err error) {
+
+	var (
+		keyBytes []byte
+		pubKey   crypto.PublicKey
+	)
+
+	switch key := privKey.(type) {
+	case ed25519.PrivateKey:
+		pubKey = key.Public()
+	case *ecdh.PrivateKey:
+		if key.Curve() != ecdh.X25519() {
+			return errors.New("Error: Only X25519 ecdh keys are Curve25519 keys")
+		}
+		pubKey = key.PublicKey()
+	default:
+		return fmt.Errorf("Error: %T is not a Curve25519 private key", privKey)
+	}
+
+	if len(savePriv) > 0 {
+		keyBytes, err = x509.MarshalPKCS8PrivateKey(privKey)
+		if err != nil {
+			return err
+		}
+		err = writePEM(savePriv, "PRIVATE KEY", keyBytes, 0600)
+		if err != nil {
+			return err
+		}
+	}
+	if len(savePub) > 0 {
+		keyBytes, err = x509.MarshalPKIXPublicKey(pubKey)
+		if err != nil {
+			return err
+		}
+		err = writePEM(savePub, "PUBLIC KEY", keyBytes, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePEM writes a single PEM block to savePath; perm is also applied to an existing file
+func writePEM(savePath string, blockType string, der []byte, perm os.FileMode) error {
+	return writeKeyFile(savePath, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), perm)
+}
+
+/*
+*  writeKeyFile writes data to savePath with permissions perm. An existing file is truncated and
+*  given perm before anything is written, so a private key never lands in a file others can read.
+ */
+func writeKeyFile(savePath string, data []byte, perm os.FileMode) error {
+
+	saveFile, err := os.OpenFile(savePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	err = saveFile.Chmod(perm)
+	if err == nil {
+		_, err = saveFile.Write(data)
+	}
+	if closeErr := saveFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+//Ed25519PrivKeyLoad is an exportable function
+// Ed25519PrivKeyLoad loads an Ed25519 private key in any format read by LoadKey
+func Ed25519PrivKeyLoad(sourcePath string) (privKey ed25519.PrivateKey, err error) {
+
+	key, err := LoadKey(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.Ed25519Private()
+}
+
+//Ed25519PubKeyLoad is an exportable function
+// Ed25519PubKeyLoad loads an Ed25519 public key (or the public half of a private key) in any format read by LoadKey
+func Ed25519PubKeyLoad(sourcePath string) (pubKey ed25519.PublicKey, err error) {
+
+	key, err := LoadKey(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.Ed25519Public()
+}
+
+//X25519PrivKeyLoad is an exportable function
+// X25519PrivKeyLoad loads an X25519 private key in any format read by LoadKey
+func X25519PrivKeyLoad(sourcePath string) (privKey *ecdh.PrivateKey, err error) {
+
+	key, err := LoadKey(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	return key.X25519Private()
+}
+
+//X25519PubKeyLoad is an exportable function
+// X25519PubKeyLoad loads an X25519 public key (or the public half of a private key) in any format read by LoadKey
+func X25519PubKeyLoad(sourcePath string) (pubKey *ecdh.PublicKey, err error) {
+
+	key, err := LoadKey(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	return key.X25519Public()
+}
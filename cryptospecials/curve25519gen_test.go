@@ -0,0 +1,68 @@
+package cryptospecials
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"os"
+	"testing"
+)
+
+func TestCurve25519GenSaveLoad(t *testing.T) {
+
+	defer os.Remove("edPriv.pem")
+	defer os.Remove("edPub.pem")
+	defer os.Remove("xPriv.pem")
+	defer os.Remove("xPub.pem")
+
+	edKey, err := Ed25519KeyGen()
+	if err != nil {
+		t.Fatalf("FAIL - Ed25519KeyGen - %v", err)
+	}
+	err = Curve25519KeySave(edKey, "edPriv.pem", "edPub.pem")
+	if err != nil {
+		t.Fatalf("FAIL - Curve25519KeySave - %v", err)
+	}
+	loadEdKey, err := Ed25519PrivKeyLoad("edPriv.pem")
+	if err != nil || !loadEdKey.Equal(edKey) {
+		t.Errorf("FAIL - Ed25519PrivKeyLoad - %v", err)
+	}
+	loadEdPub, err := Ed25519PubKeyLoad("edPub.pem")
+	if err != nil || !loadEdPub.Equal(edKey.Public()) {
+		t.Errorf("FAIL - Ed25519PubKeyLoad - %v", err)
+	}
+	sig := ed25519.Sign(loadEdKey, []byte("LegitString"))
+	if !ed25519.Verify(loadEdPub, []byte("LegitString"), sig) {
+		t.Errorf("FAIL - Loaded Ed25519 keys do not sign and verify")
+	}
+	if info, err := os.Stat("edPriv.pem"); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("FAIL - Curve25519 private key PEM is not 0600")
+	}
+
+	xKey, err := X25519KeyGen()
+	if err != nil {
+		t.Fatalf("FAIL - X25519KeyGen - %v", err)
+	}
+	err = Curve25519KeySave(xKey, "xPriv.pem", "xPub.pem")
+	if err != nil {
+		t.Fatalf("FAIL - Curve25519KeySave - %v", err)
+	}
+	loadXKey, err := X25519PrivKeyLoad("xPriv.pem")
+	if err != nil || !bytes.Equal(loadXKey.Bytes(), xKey.Bytes()) {
+		t.Errorf("FAIL - X25519PrivKeyLoad - %v", err)
+	}
+	loadXPub, err := X25519PubKeyLoad("xPub.pem")
+	if err != nil || !loadXPub.Equal(xKey.PublicKey()) {
+		t.Errorf("FAIL - X25519PubKeyLoad - %v", err)
+	}
+
+	// Each loader must reject the other kind of key
+	if _, err = Ed25519PrivKeyLoad("xPriv.pem"); err == nil {
+		t.Errorf("FAIL - Ed25519PrivKeyLoad accepted an X25519 key")
+	}
+	if _, err = X25519PubKeyLoad("edPub.pem"); err == nil {
+		t.Errorf("FAIL - X25519PubKeyLoad accepted an Ed25519 key")
+	}
+	if _, err = EccPrivKeyLoad("edPriv.pem"); err == nil {
+		t.Errorf("FAIL - EccPrivKeyLoad accepted an Ed25519 key")
+	}
+}
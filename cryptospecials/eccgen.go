@@ -14,12 +14,50 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/x509"
-	"encoding/pem"
-	"io/ioutil"
-	"os"
+	"fmt"
+	"hash"
+	"strings"
 )
 
+//EccCurveByName is an exportable function
+/*
+*  EccCurveByName maps a curve name onto a NIST curve. P-256, P-384, and P-521 are accepted
+*  without regard to case or the dash (e.g. "p384"); as are the SEC names (e.g. "secp384r1").
+ */
+func EccCurveByName(name string) (ec elliptic.Curve, err error) {
+
+	switch strings.ToUpper(strings.Replace(name, "-", "", -1)) {
+	case "P256", "SECP256R1", "PRIME256V1":
+		return elliptic.P256(), nil
+	case "P384", "SECP384R1":
+		return elliptic.P384(), nil
+	case "P521", "SECP521R1":
+		return elliptic.P521(), nil
+	}
+
+	return nil, fmt.Errorf("Error: Unsupported curve %q; use P-256, P-384, or P-521", name)
+}
+
+//EccHashForCurve is an exportable function
+/*
+*  EccHashForCurve returns the SHA-2 hash matching the security level of ec: SHA-256 for
+*  P-256, SHA-384 for P-384, and SHA-512 for P-521.
+ */
+func EccHashForCurve(ec elliptic.Curve) hash.Hash {
+
+	switch ec.Params().BitSize {
+	case 384:
+		return sha512.New384()
+	case 521:
+		return sha512.New()
+	}
+
+	return sha256.New()
+}
+
 //EccPrivKeyGen is an exportable function
 /*
 *  EccPrivKeyGen uses the ecdsa package to neatly generate a random key 's' with
@@ -37,42 +75,39 @@ func EccPrivKeyGen(ec elliptic.Curve) (privKey *ecdsa.PrivateKey, err error) {
 }
 
 //EccKeySave is an exportable function
+/*
+*  EccKeySave saves the private key at savePriv (0600) and its public key as a PKIX PEM at
+*  savePub. Either path may be empty to skip that file.
+ */
 func EccKeySave(privKey *ecdsa.PrivateKey, savePriv string, savePub string) (err error) {
 
 	var (
-		keyBytes     []byte
-		savePrivFile *os.File
-		savePubFile  *os.File
+		keyBytes []byte
 	)
 
-	// Attempt to open file at savePriv
-	savePrivFile, err = os.Create(savePriv)
-	if err != nil {
-		return err
-	}
-	defer savePrivFile.Close()
-	// Encode the private key as DER bytes
-	keyBytes, err = x509.MarshalECPrivateKey(privKey)
-	if err != nil {
-		return err
+	if len(savePriv) > 0 {
+		// Encode the private key as PKCS#8 DER bytes to match the "PRIVATE KEY" label
+		keyBytes, err = x509.MarshalPKCS8PrivateKey(privKey)
+		if err != nil {
+			return err
+		}
+		// Write the PEM to savePriv; only the owner may read it
+		err = writePEM(savePriv, "PRIVATE KEY", keyBytes, 0600)
+		if err != nil {
+			return err
+		}
 	}
-	// Write the PEM to saveFile
-	err = pem.Encode(savePrivFile, &pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes})
 
-	// Attempt to open file at savePub
-	savePubFile, err = os.Create(savePub)
-	if err != nil {
-		return err
-	}
-	defer savePubFile.Close()
-	// Encode the public key as DER bytes
-	keyBytes, err = x509.MarshalPKIXPublicKey(&privKey.PublicKey)
-	if err != nil {
-		return err
-	}
-	err = pem.Encode(savePubFile, &pem.Block{Type: "PUBLIC KEY", Bytes: keyBytes})
-	if err != nil {
-		return err
+	if len(savePub) > 0 {
+		// Encode the public key as DER bytes
+		keyBytes, err = x509.MarshalPKIXPublicKey(&privKey.PublicKey)
+		if err != nil {
+			return err
+		}
+		err = writePEM(savePub, "PUBLIC KEY", keyBytes, 0644)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -80,47 +115,25 @@ func EccKeySave(privKey *ecdsa.PrivateKey, savePriv string, savePub string) (err
 }
 
 //EccPrivKeyLoad is an exportable function
+// EccPrivKeyLoad loads an EC private key in any format read by LoadKey
 func EccPrivKeyLoad(sourcePath string) (privKey *ecdsa.PrivateKey, err error) {
 
-	var (
-		readFile []byte
-		swap     *pem.Block
-	)
-
-	// Attempt to open ReadFile at sourcePath
-	readFile, err = ioutil.ReadFile(sourcePath)
-	if err != nil {
-		return nil, err
-	}
-	swap, _ = pem.Decode(readFile)
-	privKey, err = x509.ParseECPrivateKey(swap.Bytes)
+	key, err := LoadKey(sourcePath)
 	if err != nil {
 		return nil, err
 	}
 
-	return privKey, nil
+	return key.ECPrivate()
 }
 
 //EccPubKeyLoad is an exportable function
+// EccPubKeyLoad loads an EC public key (or the public half of a private key) in any format read by LoadKey
 func EccPubKeyLoad(sourcePath string) (pubKey *ecdsa.PublicKey, err error) {
 
-	var (
-		readFile []byte
-		swap     *pem.Block
-		ipubKey  interface{}
-	)
-
-	// Attempt to open ReadFile at sourcePath
-	readFile, err = ioutil.ReadFile(sourcePath)
-	if err != nil {
-		return nil, err
-	}
-	swap, _ = pem.Decode(readFile)
-	ipubKey, err = x509.ParsePKIXPublicKey(swap.Bytes)
+	key, err := LoadKey(sourcePath)
 	if err != nil {
 		return nil, err
 	}
-	pubKey = ipubKey.(*ecdsa.PublicKey)
 
-	return pubKey, nil
+	return key.ECPublic()
 }
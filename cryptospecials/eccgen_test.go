@@ -3,6 +3,9 @@ package cryptospecials
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
 	"testing"
 )
 
@@ -40,3 +43,43 @@ func TestEccGenSaveLoad(t *testing.T) {
 
 	_, _ = loadPrivKey, loadPubKey
 }
+
+// Keys on every supported curve must load back on the same curve; in SEC 1 or PKCS#8 form
+func TestEccCurves(t *testing.T) {
+
+	dir := t.TempDir()
+	privPath, pubPath := dir+"/ecCurvePriv.pem", dir+"/ecCurvePub.pem"
+	for _, name := range []string{"P-256", "p384", "secp521r1"} {
+		ec, err := EccCurveByName(name)
+		if err != nil {
+			t.Fatalf("FAIL - EccCurveByName(%s) - %v", name, err)
+		}
+		privKey, _ := EccPrivKeyGen(ec)
+		err = EccKeySave(privKey, privPath, pubPath)
+		if err != nil {
+			t.Fatalf("FAIL - EccKeySave - %v", err)
+		}
+		loadPrivKey, err := EccPrivKeyLoad(privPath)
+		if err != nil || loadPrivKey.Curve != ec || loadPrivKey.D.Cmp(privKey.D) != 0 {
+			t.Errorf("FAIL - EccPrivKeyLoad (%s) - %v", name, err)
+		}
+		loadPubKey, err := EccPubKeyLoad(pubPath)
+		if err != nil || loadPubKey.Curve != ec {
+			t.Errorf("FAIL - EccPubKeyLoad (%s) - %v", name, err)
+		}
+		if EccHashForCurve(ec).Size()*8 < ec.Params().BitSize/2 {
+			t.Errorf("FAIL - EccHashForCurve (%s) is too short", name)
+		}
+
+		der, _ := x509.MarshalPKCS8PrivateKey(privKey)
+		ioutil.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+		loadPrivKey, err = EccPrivKeyLoad(privPath)
+		if err != nil || loadPrivKey.Curve != ec {
+			t.Errorf("FAIL - EccPrivKeyLoad of PKCS#8 (%s) - %v", name, err)
+		}
+	}
+
+	if _, err := EccCurveByName("P-224"); err == nil {
+		t.Errorf("FAIL - EccCurveByName accepted P-224")
+	}
+}
@@ -17,6 +17,8 @@ import (
 	"fmt"
 	"hash"
 	"math/big"
+
+	"foil/helpers"
 )
 
 //OPRF is an exportable struct
@@ -82,15 +84,15 @@ func (rep OPRF) Mask(data string, h hash.Hash, ec elliptic.Curve, verbose bool)
 	}
 
 	if verbose {
-		fmt.Println("Number of random bytes read:", numRead)
-		fmt.Println("Size of H(data)            :", len(hData))
-		fmt.Println("SECRET x-coordinate:", pt.X)
-		fmt.Println("SECRET y-coordinate:", pt.Y)
-		fmt.Println("SECRET r           :", r)
-		fmt.Println("SECRET r-inv       :", rInv)
-		fmt.Println("Masked x-coordinate:", mask.X)
-		fmt.Println("Masked y-coordinate:", mask.Y)
-		fmt.Println("Is Masked (x,y) on the curve:", ec.IsOnCurve(mask.X, mask.Y))
+		fmt.Fprintln(helpers.Messages, "Number of random bytes read:", numRead)
+		fmt.Fprintln(helpers.Messages, "Size of H(data)            :", len(hData))
+		fmt.Fprintln(helpers.Messages, "SECRET x-coordinate:", pt.X)
+		fmt.Fprintln(helpers.Messages, "SECRET y-coordinate:", pt.Y)
+		fmt.Fprintln(helpers.Messages, "SECRET r           :", r)
+		fmt.Fprintln(helpers.Messages, "SECRET r-inv       :", rInv)
+		fmt.Fprintln(helpers.Messages, "Masked x-coordinate:", mask.X)
+		fmt.Fprintln(helpers.Messages, "Masked y-coordinate:", mask.Y)
+		fmt.Fprintln(helpers.Messages, "Is Masked (x,y) on the curve:", ec.IsOnCurve(mask.X, mask.Y))
 	}
 
 	// (x1,y1) = r*(x,y) : x, y <-- H(data) into ec
@@ -116,16 +118,16 @@ func (rep OPRF) Salt(mask ECPoint, s *big.Int, ec elliptic.Curve, verbose bool)
 		rand.Reader.Read(randBytes)
 		s.SetBytes(randBytes)
 		s.Mod(s, ec.Params().N)
-		fmt.Println("SECRET - s (new)  :", s)
+		fmt.Fprintln(helpers.Messages, "SECRET - s (new)  :", s)
 	}
 
 	salt.X, salt.Y = ec.ScalarMult(mask.X, mask.Y, s.Bytes())
 
 	if verbose {
-		fmt.Println("SECRET - s (used)  :", s)
-		fmt.Println("Salted x-coordinate:", salt.X)
-		fmt.Println("Salted y-coordinate:", salt.Y)
-		fmt.Println("Is Salted (x, y) on the curve:", ec.IsOnCurve(salt.X, salt.Y))
+		fmt.Fprintln(helpers.Messages, "SECRET - s (used)  :", s)
+		fmt.Fprintln(helpers.Messages, "Salted x-coordinate:", salt.X)
+		fmt.Fprintln(helpers.Messages, "Salted y-coordinate:", salt.Y)
+		fmt.Fprintln(helpers.Messages, "Is Salted (x, y) on the curve:", ec.IsOnCurve(salt.X, salt.Y))
 	}
 
 	return salt, s, nil
@@ -142,9 +144,9 @@ func (rep OPRF) Unmask(salt ECPoint, rInv *big.Int, ec elliptic.Curve, verbose b
 	unmask.X, unmask.Y = ec.ScalarMult(salt.X, salt.Y, rInv.Bytes())
 
 	if verbose {
-		fmt.Println("Unmasked x-coordinate:", unmask.X)
-		fmt.Println("Unmasked y-coordinate:", unmask.Y)
-		fmt.Println("Is Unmasked (x, y) on the curve:", ec.IsOnCurve(unmask.X, unmask.Y))
+		fmt.Fprintln(helpers.Messages, "Unmasked x-coordinate:", unmask.X)
+		fmt.Fprintln(helpers.Messages, "Unmasked y-coordinate:", unmask.Y)
+		fmt.Fprintln(helpers.Messages, "Is Unmasked (x, y) on the curve:", ec.IsOnCurve(unmask.X, unmask.Y))
 	}
 	return unmask, nil
 }
@@ -167,7 +169,7 @@ func (rep OPRF) unsalt(unmask ECPoint, s *big.Int, ec elliptic.Curve, verbose bo
 	unsalt.X, unsalt.Y = ec.ScalarMult(unmask.X, unmask.Y, sInv.Bytes())
 
 	if verbose {
-		fmt.Println("Is unsalted (x, y) on the curve:", ec.IsOnCurve(unsalt.X, unsalt.Y))
+		fmt.Fprintln(helpers.Messages, "Is unsalted (x, y) on the curve:", ec.IsOnCurve(unsalt.X, unsalt.Y))
 	}
 
 	return unsalt, nil
@@ -15,10 +15,11 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/sha256"
 	"fmt"
 	"hash"
 	"math/big"
+
+	"foil/helpers"
 )
 
 //Proof is an exportable struct
@@ -151,7 +152,7 @@ func (rep ECCVRF) Generate(h hash.Hash, ec elliptic.Curve, privKey *ecdsa.Privat
 	c.SetBytes(swap)
 	c.Mod(c, ec.Params().N)
 	if verbose {
-		fmt.Printf("c - Calulated (hex): %x\n", swap)
+		fmt.Fprintf(helpers.Messages, "c - Calulated (hex): %x\n", swap)
 	}
 
 	// *** Step (4) ****
@@ -182,30 +183,30 @@ func (rep ECCVRF) Generate(h hash.Hash, ec elliptic.Curve, privKey *ecdsa.Privat
 
 	if verbose {
 
-		fmt.Printf("SECRET - x      : %v\n", privKey.D)
-		fmt.Printf("SECRET - k      : %v\n\n", k)
-
-		fmt.Printf("Public - xGx    : %v\n", privKey.X)
-		fmt.Printf("Public - xGy    : %v\n", privKey.Y)
-		fmt.Printf("Public - s      : %v\n", s)
-		fmt.Printf("Public - c      : %v\n", c)
-		fmt.Printf("Public - h^x - x: %v\n", pth2.X)
-		fmt.Printf("Public - h^x - y: %v\n", pth2.Y)
-		fmt.Printf("Public - beta (hex): %x\n\n", beta)
-
-		fmt.Println("c - Inputs:")
-		fmt.Printf("  G - x     (hex): %x\n", ec.Params().Gx.Bytes())
-		fmt.Printf("  G - y     (hex): %x\n", ec.Params().Gy.Bytes())
-		fmt.Printf("  h1(a) - x (hex): %x\n", pth1.X.Bytes())
-		fmt.Printf("  h1(a) - y (hex): %x\n", pth1.Y.Bytes())
-		fmt.Printf("  PubK - x  (hex): %x\n", privKey.X.Bytes())
-		fmt.Printf("  PubK - y  (hex): %x\n", privKey.Y.Bytes())
-		fmt.Printf("  h2 - x    (hex): %x\n", pth2.X.Bytes())
-		fmt.Printf("  h2 - y    (hex): %x\n", pth2.Y.Bytes())
-		fmt.Printf("  g^k - x   (hex): %x\n", ptgk.X.Bytes())
-		fmt.Printf("  g^k - y   (hex): %x\n", ptgk.Y.Bytes())
-		fmt.Printf("  h^k - x   (hex): %x\n", pthk.X.Bytes())
-		fmt.Printf("  h^k - y   (hex): %x\n\n", pthk.Y.Bytes())
+		fmt.Fprintf(helpers.Messages, "SECRET - x      : %v\n", privKey.D)
+		fmt.Fprintf(helpers.Messages, "SECRET - k      : %v\n\n", k)
+
+		fmt.Fprintf(helpers.Messages, "Public - xGx    : %v\n", privKey.X)
+		fmt.Fprintf(helpers.Messages, "Public - xGy    : %v\n", privKey.Y)
+		fmt.Fprintf(helpers.Messages, "Public - s      : %v\n", s)
+		fmt.Fprintf(helpers.Messages, "Public - c      : %v\n", c)
+		fmt.Fprintf(helpers.Messages, "Public - h^x - x: %v\n", pth2.X)
+		fmt.Fprintf(helpers.Messages, "Public - h^x - y: %v\n", pth2.Y)
+		fmt.Fprintf(helpers.Messages, "Public - beta (hex): %x\n\n", beta)
+
+		fmt.Fprintln(helpers.Messages, "c - Inputs:")
+		fmt.Fprintf(helpers.Messages, "  G - x     (hex): %x\n", ec.Params().Gx.Bytes())
+		fmt.Fprintf(helpers.Messages, "  G - y     (hex): %x\n", ec.Params().Gy.Bytes())
+		fmt.Fprintf(helpers.Messages, "  h1(a) - x (hex): %x\n", pth1.X.Bytes())
+		fmt.Fprintf(helpers.Messages, "  h1(a) - y (hex): %x\n", pth1.Y.Bytes())
+		fmt.Fprintf(helpers.Messages, "  PubK - x  (hex): %x\n", privKey.X.Bytes())
+		fmt.Fprintf(helpers.Messages, "  PubK - y  (hex): %x\n", privKey.Y.Bytes())
+		fmt.Fprintf(helpers.Messages, "  h2 - x    (hex): %x\n", pth2.X.Bytes())
+		fmt.Fprintf(helpers.Messages, "  h2 - y    (hex): %x\n", pth2.Y.Bytes())
+		fmt.Fprintf(helpers.Messages, "  g^k - x   (hex): %x\n", ptgk.X.Bytes())
+		fmt.Fprintf(helpers.Messages, "  g^k - y   (hex): %x\n", ptgk.Y.Bytes())
+		fmt.Fprintf(helpers.Messages, "  h^k - x   (hex): %x\n", pthk.X.Bytes())
+		fmt.Fprintf(helpers.Messages, "  h^k - y   (hex): %x\n\n", pthk.Y.Bytes())
 
 	}
 
@@ -259,8 +260,9 @@ func (rep ECCVRF) Verify(h hash.Hash, pubK *ecdsa.PublicKey, ec elliptic.Curve,
 	if ec.IsOnCurve(eccProof.X, eccProof.Y) == false {
 		return false, fmt.Errorf("Error: The lambda provided is not on the provided elliptic curve")
 	}
-	// Note: using sha256; this should be abstracted to other functions at some point
-	h1, err = Hash2curve(alpha, sha256.New(), ec.Params(), 1, verbose)
+	// H_1 uses the same hash as Generate
+	h.Reset()
+	h1, err = Hash2curve(alpha, h, ec.Params(), 1, verbose)
 	if err != nil {
 		return false, err
 	}
@@ -299,39 +301,39 @@ func (rep ECCVRF) Verify(h hash.Hash, pubK *ecdsa.PublicKey, ec elliptic.Curve,
 
 	if verbose {
 
-		fmt.Printf("Public - s      : %v\n", eccProof.S)
-		fmt.Printf("Public - h^x - x: %v\n", eccProof.X)
-		fmt.Printf("Public - h^x - y: %v\n", eccProof.Y)
-		fmt.Println("PubK = (x, y)")
-		fmt.Printf("  x             : %v\n", pubK.X)
-		fmt.Printf("  y             : %v\n", pubK.Y)
-		fmt.Println("u = (x, y)")
-		fmt.Printf("  x             : %v\n", u.X)
-		fmt.Printf("  y             : %v\n", u.Y)
-		fmt.Println("v = (x, y)")
-		fmt.Printf("  x             : %v\n", v.X)
-		fmt.Printf("  y             : %v\n\n", v.Y)
-
-		fmt.Printf("c - Provided   (hex): %x\n", eccProof.C.Bytes())
-		fmt.Printf("c - Calculated (hex): %x\n", swapByte)
-		fmt.Println("c - Calculated Inputs:")
-		fmt.Printf("  G - x     (hex): %x\n", ec.Params().Gx.Bytes())
-		fmt.Printf("  G - y     (hex): %x\n", ec.Params().Gy.Bytes())
-		fmt.Printf("  h1(a) - x (hex): %x\n", h1.X.Bytes())
-		fmt.Printf("  h1(a) - y (hex): %x\n", h1.Y.Bytes())
-		fmt.Printf("  PubK - x  (hex): %x\n", pubK.X.Bytes())
-		fmt.Printf("  PubK - y  (hex): %x\n", pubK.Y.Bytes())
-		fmt.Printf("  h2 - x    (hex): %x\n", eccProof.X.Bytes())
-		fmt.Printf("  h2 - y    (hex): %x\n", eccProof.Y.Bytes())
-		fmt.Printf("  u - x     (hex): %x\n", u.X.Bytes())
-		fmt.Printf("  u - y     (hex): %x\n", u.Y.Bytes())
-		fmt.Printf("  v - x     (hex): %x\n", v.X.Bytes())
-		fmt.Printf("  v - y     (hex): %x\n\n", v.Y.Bytes())
+		fmt.Fprintf(helpers.Messages, "Public - s      : %v\n", eccProof.S)
+		fmt.Fprintf(helpers.Messages, "Public - h^x - x: %v\n", eccProof.X)
+		fmt.Fprintf(helpers.Messages, "Public - h^x - y: %v\n", eccProof.Y)
+		fmt.Fprintln(helpers.Messages, "PubK = (x, y)")
+		fmt.Fprintf(helpers.Messages, "  x             : %v\n", pubK.X)
+		fmt.Fprintf(helpers.Messages, "  y             : %v\n", pubK.Y)
+		fmt.Fprintln(helpers.Messages, "u = (x, y)")
+		fmt.Fprintf(helpers.Messages, "  x             : %v\n", u.X)
+		fmt.Fprintf(helpers.Messages, "  y             : %v\n", u.Y)
+		fmt.Fprintln(helpers.Messages, "v = (x, y)")
+		fmt.Fprintf(helpers.Messages, "  x             : %v\n", v.X)
+		fmt.Fprintf(helpers.Messages, "  y             : %v\n\n", v.Y)
+
+		fmt.Fprintf(helpers.Messages, "c - Provided   (hex): %x\n", eccProof.C.Bytes())
+		fmt.Fprintf(helpers.Messages, "c - Calculated (hex): %x\n", swapByte)
+		fmt.Fprintln(helpers.Messages, "c - Calculated Inputs:")
+		fmt.Fprintf(helpers.Messages, "  G - x     (hex): %x\n", ec.Params().Gx.Bytes())
+		fmt.Fprintf(helpers.Messages, "  G - y     (hex): %x\n", ec.Params().Gy.Bytes())
+		fmt.Fprintf(helpers.Messages, "  h1(a) - x (hex): %x\n", h1.X.Bytes())
+		fmt.Fprintf(helpers.Messages, "  h1(a) - y (hex): %x\n", h1.Y.Bytes())
+		fmt.Fprintf(helpers.Messages, "  PubK - x  (hex): %x\n", pubK.X.Bytes())
+		fmt.Fprintf(helpers.Messages, "  PubK - y  (hex): %x\n", pubK.Y.Bytes())
+		fmt.Fprintf(helpers.Messages, "  h2 - x    (hex): %x\n", eccProof.X.Bytes())
+		fmt.Fprintf(helpers.Messages, "  h2 - y    (hex): %x\n", eccProof.Y.Bytes())
+		fmt.Fprintf(helpers.Messages, "  u - x     (hex): %x\n", u.X.Bytes())
+		fmt.Fprintf(helpers.Messages, "  u - y     (hex): %x\n", u.Y.Bytes())
+		fmt.Fprintf(helpers.Messages, "  v - x     (hex): %x\n", v.X.Bytes())
+		fmt.Fprintf(helpers.Messages, "  v - y     (hex): %x\n\n", v.Y.Bytes())
 
 	}
 
-	// Validate Proof.c = Calculated c
-	if bytes.Compare(eccProof.C.Bytes(), swapByte) != 0 {
+	// Validate Proof.c = Calculated c (mod q)
+	if new(big.Int).Mod(new(big.Int).SetBytes(swapByte), ec.Params().N).Cmp(eccProof.C) != 0 {
 		return false, nil
 	}
 
@@ -352,8 +354,8 @@ func (rep ECCVRF) Verify(h hash.Hash, pubK *ecdsa.PublicKey, ec elliptic.Curve,
 	h.Write(eccProof.Y.Bytes())
 	swapByte = h.Sum(nil)
 	if verbose {
-		fmt.Printf("Beta - Calculated (hex): %x\n", swapByte)
-		fmt.Printf("Beta - Provided   (hex): %x\n\n", beta)
+		fmt.Fprintf(helpers.Messages, "Beta - Calculated (hex): %x\n", swapByte)
+		fmt.Fprintf(helpers.Messages, "Beta - Provided   (hex): %x\n\n", beta)
 	}
 	if bytes.Compare(beta, swapByte) != 0 {
 		return false, fmt.Errorf("Validation Error: Beta - provided_beta does not equal calculated_beta")
@@ -0,0 +1,424 @@
+/*
+*	This package contains mechanisms that will allow for VRF and OPRF calculations.
+*
+*	OPRF: https://eprint.iacr.org/2017/111
+*
+*	RSA-VRF: https://eprint.iacr.org/2017/099.pdf
+*
+*	ECVRF: https://www.rfc-editor.org/rfc/rfc9381
+*
+*		-Brian
+ */
+
+package cryptospecials
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+
+	"foil/helpers"
+)
+
+// ECVRF suites of RFC 9381 supported by ECVRF
+const (
+	SuiteECVRFP256SHA256TAI          = "ECVRF-P256-SHA256-TAI"
+	SuiteECVRFEdwards25519SHA512TAI  = "ECVRF-EDWARDS25519-SHA512-TAI"
+	SuiteECVRFEdwards25519SHA512ELL2 = "ECVRF-EDWARDS25519-SHA512-ELL2"
+)
+
+//ECVRF is an exportable struct
+/*
+*  ECVRF is an RFC 9381 verifiable random function. Unlike ECCVRF (based on the NSEC5 paper),
+*  its proofs (pi) and outputs (beta) are byte strings that any other RFC 9381 implementation
+*  of the same suite produces and verifies. Suite names one of the Suite constants.
+ */
+type ECVRF struct {
+	Suite string
+	Proof []byte
+	Alpha []byte
+	Beta  []byte
+}
+
+/*
+*  ecvrfSuite holds the suite_string, lengths, and hash of an RFC 9381 suite and the functions
+*  that work on its curve: prove and verify, and hashGamma which decodes Gamma from a proof and
+*  returns the string of cofactor*Gamma for proof_to_hash.
+ */
+type ecvrfSuite struct {
+	name        string
+	suiteString byte
+	ptLen       int
+	cLen        int
+	qLen        int
+	newHash     func() hash.Hash
+	prove       func(suite ecvrfSuite, privKey crypto.PrivateKey, alpha []byte, verbose bool) ([]byte, error)
+	verify      func(suite ecvrfSuite, pubKey crypto.PublicKey, alpha []byte, pi []byte, verbose bool) (bool, error)
+	hashGamma   func(gammaString []byte) ([]byte, error)
+}
+
+var ecvrfSuiteList = []ecvrfSuite{
+	{name: SuiteECVRFP256SHA256TAI, suiteString: 0x01, ptLen: 33, cLen: 16, qLen: 32, newHash: sha256.New,
+		prove: p256Prove, verify: p256Verify, hashGamma: p256HashGamma},
+	{name: SuiteECVRFEdwards25519SHA512TAI, suiteString: 0x03, ptLen: 32, cLen: 16, qLen: 32, newHash: sha512.New,
+		prove: edwardsProve, verify: edwardsVerify, hashGamma: edwardsHashGamma},
+	{name: SuiteECVRFEdwards25519SHA512ELL2, suiteString: 0x04, ptLen: 32, cLen: 16, qLen: 32, newHash: sha512.New,
+		prove: edwardsProve, verify: edwardsVerify, hashGamma: edwardsHashGamma},
+}
+
+//ECVRFSuites is an exportable function
+// ECVRFSuites lists the names of the supported RFC 9381 suites
+func ECVRFSuites() []string {
+
+	var names []string
+
+	for _, suite := range ecvrfSuiteList {
+		names = append(names, suite.name)
+	}
+
+	return names
+}
+
+// ecvrfSuiteByName finds a suite; names are not case sensitive
+func ecvrfSuiteByName(name string) (ecvrfSuite, error) {
+
+	for _, suite := range ecvrfSuiteList {
+		if strings.EqualFold(name, suite.name) {
+			return suite, nil
+		}
+	}
+
+	return ecvrfSuite{}, fmt.Errorf("Error: Unknown ECVRF suite %q; use [%s]", name, strings.Join(ECVRFSuites(), ", "))
+}
+
+// proofLen is the length of pi: Gamma, c, and s
+func (suite ecvrfSuite) proofLen() int {
+
+	return suite.ptLen + suite.cLen + suite.qLen
+}
+
+// hashString is Hash(suite_string || domain || data... || 0x00), the form of every RFC 9381 hash
+func (suite ecvrfSuite) hashString(domain byte, data ...[]byte) []byte {
+
+	h := suite.newHash()
+	h.Write([]byte{suite.suiteString, domain})
+	for _, d := range data {
+		h.Write(d)
+	}
+	h.Write([]byte{0x00})
+
+	return h.Sum(nil)
+}
+
+/*
+*  encodeToCurveTAI is ECVRF_encode_to_curve_try_and_increment (RFC 9381 section 5.4.1.1). The
+*  salt is the public key string. toPoint interprets a hash as a point and reports whether it
+*  is one; the string of the first point found is returned.
+ */
+func (suite ecvrfSuite) encodeToCurveTAI(salt []byte, alpha []byte, toPoint func(hashString []byte) ([]byte, bool)) ([]byte, error) {
+
+	for ctr := 0; ctr < 256; ctr++ {
+		hashString := suite.hashString(0x01, salt, alpha, []byte{byte(ctr)})
+		if point, ok := toPoint(hashString); ok {
+			return point, nil
+		}
+	}
+
+	return nil, errors.New("Error: encode_to_curve found no point in 256 attempts")
+}
+
+// challenge is ECVRF_challenge_generation (RFC 9381 section 5.4.3) over point strings
+func (suite ecvrfSuite) challenge(points ...[]byte) []byte {
+
+	return suite.hashString(0x02, points...)[:suite.cLen]
+}
+
+// proofToHash is the final hash of ECVRF_proof_to_hash (RFC 9381 section 5.2) of cofactor*Gamma
+func (suite ecvrfSuite) proofToHash(gammaString []byte) []byte {
+
+	return suite.hashString(0x03, gammaString)
+}
+
+/*
+*  rfc6979Nonce is the deterministic nonce of RFC 6979 section 3.2 for the secret x, order q,
+*  and message m: the message is hashed with newHash and HMAC (with the same hash) is the PRF.
+ */
+func rfc6979Nonce(x *big.Int, q *big.Int, newHash func() hash.Hash, m []byte) *big.Int {
+
+	qlen := q.BitLen()
+	rlen := (qlen + 7) / 8
+
+	bits2int := func(b []byte) *big.Int {
+		v := new(big.Int).SetBytes(b)
+		if len(b)*8 > qlen {
+			v.Rsh(v, uint(len(b)*8-qlen))
+		}
+		return v
+	}
+	int2octets := func(v *big.Int) []byte {
+		return v.FillBytes(make([]byte, rlen))
+	}
+	mac := func(key []byte, data ...[]byte) []byte {
+		h := hmac.New(newHash, key)
+		for _, d := range data {
+			h.Write(d)
+		}
+		return h.Sum(nil)
+	}
+
+	h1 := newHash()
+	h1.Write(m)
+	z := bits2int(h1.Sum(nil))
+	z.Mod(z, q)
+	seed := append(int2octets(x), int2octets(z)...)
+
+	// Steps (b) through (g)
+	hLen := newHash().Size()
+	V := bytes.Repeat([]byte{0x01}, hLen)
+	K := make([]byte, hLen)
+	K = mac(K, V, []byte{0x00}, seed)
+	V = mac(K, V)
+	K = mac(K, V, []byte{0x01}, seed)
+	V = mac(K, V)
+
+	// Step (h)
+	for {
+		var T []byte
+		for len(T) < rlen {
+			V = mac(K, V)
+			T = append(T, V...)
+		}
+		k := bits2int(T[:rlen])
+		if k.Sign() > 0 && k.Cmp(q) < 0 {
+			return k
+		}
+		K = mac(K, V, []byte{0x00})
+		V = mac(K, V)
+	}
+}
+
+// p256EncodeToCurve hashes alpha to a P-256 point; string_to_point(0x02 || hash) for each try
+func p256EncodeToCurve(suite ecvrfSuite, pkString []byte, alpha []byte) (hx *big.Int, hy *big.Int, hString []byte, err error) {
+
+	ec := elliptic.P256()
+	hString, err = suite.encodeToCurveTAI(pkString, alpha, func(hashString []byte) ([]byte, bool) {
+		point := append([]byte{0x02}, hashString...)
+		hx, hy = elliptic.UnmarshalCompressed(ec, point)
+		return point, hx != nil
+	})
+
+	return hx, hy, hString, err
+}
+
+// p256Sub returns (x1, y1) - (x2, y2) on P-256
+func p256Sub(x1 *big.Int, y1 *big.Int, x2 *big.Int, y2 *big.Int) (*big.Int, *big.Int) {
+
+	ec := elliptic.P256()
+	negY := new(big.Int).Sub(ec.Params().P, y2)
+
+	return ec.Add(x1, y1, x2, negY.Mod(negY, ec.Params().P))
+}
+
+// p256Key checks that a key is a P-256 key as ECVRF-P256-SHA256-TAI requires
+func p256Key(curve elliptic.Curve) error {
+
+	if curve != elliptic.P256() {
+		return fmt.Errorf("Error: %s requires a P-256 key, not %s", SuiteECVRFP256SHA256TAI, curve.Params().Name)
+	}
+
+	return nil
+}
+
+// p256Prove is ECVRF_prove (RFC 9381 section 5.1) for ECVRF-P256-SHA256-TAI
+func p256Prove(suite ecvrfSuite, key crypto.PrivateKey, alpha []byte, verbose bool) ([]byte, error) {
+
+	privKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Error: %s requires an EC private key", suite.name)
+	}
+	if err := p256Key(privKey.Curve); err != nil {
+		return nil, err
+	}
+	ec := elliptic.P256()
+	q := ec.Params().N
+	x := privKey.D.FillBytes(make([]byte, 32))
+	pkString := elliptic.MarshalCompressed(ec, privKey.X, privKey.Y)
+
+	// H = encode_to_curve(PK, alpha); Gamma = x*H
+	hx, hy, hString, err := p256EncodeToCurve(suite, pkString, alpha)
+	if err != nil {
+		return nil, err
+	}
+	gx, gy := ec.ScalarMult(hx, hy, x)
+	gammaString := elliptic.MarshalCompressed(ec, gx, gy)
+
+	// k = RFC 6979 nonce of h_string; c = challenge(Y, H, Gamma, k*B, k*H); s = k + c*x (mod q)
+	k := rfc6979Nonce(privKey.D, q, sha256.New, hString)
+	kBytes := k.FillBytes(make([]byte, 32))
+	ux, uy := ec.ScalarBaseMult(kBytes)
+	vx, vy := ec.ScalarMult(hx, hy, kBytes)
+	cString := suite.challenge(pkString, hString, gammaString,
+		elliptic.MarshalCompressed(ec, ux, uy), elliptic.MarshalCompressed(ec, vx, vy))
+	s := new(big.Int).SetBytes(cString)
+	s.Mul(s, privKey.D)
+	s.Add(s, k)
+	s.Mod(s, q)
+
+	if verbose {
+		fmt.Fprintf(helpers.Messages, "SECRET - k      (hex): %x\n", kBytes)
+		fmt.Fprintf(helpers.Messages, "Public - H      (hex): %x\n", hString)
+		fmt.Fprintf(helpers.Messages, "Public - Gamma  (hex): %x\n", gammaString)
+		fmt.Fprintf(helpers.Messages, "Public - c      (hex): %x\n", cString)
+		fmt.Fprintf(helpers.Messages, "Public - s      (hex): %x\n\n", s.FillBytes(make([]byte, 32)))
+	}
+
+	pi := append(gammaString, cString...)
+	return append(pi, s.FillBytes(make([]byte, 32))...), nil
+}
+
+// p256Verify is ECVRF_verify (RFC 9381 section 5.3) for ECVRF-P256-SHA256-TAI
+func p256Verify(suite ecvrfSuite, key crypto.PublicKey, alpha []byte, pi []byte, verbose bool) (bool, error) {
+
+	pubKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return false, fmt.Errorf("Error: %s requires an EC public key", suite.name)
+	}
+	if err := p256Key(pubKey.Curve); err != nil {
+		return false, err
+	}
+	ec := elliptic.P256()
+	q := ec.Params().N
+
+	// Decode pi = Gamma (33) || c (16) || s (32); an invalid Gamma or s >= q is INVALID
+	gammaString := pi[:suite.ptLen]
+	gx, gy := elliptic.UnmarshalCompressed(ec, gammaString)
+	if gx == nil {
+		return false, nil
+	}
+	cString := pi[suite.ptLen : suite.ptLen+suite.cLen]
+	s := new(big.Int).SetBytes(pi[suite.ptLen+suite.cLen:])
+	if s.Cmp(q) >= 0 {
+		return false, nil
+	}
+
+	// H = encode_to_curve(PK, alpha); U = s*B - c*Y; V = s*H - c*Gamma
+	pkString := elliptic.MarshalCompressed(ec, pubKey.X, pubKey.Y)
+	hx, hy, hString, err := p256EncodeToCurve(suite, pkString, alpha)
+	if err != nil {
+		return false, err
+	}
+	sx, sy := ec.ScalarBaseMult(s.FillBytes(make([]byte, 32)))
+	cx, cy := ec.ScalarMult(pubKey.X, pubKey.Y, cString)
+	ux, uy := p256Sub(sx, sy, cx, cy)
+	sx, sy = ec.ScalarMult(hx, hy, s.FillBytes(make([]byte, 32)))
+	cx, cy = ec.ScalarMult(gx, gy, cString)
+	vx, vy := p256Sub(sx, sy, cx, cy)
+
+	cPrime := suite.challenge(pkString, hString, gammaString,
+		elliptic.MarshalCompressed(ec, ux, uy), elliptic.MarshalCompressed(ec, vx, vy))
+	if verbose {
+		fmt.Fprintf(helpers.Messages, "Public - H            (hex): %x\n", hString)
+		fmt.Fprintf(helpers.Messages, "c - Provided          (hex): %x\n", cString)
+		fmt.Fprintf(helpers.Messages, "c - Calculated        (hex): %x\n\n", cPrime)
+	}
+
+	return hmac.Equal(cString, cPrime), nil
+}
+
+// p256HashGamma checks that Gamma is a P-256 point; the cofactor is 1
+func p256HashGamma(gammaString []byte) ([]byte, error) {
+
+	if x, _ := elliptic.UnmarshalCompressed(elliptic.P256(), gammaString); x == nil {
+		return nil, errors.New("Error: Gamma is not a point on P-256")
+	}
+
+	return gammaString, nil
+}
+
+//Generate is an exportable method
+/*
+*  Generate computes the RFC 9381 proof pi and output beta of alpha for the suite vrf.Suite.
+*  Proofs are deterministic: the nonce is derived from the key and alpha (RFC 6979 or RFC 8032),
+*  so the same key and alpha always give the same pi. ECVRF-P256-SHA256-TAI takes a P-256
+*  private key and gives 81-BYTE proofs and 32-BYTE outputs; the EDWARDS25519 suites take an
+*  Ed25519 private key and give 80-BYTE proofs and 64-BYTE outputs.
+ */
+func (vrf ECVRF) Generate(alpha []byte, privKey crypto.PrivateKey, verbose bool) (proof []byte, beta []byte, err error) {
+
+	suite, err := ecvrfSuiteByName(vrf.Suite)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof, err = suite.prove(suite, privKey, alpha, verbose)
+	if err != nil {
+		return nil, nil, err
+	}
+	beta, err = vrf.ProofToHash(proof)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return proof, beta, nil
+}
+
+//ProofToHash is an exportable method
+/*
+*  ProofToHash is ECVRF_proof_to_hash: it returns beta for a proof without verifying it. Use
+*  Verify unless the proof is already known to be valid.
+ */
+func (vrf ECVRF) ProofToHash(proof []byte) ([]byte, error) {
+
+	suite, err := ecvrfSuiteByName(vrf.Suite)
+	if err != nil {
+		return nil, err
+	}
+	if len(proof) != suite.proofLen() {
+		return nil, fmt.Errorf("Error: An %s proof is %d BYTES, not %d", suite.name, suite.proofLen(), len(proof))
+	}
+	gammaString, err := suite.hashGamma(proof[:suite.ptLen])
+	if err != nil {
+		return nil, err
+	}
+
+	return suite.proofToHash(gammaString), nil
+}
+
+//Verify is an exportable method
+/*
+*  Verify checks the RFC 9381 proof of alpha with the prover's public key and that beta is
+*  the output of the proof. An invalid proof or a beta that does not match returns false.
+ */
+func (vrf ECVRF) Verify(alpha []byte, beta []byte, proof []byte, pubKey crypto.PublicKey, verbose bool) (validity bool, err error) {
+
+	suite, err := ecvrfSuiteByName(vrf.Suite)
+	if err != nil {
+		return false, err
+	}
+	if len(proof) != suite.proofLen() {
+		return false, fmt.Errorf("Error: An %s proof is %d BYTES, not %d", suite.name, suite.proofLen(), len(proof))
+	}
+	valid, err := suite.verify(suite, pubKey, alpha, proof, verbose)
+	if err != nil || !valid {
+		return false, err
+	}
+
+	betaPrime, err := vrf.ProofToHash(proof)
+	if err != nil {
+		return false, err
+	}
+	if verbose {
+		fmt.Fprintf(helpers.Messages, "Beta - Calculated (hex): %x\n", betaPrime)
+		fmt.Fprintf(helpers.Messages, "Beta - Provided   (hex): %x\n\n", beta)
+	}
+
+	return hmac.Equal(beta, betaPrime), nil
+}
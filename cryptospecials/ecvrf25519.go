@@ -0,0 +1,306 @@
+/*
+*	This package contains mechanisms that will allow for VRF and OPRF calculations.
+*
+*	OPRF: https://eprint.iacr.org/2017/111
+*
+*	RSA-VRF: https://eprint.iacr.org/2017/099.pdf
+*
+*	ECVRF: https://www.rfc-editor.org/rfc/rfc9381
+*
+*		-Brian
+ */
+
+package cryptospecials
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"filippo.io/edwards25519"
+
+	"foil/helpers"
+)
+
+// The hash_to_curve suite of ECVRF-EDWARDS25519-SHA512-ELL2 (RFC 9381 section 5.5)
+const edwardsH2CSuiteID = "edwards25519_XMD:SHA-512_ELL2_NU_"
+
+var (
+	// p = 2^255 - 19 and the Montgomery coefficient J of curve25519
+	curve25519P = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	curve25519J = big.NewInt(486662)
+)
+
+// edwardsPoint decodes a point as RFC 8032 does; non-canonical encodings are not points
+func edwardsPoint(pointString []byte) (*edwards25519.Point, bool) {
+
+	point, err := new(edwards25519.Point).SetBytes(pointString)
+	if err != nil || !bytes.Equal(point.Bytes(), pointString) {
+		return nil, false
+	}
+
+	return point, true
+}
+
+// edwardsScalar reads a little-endian integer below the group order
+func edwardsScalar(intString []byte) (*edwards25519.Scalar, bool) {
+
+	padded := make([]byte, 32)
+	copy(padded, intString)
+	scalar, err := edwards25519.NewScalar().SetCanonicalBytes(padded)
+
+	return scalar, err == nil
+}
+
+/*
+*  expandMessageXMD is expand_message_xmd (RFC 9380 section 5.3.1) with SHA-512. lenInBytes
+*  must be at most 255 * 64.
+ */
+func expandMessageXMD(msg []byte, dst []byte, lenInBytes int) []byte {
+
+	h := sha512.New()
+	ell := (lenInBytes + h.Size() - 1) / h.Size()
+	dstPrime := append(append([]byte(nil), dst...), byte(len(dst)))
+
+	h.Write(make([]byte, h.BlockSize()))
+	h.Write(msg)
+	h.Write([]byte{byte(lenInBytes >> 8), byte(lenInBytes), 0x00})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	var uniform, bi []byte
+	for i := 1; i <= ell; i++ {
+		h.Reset()
+		if i == 1 {
+			h.Write(b0)
+		} else {
+			for j := range bi {
+				bi[j] ^= b0[j]
+			}
+			h.Write(bi)
+		}
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+		uniform = append(uniform, bi...)
+	}
+
+	return uniform[:lenInBytes]
+}
+
+// sqrtWithSign returns the square root of a (mod p) whose low bit (sgn0) is sign
+func sqrtWithSign(a *big.Int, sign uint) *big.Int {
+
+	root := new(big.Int).ModSqrt(a, curve25519P)
+	if root.Bit(0) != sign {
+		root.Sub(curve25519P, root)
+		root.Mod(root, curve25519P)
+	}
+
+	return root
+}
+
+/*
+*  elligator2Edwards25519 is map_to_curve_elligator2_edwards25519 (RFC 9380 sections 6.7.1 and
+*  6.8.2): Elligator 2 onto curve25519 (Z = 2) then the rational map onto edwards25519. It is not
+*  constant time; alpha is not secret.
+ */
+func elligator2Edwards25519(u *big.Int) (*edwards25519.Point, error) {
+
+	p := curve25519P
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+	inv0 := func(v *big.Int) *big.Int { return new(big.Int).Exp(v, new(big.Int).Sub(p, big.NewInt(2)), p) }
+	g := func(x *big.Int) *big.Int {
+		// x^3 + J*x^2 + x
+		x2 := mod(new(big.Int).Mul(x, x))
+		gx := mod(new(big.Int).Mul(x2, x))
+		gx.Add(gx, new(big.Int).Mul(curve25519J, x2))
+		gx.Add(gx, x)
+		return mod(gx)
+	}
+
+	// x1 = -J / (1 + 2u^2), or -J when the denominator is 0
+	tv := mod(new(big.Int).Mul(u, u))
+	tv.Lsh(tv, 1)
+	tv.Add(tv, big.NewInt(1))
+	x1 := mod(new(big.Int).Mul(new(big.Int).Neg(curve25519J), inv0(mod(tv))))
+	if x1.Sign() == 0 {
+		x1 = mod(new(big.Int).Neg(curve25519J))
+	}
+	x2 := mod(new(big.Int).Sub(new(big.Int).Neg(x1), curve25519J))
+
+	var s, t *big.Int
+	if gx1 := g(x1); big.Jacobi(gx1, p) >= 0 {
+		s, t = x1, sqrtWithSign(gx1, 1)
+	} else {
+		s, t = x2, sqrtWithSign(g(x2), 0)
+	}
+
+	// (v, w) = (sqrt(-486664) * s / t, (s - 1) / (s + 1)); (0, 1) at the exceptional points
+	v, w := big.NewInt(0), big.NewInt(1)
+	sPlusOne := mod(new(big.Int).Add(s, big.NewInt(1)))
+	if t.Sign() != 0 && sPlusOne.Sign() != 0 {
+		c1 := sqrtWithSign(mod(big.NewInt(-486664)), 0)
+		v = mod(new(big.Int).Mul(mod(new(big.Int).Mul(c1, s)), inv0(t)))
+		w = mod(new(big.Int).Mul(new(big.Int).Sub(s, big.NewInt(1)), inv0(sPlusOne)))
+	}
+
+	// Encode (v, w) as RFC 8032 does: w little-endian with the low bit of v as the top bit
+	encoded := w.FillBytes(make([]byte, 32))
+	for i, j := 0, 31; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	encoded[31] |= byte(v.Bit(0)) << 7
+	point, ok := edwardsPoint(encoded)
+	if !ok {
+		return nil, errors.New("Error: Elligator 2 did not give an edwards25519 point")
+	}
+
+	return point, nil
+}
+
+/*
+*  edwardsEncodeToCurve is ECVRF_encode_to_curve for the EDWARDS25519 suites: try-and-increment
+*  (TAI, RFC 9381 section 5.4.1.1) or hash_to_curve with Elligator 2 (ELL2, RFC 9381 section
+*  5.4.1.2 and RFC 9380). The point is multiplied by the cofactor (8).
+ */
+func edwardsEncodeToCurve(suite ecvrfSuite, pkString []byte, alpha []byte) (*edwards25519.Point, []byte, error) {
+
+	var point *edwards25519.Point
+
+	if suite.name == SuiteECVRFEdwards25519SHA512TAI {
+		hString, err := suite.encodeToCurveTAI(pkString, alpha, func(hashString []byte) ([]byte, bool) {
+			var ok bool
+			point, ok = edwardsPoint(hashString[:32])
+			if !ok {
+				return nil, false
+			}
+			point.MultByCofactor(point)
+			return point.Bytes(), true
+		})
+		return point, hString, err
+	}
+
+	// encode_to_curve(salt || alpha) with DST = "ECVRF_" || h2c_suite_ID_string || suite_string
+	dst := append([]byte("ECVRF_"+edwardsH2CSuiteID), suite.suiteString)
+	msg := append(append([]byte(nil), pkString...), alpha...)
+	u := new(big.Int).SetBytes(expandMessageXMD(msg, dst, 48))
+	point, err := elligator2Edwards25519(u.Mod(u, curve25519P))
+	if err != nil {
+		return nil, nil, err
+	}
+	point.MultByCofactor(point)
+
+	return point, point.Bytes(), nil
+}
+
+// edwardsProve is ECVRF_prove (RFC 9381 section 5.1) for the EDWARDS25519 suites
+func edwardsProve(suite ecvrfSuite, key crypto.PrivateKey, alpha []byte, verbose bool) ([]byte, error) {
+
+	privKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Error: %s requires an Ed25519 private key", suite.name)
+	}
+
+	// x is the secret scalar of the Ed25519 key (RFC 8032 section 5.1.5); Y = x*B
+	hashedSK := sha512.Sum512(privKey.Seed())
+	x, err := edwards25519.NewScalar().SetBytesWithClamping(hashedSK[:32])
+	if err != nil {
+		return nil, err
+	}
+	pkString := new(edwards25519.Point).ScalarBaseMult(x).Bytes()
+
+	// H = encode_to_curve(PK, alpha); Gamma = x*H
+	H, hString, err := edwardsEncodeToCurve(suite, pkString, alpha)
+	if err != nil {
+		return nil, err
+	}
+	gammaString := new(edwards25519.Point).ScalarMult(x, H).Bytes()
+
+	// k = SHA-512(hashed SK[32:64] || h_string) (mod q), RFC 9381 section 5.4.2.2
+	kHash := sha512.New()
+	kHash.Write(hashedSK[32:])
+	kHash.Write(hString)
+	k, err := edwards25519.NewScalar().SetUniformBytes(kHash.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	// c = challenge(Y, H, Gamma, k*B, k*H); s = k + c*x (mod q)
+	cString := suite.challenge(pkString, hString, gammaString,
+		new(edwards25519.Point).ScalarBaseMult(k).Bytes(), new(edwards25519.Point).ScalarMult(k, H).Bytes())
+	c, _ := edwardsScalar(cString)
+	s := edwards25519.NewScalar().MultiplyAdd(c, x, k)
+
+	if verbose {
+		fmt.Fprintf(helpers.Messages, "SECRET - k      (hex): %x\n", k.Bytes())
+		fmt.Fprintf(helpers.Messages, "Public - H      (hex): %x\n", hString)
+		fmt.Fprintf(helpers.Messages, "Public - Gamma  (hex): %x\n", gammaString)
+		fmt.Fprintf(helpers.Messages, "Public - c      (hex): %x\n", cString)
+		fmt.Fprintf(helpers.Messages, "Public - s      (hex): %x\n\n", s.Bytes())
+	}
+
+	pi := append(gammaString, cString...)
+	return append(pi, s.Bytes()...), nil
+}
+
+// edwardsVerify is ECVRF_verify (RFC 9381 section 5.3) for the EDWARDS25519 suites
+func edwardsVerify(suite ecvrfSuite, key crypto.PublicKey, alpha []byte, pi []byte, verbose bool) (bool, error) {
+
+	pubKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return false, fmt.Errorf("Error: %s requires an Ed25519 public key", suite.name)
+	}
+
+	// ECVRF_validate_key: Y must be a point and not of small order
+	Y, ok := edwardsPoint(pubKey)
+	if !ok || new(edwards25519.Point).MultByCofactor(Y).Equal(edwards25519.NewIdentityPoint()) == 1 {
+		return false, errors.New("Error: The Ed25519 public key is not a valid VRF key")
+	}
+
+	// Decode pi = Gamma (32) || c (16) || s (32); an invalid Gamma or s >= q is INVALID
+	gammaString := pi[:suite.ptLen]
+	gamma, ok := edwardsPoint(gammaString)
+	if !ok {
+		return false, nil
+	}
+	cString := pi[suite.ptLen : suite.ptLen+suite.cLen]
+	c, _ := edwardsScalar(cString)
+	s, ok := edwardsScalar(pi[suite.ptLen+suite.cLen:])
+	if !ok {
+		return false, nil
+	}
+
+	// H = encode_to_curve(PK, alpha); U = s*B - c*Y; V = s*H - c*Gamma
+	H, hString, err := edwardsEncodeToCurve(suite, pubKey, alpha)
+	if err != nil {
+		return false, err
+	}
+	U := new(edwards25519.Point).Subtract(new(edwards25519.Point).ScalarBaseMult(s), new(edwards25519.Point).ScalarMult(c, Y))
+	V := new(edwards25519.Point).Subtract(new(edwards25519.Point).ScalarMult(s, H), new(edwards25519.Point).ScalarMult(c, gamma))
+
+	cPrime := suite.challenge(pubKey, hString, gammaString, U.Bytes(), V.Bytes())
+	if verbose {
+		fmt.Fprintf(helpers.Messages, "Public - H            (hex): %x\n", hString)
+		fmt.Fprintf(helpers.Messages, "c - Provided          (hex): %x\n", cString)
+		fmt.Fprintf(helpers.Messages, "c - Calculated        (hex): %x\n\n", cPrime)
+	}
+
+	return hmac.Equal(cString, cPrime), nil
+}
+
+// edwardsHashGamma decodes Gamma and returns the string of cofactor*Gamma
+func edwardsHashGamma(gammaString []byte) ([]byte, error) {
+
+	gamma, ok := edwardsPoint(gammaString)
+	if !ok {
+		return nil, errors.New("Error: Gamma is not a point on edwards25519")
+	}
+
+	return new(edwards25519.Point).MultByCofactor(gamma).Bytes(), nil
+}
@@ -0,0 +1,175 @@
+package cryptospecials
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// RFC 6979 appendix A.2.5: P-256 with SHA-256
+func TestRFC6979Nonce(t *testing.T) {
+
+	x, _ := new(big.Int).SetString("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721", 16)
+	for _, vector := range []struct{ message, k string }{
+		{"sample", "a6e3c57dd01abe90086538398355dd4c3b17aa873382b0f24d6129493d8aad60"},
+		{"test", "d16b6ae827f17175e040871a1c7ec3500192c4c92677336ec2537acaee0008e0"},
+	} {
+		k := rfc6979Nonce(x, elliptic.P256().Params().N, sha256.New, []byte(vector.message))
+		if hex.EncodeToString(k.Bytes()) != vector.k {
+			t.Errorf("FAIL - RFC 6979 nonce of %q is %x", vector.message, k)
+		}
+	}
+}
+
+// RFC 9381 appendix B.1, examples 10 to 12: ECVRF-P256-SHA256-TAI
+func TestECVRFP256Vectors(t *testing.T) {
+
+	var (
+		vrf     ECVRF
+		privKey *ecdsa.PrivateKey
+		alpha   []byte
+		pi      []byte
+		beta    []byte
+		valid   bool
+		err     error
+	)
+
+	vrf.Suite = "ecvrf-p256-sha256-tai"
+	for _, vector := range []struct{ sk, pk, alpha, pi, beta string }{
+		{"c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721",
+			"0360fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6", "sample",
+			"035b5c726e8c0e2c488a107c600578ee75cb702343c153cb1eb8dec77f4b5071b4a53f0a46f018bc2c56e58d383f2305e0975972c26feea0eb122fe7893c15af376b33edf7de17c6ea056d4d82de6bc02f",
+			"a3ad7b0ef73d8fc6655053ea22f9bede8c743f08bbed3d38821f0e16474b505e"},
+		{"c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721",
+			"0360fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6", "test",
+			"034dac60aba508ba0c01aa9be80377ebd7562c4a52d74722e0abae7dc3080ddb56c19e067b15a8a8174905b13617804534214f935b94c2287f797e393eb0816969d864f37625b443f30f1a5a33f2b3c854",
+			"a284f94ceec2ff4b3794629da7cbafa49121972671b466cab4ce170aa365f26d"},
+		{"2ca1411a41b17b24cc8c3b089cfd033f1920202a6c0de8abb97df1498d50d2c8",
+			"03596375e6ce57e0f20294fc46bdfcfd19a39f8161b58695b3ec5b3d16427c274d", "Example using ECDSA key from Appendix L.4.2 of ANSI.X9-62-2005",
+			"03d03398bf53aa23831d7d1b2937e005fb0062cbefa06796579f2a1fc7e7b8c667d091c00b0f5c3619d10ecea44363b5a599cadc5b2957e223fec62e81f7b4825fc799a771a3d7334b9186bdbee87316b1",
+			"90871e06da5caa39a3c61578ebb844de8635e27ac0b13e829997d0d95dd98c19"},
+	} {
+		d, _ := new(big.Int).SetString(vector.sk, 16)
+		privKey = new(ecdsa.PrivateKey)
+		privKey.Curve, privKey.D = elliptic.P256(), d
+		privKey.X, privKey.Y = privKey.Curve.ScalarBaseMult(d.Bytes())
+		if pk := hex.EncodeToString(elliptic.MarshalCompressed(privKey.Curve, privKey.X, privKey.Y)); pk != vector.pk {
+			t.Fatalf("FAIL - The public key is %s", pk)
+		}
+
+		alpha = []byte(vector.alpha)
+		pi, beta, err = vrf.Generate(alpha, privKey, false)
+		if err != nil {
+			t.Fatalf("FAIL - Generate - %v", err)
+		}
+		if hex.EncodeToString(pi) != vector.pi {
+			t.Errorf("FAIL - pi of %q is %x", vector.alpha, pi)
+		}
+		if hex.EncodeToString(beta) != vector.beta {
+			t.Errorf("FAIL - beta of %q is %x", vector.alpha, beta)
+		}
+
+		valid, err = vrf.Verify(alpha, beta, pi, &privKey.PublicKey, false)
+		if err != nil || !valid {
+			t.Errorf("FAIL - The RFC 9381 proof of %q did not verify - %v", vector.alpha, err)
+		}
+	}
+
+	// A changed alpha, proof, or beta must not verify (example 12)
+	if valid, _ = vrf.Verify([]byte("example"), beta, pi, &privKey.PublicKey, false); valid {
+		t.Errorf("FAIL - The proof verified for another alpha")
+	}
+	for _, i := range []int{1, 40, 70} {
+		bad := append([]byte(nil), pi...)
+		bad[i] ^= 0x01
+		if valid, _ = vrf.Verify(alpha, beta, bad, &privKey.PublicKey, false); valid {
+			t.Errorf("FAIL - A proof changed at BYTE %d verified", i)
+		}
+	}
+	if valid, _ = vrf.Verify(alpha, beta[1:], pi, &privKey.PublicKey, false); valid {
+		t.Errorf("FAIL - A truncated beta verified")
+	}
+
+	// Other curves and unknown suites are rejected
+	p384Key, _ := EccPrivKeyGen(elliptic.P384())
+	if _, _, err = vrf.Generate([]byte("sample"), p384Key, false); err == nil {
+		t.Errorf("FAIL - Generate accepted a P-384 key")
+	}
+	vrf.Suite = "ECVRF-P384-SHA384-TAI"
+	if _, _, err = vrf.Generate([]byte("sample"), privKey, false); err == nil {
+		t.Errorf("FAIL - Generate accepted an unknown suite")
+	}
+}
+
+/*
+*  RFC 9381 appendices B.3 (examples 16 to 18, TAI) and B.4 (examples 19 to 21, ELL2): the keys
+*  and messages of RFC 8032 tests 1 to 3
+ */
+func TestECVRFEdwards25519Vectors(t *testing.T) {
+
+	for _, vector := range []struct{ suite, seed, pk, alpha, pi, beta string }{
+		{SuiteECVRFEdwards25519SHA512TAI, "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60", "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a", "",
+			"8657106690b5526245a92b003bb079ccd1a92130477671f6fc01ad16f26f723f26f8a57ccaed74ee1b190bed1f479d9727d2d0f9b005a6e456a35d4fb0daab1268a1b0db10836d9826a528ca76567805",
+			"90cf1df3b703cce59e2a35b925d411164068269d7b2d29f3301c03dd757876ff66b71dda49d2de59d03450451af026798e8f81cd2e333de5cdf4f3e140fdd8ae"},
+		{SuiteECVRFEdwards25519SHA512TAI, "4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb", "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c", "72",
+			"f3141cd382dc42909d19ec5110469e4feae18300e94f304590abdced48aed5933bf0864a62558b3ed7f2fea45c92a465301b3bbf5e3e54ddf2d935be3b67926da3ef39226bbc355bdc9850112c8f4b02",
+			"eb4440665d3891d668e7e0fcaf587f1b4bd7fbfe99d0eb2211ccec90496310eb5e33821bc613efb94db5e5b54c70a848a0bef4553a41befc57663b56373a5031"},
+		{SuiteECVRFEdwards25519SHA512TAI, "c5aa8df43f9f837bedb7442f31dcb7b166d38535076f094b85ce3a2e0b4458f7", "fc51cd8e6218a1a38da47ed00230f0580816ed13ba3303ac5deb911548908025", "af82",
+			"9bc0f79119cc5604bf02d23b4caede71393cedfbb191434dd016d30177ccbf8096bb474e53895c362d8628ee9f9ea3c0e52c7a5c691b6c18c9979866568add7a2d41b00b05081ed0f58ee5e31b3a970e",
+			"645427e5d00c62a23fb703732fa5d892940935942101e456ecca7bb217c61c452118fec1219202a0edcf038bb6373241578be7217ba85a2687f7a0310b2df19f"},
+		{SuiteECVRFEdwards25519SHA512ELL2, "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60", "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a", "",
+			"7d9c633ffeee27349264cf5c667579fc583b4bda63ab71d001f89c10003ab46f14adf9a3cd8b8412d9038531e865c341cafa73589b023d14311c331a9ad15ff2fb37831e00f0acaa6d73bc9997b06501",
+			"9d574bf9b8302ec0fc1e21c3ec5368269527b87b462ce36dab2d14ccf80c53cccf6758f058c5b1c856b116388152bbe509ee3b9ecfe63d93c3b4346c1fbc6c54"},
+		{SuiteECVRFEdwards25519SHA512ELL2, "4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb", "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c", "72",
+			"47b327393ff2dd81336f8a2ef10339112401253b3c714eeda879f12c509072ef055b48372bb82efbdce8e10c8cb9a2f9d60e93908f93df1623ad78a86a028d6bc064dbfc75a6a57379ef855dc6733801",
+			"38561d6b77b71d30eb97a062168ae12b667ce5c28caccdf76bc88e093e4635987cd96814ce55b4689b3dd2947f80e59aac7b7675f8083865b46c89b2ce9cc735"},
+		{SuiteECVRFEdwards25519SHA512ELL2, "c5aa8df43f9f837bedb7442f31dcb7b166d38535076f094b85ce3a2e0b4458f7", "fc51cd8e6218a1a38da47ed00230f0580816ed13ba3303ac5deb911548908025", "af82",
+			"926e895d308f5e328e7aa159c06eddbe56d06846abf5d98c2512235eaa57fdce35b46edfc655bc828d44ad09d1150f31374e7ef73027e14760d42e77341fe05467bb286cc2c9d7fde29120a0b2320d04",
+			"121b7f9b9aaaa29099fc04a94ba52784d44eac976dd1a3cca458733be5cd090a7b5fbd148444f17f8daf1fb55cb04b1ae85a626e30a54b4b0f8abf4a43314a58"},
+	} {
+		seed, _ := hex.DecodeString(vector.seed)
+		alpha, _ := hex.DecodeString(vector.alpha)
+		privKey := ed25519.NewKeyFromSeed(seed)
+		pubKey := privKey.Public().(ed25519.PublicKey)
+		if hex.EncodeToString(pubKey) != vector.pk {
+			t.Fatalf("FAIL - The public key is %x", pubKey)
+		}
+
+		vrf := ECVRF{Suite: vector.suite}
+		pi, beta, err := vrf.Generate(alpha, privKey, false)
+		if err != nil {
+			t.Fatalf("FAIL - %s Generate - %v", vector.suite, err)
+		}
+		if hex.EncodeToString(pi) != vector.pi {
+			t.Errorf("FAIL - %s pi of %x is %x", vector.suite, alpha, pi)
+		}
+		if hex.EncodeToString(beta) != vector.beta {
+			t.Errorf("FAIL - %s beta of %x is %x", vector.suite, alpha, beta)
+		}
+
+		valid, err := vrf.Verify(alpha, beta, pi, pubKey, false)
+		if err != nil || !valid {
+			t.Errorf("FAIL - The %s proof of %x did not verify - %v", vector.suite, alpha, err)
+		}
+		if valid, _ = vrf.Verify(append(alpha, 0x00), beta, pi, pubKey, false); valid {
+			t.Errorf("FAIL - The %s proof verified for another alpha", vector.suite)
+		}
+		for _, i := range []int{0, 40, 70} {
+			bad := append([]byte(nil), pi...)
+			bad[i] ^= 0x01
+			if valid, _ = vrf.Verify(alpha, beta, bad, pubKey, false); valid {
+				t.Errorf("FAIL - A %s proof changed at BYTE %d verified", vector.suite, i)
+			}
+		}
+
+		// EC keys are rejected by the Edwards suites
+		ecKey, _ := EccPrivKeyGen(elliptic.P256())
+		if _, _, err = vrf.Generate(alpha, ecKey, false); err == nil {
+			t.Errorf("FAIL - %s accepted a P-256 key", vector.suite)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+/*
+*	This package contains mechanisms that will allow for VRF and OPRF calculations.
+*
+*	OPRF: https://eprint.iacr.org/2017/111
+*
+*	RSA-VRF: https://eprint.iacr.org/2017/099.pdf
+*
+*		-Brian
+ */
+
+package cryptospecials
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// jsonWebKey holds the members of an RSA, EC, or OKP JSON Web Key (RFC 7517, 7518, and 8037)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	Dp  string `json:"dp,omitempty"`
+	Dq  string `json:"dq,omitempty"`
+	Qi  string `json:"qi,omitempty"`
+}
+
+// jwkCurves maps the "crv" of an EC JWK onto its curve in both elliptic and ecdh form
+var jwkCurves = map[string]struct {
+	ec   elliptic.Curve
+	ecdh ecdh.Curve
+}{
+	"P-256": {elliptic.P256(), ecdh.P256()},
+	"P-384": {elliptic.P384(), ecdh.P384()},
+	"P-521": {elliptic.P521(), ecdh.P521()},
+}
+
+// jwkEncode encodes a JWK member as base64url, left padding it with zeros to size BYTES
+func jwkEncode(value []byte, size int) string {
+
+	if len(value) < size {
+		value = append(make([]byte, size-len(value)), value...)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+// jwkBytes decodes a base64url JWK member; padding is tolerated although RFC 7515 omits it
+func jwkBytes(name string, value string) ([]byte, error) {
+
+	if len(value) == 0 {
+		return nil, fmt.Errorf("the JWK has no %q member", name)
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, fmt.Errorf("the JWK member %q is not base64url", name)
+	}
+
+	return decoded, nil
+}
+
+// jwkInt decodes a base64url JWK member as a big-endian unsigned integer
+func jwkInt(name string, value string) (*big.Int, error) {
+
+	decoded, err := jwkBytes(name, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+/*
+*  parseJWK parses a JWK, or a JWK Set holding exactly one key, into a private key (nil for a
+*  public JWK) and a public key.
+ */
+func parseJWK(data []byte) (crypto.PrivateKey, crypto.PublicKey, error) {
+
+	var (
+		key jsonWebKey
+		set struct {
+			Keys []json.RawMessage `json:"keys"`
+		}
+	)
+
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, nil, errors.New("it is not valid JSON")
+	}
+	if set.Keys != nil {
+		if len(set.Keys) != 1 {
+			return nil, nil, fmt.Errorf("the JWK Set holds %d keys; exactly one is required", len(set.Keys))
+		}
+		data = set.Keys[0]
+	}
+	err = json.Unmarshal(data, &key)
+	if err != nil {
+		return nil, nil, errors.New("it is not a valid JWK")
+	}
+
+	switch key.Kty {
+	case "RSA":
+		return parseRSAJWK(&key)
+	case "EC":
+		return parseECJWK(&key)
+	case "OKP":
+		return parseOKPJWK(&key)
+	case "":
+		return nil, nil, errors.New("the JWK has no \"kty\" member")
+	}
+
+	return nil, nil, fmt.Errorf("the JWK key type %q is not supported", key.Kty)
+}
+
+// parseRSAJWK parses an RSA JWK; private keys must carry the primes p and q
+func parseRSAJWK(key *jsonWebKey) (crypto.PrivateKey, crypto.PublicKey, error) {
+
+	n, err := jwkInt("n", key.N)
+	if err != nil {
+		return nil, nil, err
+	}
+	e, err := jwkInt("e", key.E)
+	if err != nil {
+		return nil, nil, err
+	} else if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, nil, errors.New("the RSA public exponent of the JWK is not valid")
+	}
+	pubKey := &rsa.PublicKey{N: n, E: int(e.Int64())}
+	if len(key.D) == 0 {
+		return nil, pubKey, nil
+	}
+
+	d, err := jwkInt("d", key.D)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := jwkInt("p", key.P)
+	if err != nil {
+		return nil, nil, err
+	}
+	q, err := jwkInt("q", key.Q)
+	if err != nil {
+		return nil, nil, err
+	}
+	privKey := &rsa.PrivateKey{PublicKey: *pubKey, D: d, Primes: []*big.Int{p, q}}
+	err = privKey.Validate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("the RSA JWK is not valid: %v", err)
+	}
+	privKey.Precompute()
+
+	return privKey, pubKey, nil
+}
+
+// parseECJWK parses a P-256, P-384, or P-521 JWK and checks that the point is on the curve
+func parseECJWK(key *jsonWebKey) (crypto.PrivateKey, crypto.PublicKey, error) {
+
+	curve, ok := jwkCurves[key.Crv]
+	if !ok {
+		return nil, nil, fmt.Errorf("the EC JWK curve %q is not supported", key.Crv)
+	}
+	size := (curve.ec.Params().BitSize + 7) / 8
+	x, err := jwkBytes("x", key.X)
+	if err != nil {
+		return nil, nil, err
+	}
+	y, err := jwkBytes("y", key.Y)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(x) != size || len(y) != size {
+		return nil, nil, fmt.Errorf("the %s JWK coordinates must be %d BYTES", key.Crv, size)
+	}
+	point := append(append([]byte{4}, x...), y...)
+	_, err = curve.ecdh.NewPublicKey(point)
+	if err != nil {
+		return nil, nil, fmt.Errorf("the %s JWK point is not on the curve", key.Crv)
+	}
+	pubKey := &ecdsa.PublicKey{Curve: curve.ec, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if len(key.D) == 0 {
+		return nil, pubKey, nil
+	}
+
+	d, err := jwkBytes("d", key.D)
+	if err != nil {
+		return nil, nil, err
+	}
+	ecdhKey, err := curve.ecdh.NewPrivateKey(d)
+	if err != nil {
+		return nil, nil, fmt.Errorf("the %s JWK private scalar is not valid", key.Crv)
+	} else if !bytes.Equal(ecdhKey.PublicKey().Bytes(), point) {
+		return nil, nil, fmt.Errorf("the %s JWK private and public keys do not match", key.Crv)
+	}
+	privKey := &ecdsa.PrivateKey{PublicKey: *pubKey, D: new(big.Int).SetBytes(d)}
+
+	return privKey, pubKey, nil
+}
+
+// parseOKPJWK parses an Ed25519 or X25519 JWK (RFC 8037)
+func parseOKPJWK(key *jsonWebKey) (crypto.PrivateKey, crypto.PublicKey, error) {
+
+	x, err := jwkBytes("x", key.X)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch key.Crv {
+	case "Ed25519":
+		if len(x) != ed25519.PublicKeySize {
+			return nil, nil, fmt.Errorf("the Ed25519 JWK public key must be %d BYTES", ed25519.PublicKeySize)
+		}
+		pubKey := ed25519.PublicKey(x)
+		if len(key.D) == 0 {
+			return nil, pubKey, nil
+		}
+		d, err := jwkBytes("d", key.D)
+		if err != nil {
+			return nil, nil, err
+		} else if len(d) != ed25519.SeedSize {
+			return nil, nil, fmt.Errorf("the Ed25519 JWK private key must be %d BYTES", ed25519.SeedSize)
+		}
+		privKey := ed25519.NewKeyFromSeed(d)
+		if !pubKey.Equal(privKey.Public()) {
+			return nil, nil, errors.New("the Ed25519 JWK private and public keys do not match")
+		}
+		return privKey, pubKey, nil
+	case "X25519":
+		pubKey, err := ecdh.X25519().NewPublicKey(x)
+		if err != nil {
+			return nil, nil, errors.New("the X25519 JWK public key is not valid")
+		}
+		if len(key.D) == 0 {
+			return nil, pubKey, nil
+		}
+		d, err := jwkBytes("d", key.D)
+		if err != nil {
+			return nil, nil, err
+		}
+		privKey, err := ecdh.X25519().NewPrivateKey(d)
+		if err != nil {
+			return nil, nil, errors.New("the X25519 JWK private key is not valid")
+		} else if !privKey.PublicKey().Equal(pubKey) {
+			return nil, nil, errors.New("the X25519 JWK private and public keys do not match")
+		}
+		return privKey, pubKey, nil
+	}
+
+	return nil, nil, fmt.Errorf("the OKP JWK curve %q is not supported", key.Crv)
+}
+
+/*
+*  newJSONWebKey builds the JWK of a private or public key; private keys carry every private
+*  member (RFC 7518 and RFC 8037) and the public members. The kid is left empty.
+ */
+func newJSONWebKey(key interface{}) (*jsonWebKey, error) {
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return &jsonWebKey{Kty: "RSA", N: jwkEncode(k.N.Bytes(), 0), E: jwkEncode(big.NewInt(int64(k.E)).Bytes(), 0)}, nil
+	case *rsa.PrivateKey:
+		if len(k.Primes) != 2 {
+			return nil, errors.New("Error: Multi-prime RSA keys can not be written as a JWK")
+		}
+		k.Precompute()
+		jwk, _ := newJSONWebKey(&k.PublicKey)
+		jwk.D = jwkEncode(k.D.Bytes(), 0)
+		jwk.P = jwkEncode(k.Primes[0].Bytes(), 0)
+		jwk.Q = jwkEncode(k.Primes[1].Bytes(), 0)
+		jwk.Dp = jwkEncode(k.Precomputed.Dp.Bytes(), 0)
+		jwk.Dq = jwkEncode(k.Precomputed.Dq.Bytes(), 0)
+		jwk.Qi = jwkEncode(k.Precomputed.Qinv.Bytes(), 0)
+		return jwk, nil
+	case *ecdsa.PublicKey:
+		name := k.Curve.Params().Name
+		if _, ok := jwkCurves[name]; !ok {
+			return nil, fmt.Errorf("Error: EC keys on %s can not be written as a JWK", name)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return &jsonWebKey{Kty: "EC", Crv: name, X: jwkEncode(k.X.Bytes(), size), Y: jwkEncode(k.Y.Bytes(), size)}, nil
+	case *ecdsa.PrivateKey:
+		jwk, err := newJSONWebKey(&k.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		jwk.D = jwkEncode(k.D.Bytes(), (k.Curve.Params().BitSize+7)/8)
+		return jwk, nil
+	case ed25519.PublicKey:
+		return &jsonWebKey{Kty: "OKP", Crv: "Ed25519", X: jwkEncode(k, 0)}, nil
+	case ed25519.PrivateKey:
+		return &jsonWebKey{Kty: "OKP", Crv: "Ed25519", X: jwkEncode(k.Public().(ed25519.PublicKey), 0), D: jwkEncode(k.Seed(), 0)}, nil
+	case *ecdh.PublicKey:
+		if k.Curve() != ecdh.X25519() {
+			return nil, errors.New("Error: Only X25519 ecdh keys can be written as a JWK")
+		}
+		return &jsonWebKey{Kty: "OKP", Crv: "X25519", X: jwkEncode(k.Bytes(), 0)}, nil
+	case *ecdh.PrivateKey:
+		jwk, err := newJSONWebKey(k.PublicKey())
+		if err != nil {
+			return nil, err
+		}
+		jwk.D = jwkEncode(k.Bytes(), 0)
+		return jwk, nil
+	}
+
+	return nil, fmt.Errorf("Error: %T keys can not be written as a JWK", key)
+}
+
+//JWKThumbprint is an exportable function
+/*
+*  JWKThumbprint returns the RFC 7638 thumbprint of a public key: the base64url SHA-256 of
+*  the required JWK members in lexicographic order. Private keys give the thumbprint of
+*  their public key.
+ */
+func JWKThumbprint(key interface{}) (string, error) {
+
+	jwk, err := newJSONWebKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	// The members are written by hand; RFC 7638 fixes their order and forbids whitespace
+	var canonical string
+	switch jwk.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	case "EC":
+		canonical = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, jwk.Crv, jwk.X, jwk.Y)
+	default:
+		canonical = fmt.Sprintf(`{"crv":"%s","kty":"OKP","x":"%s"}`, jwk.Crv, jwk.X)
+	}
+	digest := sha256.Sum256([]byte(canonical))
+
+	return base64.RawURLEncoding.EncodeToString(digest[:]), nil
+}
+
+//MarshalJWK is an exportable function
+/*
+*  MarshalJWK writes an RSA, EC, Ed25519, or X25519 key as an indented JWK. A private key
+*  gives a private JWK; pass its public key for a public JWK. The kid is the RFC 7638
+*  thumbprint.
+ */
+func MarshalJWK(key interface{}) ([]byte, error) {
+
+	jwk, err := newJSONWebKey(key)
+	if err != nil {
+		return nil, err
+	}
+	jwk.Kid, err = JWKThumbprint(key)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(jwk, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, '\n'), nil
+}
+
+//MarshalJWKSet is an exportable function
+// MarshalJWKSet writes keys as an indented JWK Set; each JWK is written as by MarshalJWK
+func MarshalJWKSet(keys ...interface{}) ([]byte, error) {
+
+	var set struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}
+
+	set.Keys = []*jsonWebKey{}
+	for _, key := range keys {
+		jwk, err := newJSONWebKey(key)
+		if err != nil {
+			return nil, err
+		}
+		jwk.Kid, err = JWKThumbprint(key)
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	out, err := json.MarshalIndent(&set, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, '\n'), nil
+}
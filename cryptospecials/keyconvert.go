@@ -0,0 +1,142 @@
+/*
+*	This package contains mechanisms that will allow for VRF and OPRF calculations.
+*
+*	OPRF: https://eprint.iacr.org/2017/111
+*
+*	RSA-VRF: https://eprint.iacr.org/2017/099.pdf
+*
+*		-Brian
+ */
+
+package cryptospecials
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"foil/helpers"
+)
+
+// Key encodings written by EncodeKey
+const (
+	KeyEncodingPEM     = "pem"
+	KeyEncodingDER     = "der"
+	KeyEncodingJWK     = "jwk"
+	KeyEncodingJWKS    = "jwks"
+	KeyEncodingOpenSSH = "openssh"
+)
+
+//IsPrivateKey is an exportable function
+// IsPrivateKey reports whether key is an RSA, EC, Ed25519, or X25519 private key
+func IsPrivateKey(key interface{}) bool {
+
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey, *ecdh.PrivateKey:
+		return true
+	}
+
+	return false
+}
+
+//SPKIFingerprint is an exportable function
+/*
+*  SPKIFingerprint returns the lowercase hex SHA-256 of the DER SubjectPublicKeyInfo (PKIX) of a
+*  private or public key; the same as 'openssl pkey -pubout -outform DER | sha256sum'. It names
+*  a key in output such as VRF proofs without revealing anything beyond the public key.
+ */
+func SPKIFingerprint(key interface{}) (string, error) {
+
+	der, err := x509.MarshalPKIXPublicKey(helpers.PublicOf(key))
+	if err != nil {
+		return "", fmt.Errorf("Error: %v", err)
+	}
+	digest := sha256.Sum256(der)
+
+	return hex.EncodeToString(digest[:]), nil
+}
+
+//EncodeKey is an exportable function
+/*
+*  EncodeKey writes an RSA, EC, Ed25519, or X25519 key with one of the KeyEncoding constants:
+*
+*	pem     : private keys as PKCS#8 "PRIVATE KEY"; public keys as PKIX "PUBLIC KEY"
+*	der     : the same PKCS#8 or PKIX BYTES without the PEM armor
+*	jwk     : a JWK with the RFC 7638 thumbprint as the kid (see MarshalJWK)
+*	jwks    : a JWK Set holding the one JWK
+*	openssh : private keys as an "OPENSSH PRIVATE KEY"; public keys as an authorized_keys line
+*
+*  A private key is always written as a private key; pass its public key to write only that.
+ */
+func EncodeKey(key interface{}, encoding string) ([]byte, error) {
+
+	var (
+		der []byte
+		err error
+	)
+
+	switch encoding {
+	case KeyEncodingJWK:
+		return MarshalJWK(key)
+	case KeyEncodingJWKS:
+		return MarshalJWKSet(key)
+	case KeyEncodingOpenSSH:
+		if IsPrivateKey(key) {
+			return MarshalOpenSSHPrivateKey(key, "", nil)
+		}
+		return MarshalAuthorizedKey(key, "")
+	case KeyEncodingPEM, KeyEncodingDER:
+	default:
+		return nil, fmt.Errorf("Error: Unknown key encoding %q; use [pem, der, jwk, jwks, openssh]", encoding)
+	}
+
+	blockType := "PUBLIC KEY"
+	if IsPrivateKey(key) {
+		blockType = "PRIVATE KEY"
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+	} else {
+		der, err = x509.MarshalPKIXPublicKey(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if encoding == KeyEncodingDER {
+		return der, nil
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), nil
+}
+
+//EncodedKeySave is an exportable function
+/*
+*  EncodedKeySave writes a key with EncodeKey and saves it to savePath (0600 for private keys,
+*  0644 for public keys) when savePath is not empty. The encoded key is returned.
+ */
+func EncodedKeySave(key interface{}, encoding string, savePath string) (encoded []byte, err error) {
+
+	encoded, err = EncodeKey(key, encoding)
+	if err != nil {
+		return nil, err
+	}
+	if len(savePath) == 0 {
+		return encoded, nil
+	}
+
+	perm := os.FileMode(0644)
+	if IsPrivateKey(key) {
+		perm = 0600
+	}
+	err = writeKeyFile(savePath, encoded, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return encoded, nil
+}
@@ -0,0 +1,119 @@
+package cryptospecials
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/elliptic"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// The thumbprint examples of RFC 7638 (section 3.1) and RFC 8037 (appendix A.3)
+func TestJWKThumbprint(t *testing.T) {
+
+	vectors := []struct {
+		jwk        string
+		thumbprint string
+	}{
+		{`{"kty":"RSA","e":"AQAB","n":"0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"}`,
+			"NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"},
+		{`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`,
+			"kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"},
+	}
+	for _, vector := range vectors {
+		key, err := ParseKey([]byte(vector.jwk), "vector")
+		if err != nil {
+			t.Fatalf("FAIL - ParseKey - %v", err)
+		}
+		thumbprint, err := JWKThumbprint(key.Public)
+		if err != nil || thumbprint != vector.thumbprint {
+			t.Errorf("FAIL - JWKThumbprint = %s; want %s (%v)", thumbprint, vector.thumbprint, err)
+		}
+	}
+}
+
+// Every key must survive a round trip through every encoding, as a private and as a public key
+func TestEncodeKeyRoundTrip(t *testing.T) {
+
+	defer os.Remove("convertKey")
+	rsaKey, _ := RSAKeyGen(2048)
+	ecKey, _ := EccPrivKeyGen(elliptic.P521())
+	edKey, _ := Ed25519KeyGen()
+	xKey, _ := X25519KeyGen()
+
+	for _, privKey := range []crypto.PrivateKey{rsaKey, ecKey, edKey, xKey} {
+		pubKey := privKey.(interface{ Public() crypto.PublicKey }).Public()
+		for _, encoding := range []string{KeyEncodingPEM, KeyEncodingDER, KeyEncodingJWK, KeyEncodingJWKS} {
+			for _, key := range []interface{}{privKey, pubKey} {
+				encoded, err := EncodedKeySave(key, encoding, "convertKey")
+				if err != nil {
+					t.Fatalf("FAIL - EncodedKeySave (%T, %s) - %v", key, encoding, err)
+				}
+				info, _ := os.Stat("convertKey")
+				if IsPrivateKey(key) != (info.Mode().Perm() == 0600) {
+					t.Errorf("FAIL - EncodedKeySave (%T, %s) saved the key as %v", key, encoding, info.Mode().Perm())
+				}
+				loaded, err := LoadKey("convertKey")
+				if err != nil {
+					t.Fatalf("FAIL - LoadKey (%T, %s) - %v", key, encoding, err)
+				}
+				if (loaded.Private != nil) != IsPrivateKey(key) || !pubKey.(interface{ Equal(crypto.PublicKey) bool }).Equal(loaded.Public) {
+					t.Errorf("FAIL - %T did not round trip as %s", key, encoding)
+				}
+				if loaded.Private != nil && !privKey.(interface{ Equal(crypto.PrivateKey) bool }).Equal(loaded.Private) {
+					t.Errorf("FAIL - The private %T did not round trip as %s", key, encoding)
+				}
+
+				// A JWK must carry its thumbprint as the kid
+				if encoding == KeyEncodingJWK {
+					var jwk jsonWebKey
+					json.Unmarshal(encoded, &jwk)
+					thumbprint, _ := JWKThumbprint(pubKey)
+					if jwk.Kid != thumbprint {
+						t.Errorf("FAIL - The kid of the %T JWK is %q; want %q", key, jwk.Kid, thumbprint)
+					}
+				}
+			}
+		}
+	}
+
+	// The private and public keys give the same thumbprint
+	privThumbprint, _ := JWKThumbprint(ecKey)
+	pubThumbprint, _ := JWKThumbprint(&ecKey.PublicKey)
+	if privThumbprint != pubThumbprint {
+		t.Errorf("FAIL - The private and public EC thumbprints differ")
+	}
+	set, err := MarshalJWKSet(rsaKey.Public(), edKey.Public())
+	if err != nil || !bytes.Contains(set, []byte(`"kty": "OKP"`)) {
+		t.Errorf("FAIL - MarshalJWKSet - %v", err)
+	}
+	if _, err = ParseKey(set, "set"); err == nil {
+		t.Errorf("FAIL - ParseKey accepted a JWK Set of two keys")
+	}
+	if _, err = EncodeKey(edKey, "ssh"); err == nil {
+		t.Errorf("FAIL - EncodeKey accepted an unknown encoding")
+	}
+}
+
+// The SPKI fingerprint must match 'openssl pkey -pubout -outform DER | sha256sum' for private and public keys
+func TestSPKIFingerprint(t *testing.T) {
+
+	defer func() { PassphraseFunc = nil }()
+	PassphraseFunc = func(string) ([]byte, error) { return []byte("foiltest"), nil }
+
+	want := "8c40ed0b0f2712a4015ed81123eccb0546601037048aef43b682854877639545"
+	for _, name := range []string{"testdata/openssl_pub.pem", "testdata/openssl_scrypt.pem"} {
+		key, err := LoadKey(name)
+		if err != nil {
+			t.Fatalf("FAIL - LoadKey (%s) - %v", name, err)
+		}
+		fingerprint, err := key.Fingerprint()
+		if err != nil || fingerprint != want {
+			t.Errorf("FAIL - Fingerprint (%s) = %s; want %s", name, fingerprint, want)
+		}
+		if fingerprint, _ = SPKIFingerprint(key.Private); key.Private != nil && fingerprint != want {
+			t.Errorf("FAIL - SPKIFingerprint of the private key (%s) = %s", name, fingerprint)
+		}
+	}
+}
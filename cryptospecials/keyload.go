@@ -0,0 +1,462 @@
+/*
+*	This package contains mechanisms that will allow for VRF and OPRF calculations.
+*
+*	OPRF: https://eprint.iacr.org/2017/111
+*
+*	RSA-VRF: https://eprint.iacr.org/2017/099.pdf
+*
+*		-Brian
+ */
+
+package cryptospecials
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Key algorithms reported by Key.Algorithm
+const (
+	KeyRSA     = "RSA"
+	KeyEC      = "EC"
+	KeyEd25519 = "Ed25519"
+	KeyX25519  = "X25519"
+)
+
+// Key encodings reported by Key.Format
+const (
+	FormatPKCS1          = "PKCS#1"
+	FormatPKCS8          = "PKCS#8"
+	FormatEncryptedPKCS8 = "encrypted PKCS#8"
+	FormatSEC1           = "SEC1"
+	FormatPKIX           = "PKIX"
+	FormatOpenSSH        = "OpenSSH"
+	FormatJWK            = "JWK"
+	FormatX509           = "X.509 certificate"
+)
+
+//Key is an exportable STRUCT
+/*
+*  Key is a private or public key read by LoadKey or ParseKey. Private is nil when only a
+*  public key was read; Public is always set. Algorithm is one of KeyRSA, KeyEC, KeyEd25519,
+*  or KeyX25519 and Format is the encoding that the key was read from.
+ */
+type Key struct {
+	Private   crypto.PrivateKey
+	Public    crypto.PublicKey
+	Algorithm string
+	Format    string
+	Source    string
+}
+
+//KeyFormatError is an exportable STRUCT
+// KeyFormatError is returned when the input is not a key in any supported format
+type KeyFormatError struct {
+	Source string
+	Reason string
+}
+
+func (e *KeyFormatError) Error() string {
+	return fmt.Sprintf("Error: %s is not a supported key: %s", e.Source, e.Reason)
+}
+
+//KeyMismatchError is an exportable STRUCT
+// KeyMismatchError is returned when a key was read but it is not the kind of key required
+type KeyMismatchError struct {
+	Source string
+	Want   string
+	Got    string
+}
+
+func (e *KeyMismatchError) Error() string {
+	return fmt.Sprintf("Error: %s holds an %s but an %s is required", e.Source, e.Got, e.Want)
+}
+
+//LoadKey is an exportable function
+/*
+*  LoadKey reads the key at sourcePath ("-" for StdIn) with ParseKey. Encrypted private keys
+*  are decrypted with the passphrase from PassphraseFunc.
+ */
+func LoadKey(sourcePath string) (*Key, error) {
+
+	data, err := readSource(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseKey(data, sourcePath)
+}
+
+//ParseKey is an exportable function
+/*
+*  ParseKey identifies and parses an RSA, EC, Ed25519, or X25519 key in any of:
+*
+*	PEM  : PKCS#1, SEC1, PKCS#8, encrypted PKCS#8, PKIX, OpenSSH private keys, X.509 certificates
+*	DER  : PKCS#8, PKCS#1, SEC1, or PKIX
+*	Text : OpenSSH public keys (authorized_keys lines) and JWKs (or a JWK Set with one key)
+*
+*  The PEM label is only a hint; a SEC1 or PKIX body under the wrong label is still read.
+*  sourcePath is used in errors and given to PassphraseFunc.
+ */
+func ParseKey(data []byte, sourcePath string) (*Key, error) {
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, &KeyFormatError{Source: sourcePath, Reason: "it is empty"}
+	}
+
+	// JWK or JWK Set
+	if trimmed[0] == '{' {
+		privKey, pubKey, err := parseJWK(trimmed)
+		if err != nil {
+			return nil, &KeyFormatError{Source: sourcePath, Reason: err.Error()}
+		}
+		return newKey(sourcePath, FormatJWK, privKey, pubKey)
+	}
+
+	// 'openssl ecparam -genkey' writes an EC PARAMETERS block before the key
+	block, rest := pem.Decode(trimmed)
+	for block != nil && block.Type == "EC PARAMETERS" {
+		block, rest = pem.Decode(rest)
+	}
+	if block != nil {
+		return parsePEMKey(block, sourcePath)
+	}
+
+	// OpenSSH public key, as found in authorized_keys
+	if sshKey, _, _, _, err := ssh.ParseAuthorizedKey(trimmed); err == nil {
+		cryptoKey, ok := sshKey.(ssh.CryptoPublicKey)
+		if !ok {
+			return nil, &KeyFormatError{Source: sourcePath, Reason: fmt.Sprintf("OpenSSH %s keys are not supported", sshKey.Type())}
+		}
+		return newKey(sourcePath, FormatOpenSSH, nil, cryptoKey.CryptoPublicKey())
+	}
+
+	// Bare DER
+	if privKey, format, err := parsePrivateDER(data); err == nil {
+		return newKey(sourcePath, format, privKey, nil)
+	}
+	if pubKey, format, err := parsePublicDER(data); err == nil {
+		return newKey(sourcePath, format, nil, pubKey)
+	}
+
+	return nil, &KeyFormatError{Source: sourcePath, Reason: "it is not a PEM, DER, OpenSSH, or JWK key"}
+}
+
+// parsePEMKey parses a single PEM block by its label, falling back on the other DER encodings
+func parsePEMKey(block *pem.Block, sourcePath string) (*Key, error) {
+
+	switch block.Type {
+	case "ENCRYPTED PRIVATE KEY", "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+		der, err := privateKeyDER(block, sourcePath)
+		if err != nil {
+			return nil, err
+		}
+		privKey, format, err := parsePrivateDER(der)
+		if err != nil {
+			return nil, &KeyFormatError{Source: sourcePath, Reason: fmt.Sprintf("the %s PEM does not hold a PKCS#8, PKCS#1, or SEC1 key", block.Type)}
+		}
+		if block.Type == "ENCRYPTED PRIVATE KEY" {
+			format = FormatEncryptedPKCS8
+		}
+		return newKey(sourcePath, format, privKey, nil)
+	case "OPENSSH PRIVATE KEY":
+		privKey, err := parseOpenSSHPrivateKey(pem.EncodeToMemory(block), sourcePath)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(sourcePath, FormatOpenSSH, privKey, nil)
+	case "PUBLIC KEY", "RSA PUBLIC KEY":
+		pubKey, format, err := parsePublicDER(block.Bytes)
+		if err != nil {
+			return nil, &KeyFormatError{Source: sourcePath, Reason: fmt.Sprintf("the %s PEM does not hold a PKIX or PKCS#1 key", block.Type)}
+		}
+		return newKey(sourcePath, format, nil, pubKey)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, &KeyFormatError{Source: sourcePath, Reason: fmt.Sprintf("the certificate is not valid: %v", err)}
+		}
+		return newKey(sourcePath, FormatX509, nil, cert.PublicKey)
+	}
+
+	return nil, &KeyFormatError{Source: sourcePath, Reason: fmt.Sprintf("PEM type %q is not supported", block.Type)}
+}
+
+// parsePrivateDER tries PKCS#8, PKCS#1, then SEC1
+func parsePrivateDER(der []byte) (crypto.PrivateKey, string, error) {
+
+	if privKey, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return privKey, FormatPKCS8, nil
+	}
+	if privKey, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return privKey, FormatPKCS1, nil
+	}
+	privKey, err := x509.ParseECPrivateKey(der)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return privKey, FormatSEC1, nil
+}
+
+// parsePublicDER tries PKIX then PKCS#1
+func parsePublicDER(der []byte) (crypto.PublicKey, string, error) {
+
+	if pubKey, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return pubKey, FormatPKIX, nil
+	}
+	pubKey, err := x509.ParsePKCS1PublicKey(der)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return pubKey, FormatPKCS1, nil
+}
+
+// parseOpenSSHPrivateKey parses an "OPENSSH PRIVATE KEY", asking PassphraseFunc when it is encrypted
+func parseOpenSSHPrivateKey(pemBytes []byte, sourcePath string) (crypto.PrivateKey, error) {
+
+	privKey, err := ssh.ParseRawPrivateKey(pemBytes)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if PassphraseFunc == nil {
+			return nil, ErrPassphraseRequired
+		}
+		passphrase, passErr := PassphraseFunc(sourcePath)
+		if passErr != nil {
+			return nil, passErr
+		}
+		privKey, err = ssh.ParseRawPrivateKeyWithPassphrase(pemBytes, passphrase)
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, ErrIncorrectPassphrase
+		}
+	}
+	if err != nil {
+		return nil, &KeyFormatError{Source: sourcePath, Reason: fmt.Sprintf("the OpenSSH private key is not valid: %v", err)}
+	}
+
+	return privKey, nil
+}
+
+// newKey identifies the algorithm of a parsed key; pubKey may be nil when privKey is set
+func newKey(sourcePath string, format string, privKey crypto.PrivateKey, pubKey crypto.PublicKey) (*Key, error) {
+
+	// OpenSSH returns Ed25519 private keys by reference
+	if edKey, ok := privKey.(*ed25519.PrivateKey); ok {
+		privKey = *edKey
+	}
+
+	key := &Key{Private: privKey, Public: pubKey, Format: format, Source: sourcePath}
+	switch k := privKey.(type) {
+	case nil:
+	case *rsa.PrivateKey:
+		key.Public = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		key.Public = &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Public = k.Public()
+	case *ecdh.PrivateKey:
+		key.Public = k.PublicKey()
+	default:
+		return nil, &KeyFormatError{Source: sourcePath, Reason: fmt.Sprintf("%T private keys are not supported", privKey)}
+	}
+
+	switch k := key.Public.(type) {
+	case *rsa.PublicKey:
+		key.Algorithm = KeyRSA
+	case *ecdsa.PublicKey:
+		key.Algorithm = KeyEC
+	case ed25519.PublicKey:
+		key.Algorithm = KeyEd25519
+	case *ecdh.PublicKey:
+		if k.Curve() != ecdh.X25519() {
+			return nil, &KeyFormatError{Source: sourcePath, Reason: "only X25519 ecdh keys are supported"}
+		}
+		key.Algorithm = KeyX25519
+	default:
+		return nil, &KeyFormatError{Source: sourcePath, Reason: fmt.Sprintf("%T public keys are not supported", key.Public)}
+	}
+
+	return key, nil
+}
+
+//Describe is an exportable method
+// Describe names the key, e.g. "RSA-2048 private key" or "EC P-256 public key"
+func (k *Key) Describe() string {
+
+	name := k.Algorithm
+	switch pubKey := k.Public.(type) {
+	case *rsa.PublicKey:
+		name = fmt.Sprintf("RSA-%d", pubKey.N.BitLen())
+	case *ecdsa.PublicKey:
+		name = "EC " + pubKey.Curve.Params().Name
+	}
+	if k.Private != nil {
+		return name + " private key"
+	}
+
+	return name + " public key"
+}
+
+//Fingerprint is an exportable method
+// Fingerprint returns the SPKI SHA-256 fingerprint of the key (see SPKIFingerprint)
+func (k *Key) Fingerprint() (string, error) {
+	return SPKIFingerprint(k.Public)
+}
+
+// mismatch builds the error for a key that is not what the caller asked for
+func (k *Key) mismatch(want string) error {
+	return &KeyMismatchError{Source: k.Source, Want: want, Got: k.Describe()}
+}
+
+//RSAPrivate is an exportable method
+// RSAPrivate returns the key as an RSA private key or a KeyMismatchError
+func (k *Key) RSAPrivate() (*rsa.PrivateKey, error) {
+
+	privKey, ok := k.Private.(*rsa.PrivateKey)
+	if !ok {
+		return nil, k.mismatch("RSA private key")
+	}
+
+	return privKey, nil
+}
+
+//RSAPublic is an exportable method
+// RSAPublic returns the RSA public key (of a private key too) or a KeyMismatchError
+func (k *Key) RSAPublic() (*rsa.PublicKey, error) {
+
+	pubKey, ok := k.Public.(*rsa.PublicKey)
+	if !ok {
+		return nil, k.mismatch("RSA public key")
+	}
+
+	return pubKey, nil
+}
+
+//ECPrivate is an exportable method
+// ECPrivate returns the key as an EC (ECDSA) private key or a KeyMismatchError
+func (k *Key) ECPrivate() (*ecdsa.PrivateKey, error) {
+
+	privKey, ok := k.Private.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, k.mismatch("EC private key")
+	}
+
+	return privKey, nil
+}
+
+//ECPublic is an exportable method
+// ECPublic returns the EC (ECDSA) public key (of a private key too) or a KeyMismatchError
+func (k *Key) ECPublic() (*ecdsa.PublicKey, error) {
+
+	pubKey, ok := k.Public.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, k.mismatch("EC public key")
+	}
+
+	return pubKey, nil
+}
+
+//Ed25519Private is an exportable method
+// Ed25519Private returns the key as an Ed25519 private key or a KeyMismatchError
+func (k *Key) Ed25519Private() (ed25519.PrivateKey, error) {
+
+	privKey, ok := k.Private.(ed25519.PrivateKey)
+	if !ok {
+		return nil, k.mismatch("Ed25519 private key")
+	}
+
+	return privKey, nil
+}
+
+//Ed25519Public is an exportable method
+// Ed25519Public returns the Ed25519 public key (of a private key too) or a KeyMismatchError
+func (k *Key) Ed25519Public() (ed25519.PublicKey, error) {
+
+	pubKey, ok := k.Public.(ed25519.PublicKey)
+	if !ok {
+		return nil, k.mismatch("Ed25519 public key")
+	}
+
+	return pubKey, nil
+}
+
+//X25519Private is an exportable method
+// X25519Private returns the key as an X25519 private key or a KeyMismatchError
+func (k *Key) X25519Private() (*ecdh.PrivateKey, error) {
+
+	if k.Algorithm != KeyX25519 || k.Private == nil {
+		return nil, k.mismatch("X25519 private key")
+	}
+
+	return k.Private.(*ecdh.PrivateKey), nil
+}
+
+//X25519Public is an exportable method
+// X25519Public returns the X25519 public key (of a private key too) or a KeyMismatchError
+func (k *Key) X25519Public() (*ecdh.PublicKey, error) {
+
+	if k.Algorithm != KeyX25519 {
+		return nil, k.mismatch("X25519 public key")
+	}
+
+	return k.Public.(*ecdh.PublicKey), nil
+}
+
+//ECDHPrivate is an exportable method
+// ECDHPrivate returns an EC or X25519 private key as an ecdh key or a KeyMismatchError
+func (k *Key) ECDHPrivate() (*ecdh.PrivateKey, error) {
+
+	switch privKey := k.Private.(type) {
+	case *ecdsa.PrivateKey:
+		ecdhKey, err := privKey.ECDH()
+		if err != nil {
+			return nil, k.mismatch("EC (P-256, P-384, P-521) or X25519 private key")
+		}
+		return ecdhKey, nil
+	case *ecdh.PrivateKey:
+		return privKey, nil
+	}
+
+	return nil, k.mismatch("EC or X25519 private key")
+}
+
+//ECDHPublic is an exportable method
+// ECDHPublic returns an EC or X25519 public key (of a private key too) as an ecdh key or a KeyMismatchError
+func (k *Key) ECDHPublic() (*ecdh.PublicKey, error) {
+
+	switch pubKey := k.Public.(type) {
+	case *ecdsa.PublicKey:
+		ecdhKey, err := pubKey.ECDH()
+		if err != nil {
+			return nil, k.mismatch("EC (P-256, P-384, P-521) or X25519 public key")
+		}
+		return ecdhKey, nil
+	case *ecdh.PublicKey:
+		return pubKey, nil
+	}
+
+	return nil, k.mismatch("EC or X25519 public key")
+}
+
+//PrivateKey is an exportable method
+// PrivateKey returns the private key of any algorithm or a KeyMismatchError for a public key
+func (k *Key) PrivateKey() (crypto.PrivateKey, error) {
+
+	if k.Private == nil {
+		return nil, k.mismatch(k.Algorithm + " private key")
+	}
+
+	return k.Private, nil
+}
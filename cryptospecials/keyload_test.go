@@ -0,0 +1,224 @@
+package cryptospecials
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Every encoding of every algorithm must parse back to the same key with the right Algorithm and Format
+func TestParseKeyFormats(t *testing.T) {
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	edKey, _ := Ed25519KeyGen()
+	xKey, _ := X25519KeyGen()
+
+	pemOf := func(label string, der []byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: label, Bytes: der})
+	}
+	pkcs8 := func(privKey interface{}) []byte {
+		der, _ := x509.MarshalPKCS8PrivateKey(privKey)
+		return der
+	}
+	pkix := func(pubKey interface{}) []byte {
+		der, _ := x509.MarshalPKIXPublicKey(pubKey)
+		return der
+	}
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+	sshRSA, _ := ssh.NewPublicKey(&rsaKey.PublicKey)
+	sshEd, _ := ssh.NewPublicKey(edKey.Public())
+	sshECBlock, _ := ssh.MarshalPrivateKey(ecKey, "")
+	b64 := base64.RawURLEncoding.EncodeToString
+	rsaJWK := fmt.Sprintf(`{"kty":"RSA","n":"%s","e":"AQAB","d":"%s","p":"%s","q":"%s"}`,
+		b64(rsaKey.N.Bytes()), b64(rsaKey.D.Bytes()), b64(rsaKey.Primes[0].Bytes()), b64(rsaKey.Primes[1].Bytes()))
+	xJWK := fmt.Sprintf(`{"kty":"OKP","crv":"X25519","x":"%s","d":"%s"}`, b64(xKey.PublicKey().Bytes()), b64(xKey.Bytes()))
+
+	tests := []struct {
+		name      string
+		data      []byte
+		algorithm string
+		format    string
+		private   bool
+	}{
+		{"RSA PKCS#1 PEM", pemOf("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), KeyRSA, FormatPKCS1, true},
+		{"RSA PKCS#8 PEM", pemOf("PRIVATE KEY", pkcs8(rsaKey)), KeyRSA, FormatPKCS8, true},
+		{"RSA PKCS#1 under PRIVATE KEY", pemOf("PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), KeyRSA, FormatPKCS1, true},
+		{"RSA PKIX PEM", pemOf("PUBLIC KEY", pkix(&rsaKey.PublicKey)), KeyRSA, FormatPKIX, false},
+		{"RSA PKIX under RSA PUBLIC KEY", pemOf("RSA PUBLIC KEY", pkix(&rsaKey.PublicKey)), KeyRSA, FormatPKIX, false},
+		{"RSA PKCS#1 public PEM", pemOf("RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)), KeyRSA, FormatPKCS1, false},
+		{"RSA PKCS#8 DER", pkcs8(rsaKey), KeyRSA, FormatPKCS8, true},
+		{"RSA PKCS#1 DER", x509.MarshalPKCS1PrivateKey(rsaKey), KeyRSA, FormatPKCS1, true},
+		{"RSA authorized_keys", ssh.MarshalAuthorizedKey(sshRSA), KeyRSA, FormatOpenSSH, false},
+		{"RSA JWK", []byte(rsaJWK), KeyRSA, FormatJWK, true},
+		{"EC SEC1 PEM", pemOf("EC PRIVATE KEY", sec1), KeyEC, FormatSEC1, true},
+		{"EC SEC1 under PRIVATE KEY", pemOf("PRIVATE KEY", sec1), KeyEC, FormatSEC1, true},
+		{"EC PKCS#8 PEM", pemOf("PRIVATE KEY", pkcs8(ecKey)), KeyEC, FormatPKCS8, true},
+		{"EC PKIX PEM", pemOf("PUBLIC KEY", pkix(&ecKey.PublicKey)), KeyEC, FormatPKIX, false},
+		{"EC SEC1 DER", sec1, KeyEC, FormatSEC1, true},
+		{"EC PKIX DER", pkix(&ecKey.PublicKey), KeyEC, FormatPKIX, false},
+		{"EC OpenSSH", pem.EncodeToMemory(sshECBlock), KeyEC, FormatOpenSSH, true},
+		{"Ed25519 PKCS#8 PEM", pemOf("PRIVATE KEY", pkcs8(edKey)), KeyEd25519, FormatPKCS8, true},
+		{"Ed25519 PKIX PEM", pemOf("PUBLIC KEY", pkix(edKey.Public())), KeyEd25519, FormatPKIX, false},
+		{"Ed25519 authorized_keys", ssh.MarshalAuthorizedKey(sshEd), KeyEd25519, FormatOpenSSH, false},
+		{"X25519 PKCS#8 PEM", pemOf("PRIVATE KEY", pkcs8(xKey)), KeyX25519, FormatPKCS8, true},
+		{"X25519 PKIX DER", pkix(xKey.PublicKey()), KeyX25519, FormatPKIX, false},
+		{"X25519 JWK", []byte(xJWK), KeyX25519, FormatJWK, true},
+	}
+
+	for _, test := range tests {
+		key, err := ParseKey(test.data, test.name)
+		if err != nil {
+			t.Errorf("FAIL - ParseKey (%s) - %v", test.name, err)
+			continue
+		}
+		if key.Algorithm != test.algorithm || key.Format != test.format || (key.Private != nil) != test.private {
+			t.Errorf("FAIL - ParseKey (%s) read an %s from %s", test.name, key.Describe(), key.Format)
+		}
+		want := map[string]interface{ Equal(x crypto.PublicKey) bool }{
+			KeyRSA:     &rsaKey.PublicKey,
+			KeyEC:      &ecKey.PublicKey,
+			KeyEd25519: edKey.Public().(ed25519.PublicKey),
+			KeyX25519:  xKey.PublicKey(),
+		}[test.algorithm]
+		if !want.Equal(key.Public) {
+			t.Errorf("FAIL - ParseKey (%s) returned the wrong public key", test.name)
+		}
+	}
+}
+
+// Keys written by ssh-keygen, 'openssl ecparam -genkey', and the JWK examples of RFC 7517 and RFC 8037
+func TestLoadKeyFixtures(t *testing.T) {
+
+	defer func() { PassphraseFunc = nil }()
+	PassphraseFunc = func(string) ([]byte, error) { return []byte("foiltest"), nil }
+
+	for _, pair := range [][2]string{{"openssh_ed25519.pem", "openssh_ed25519.pub"}, {"openssh_ecdsa.pem", "openssh_ecdsa.pub"}} {
+		privKey, err := LoadKey("testdata/" + pair[0])
+		if err != nil {
+			t.Fatalf("FAIL - LoadKey (%s) - %v", pair[0], err)
+		}
+		pubKey, err := LoadKey("testdata/" + pair[1])
+		if err != nil {
+			t.Fatalf("FAIL - LoadKey (%s) - %v", pair[1], err)
+		}
+		if privKey.Format != FormatOpenSSH || pubKey.Private != nil || privKey.Algorithm != pubKey.Algorithm {
+			t.Errorf("FAIL - %s and %s were read as %s and %s", pair[0], pair[1], privKey.Describe(), pubKey.Describe())
+		}
+		if !pubKey.Public.(interface{ Equal(x crypto.PublicKey) bool }).Equal(privKey.Public) {
+			t.Errorf("FAIL - %s and %s do not hold the same key", pair[0], pair[1])
+		}
+	}
+
+	if _, err := EccPrivKeyLoad("testdata/openssl_ecparam.pem"); err != nil {
+		t.Errorf("FAIL - EccPrivKeyLoad of 'openssl ecparam -genkey' output - %v", err)
+	}
+
+	ecKey, err := EccPrivKeyLoad("testdata/jwk_ec_p256.json")
+	if err != nil {
+		t.Fatalf("FAIL - EccPrivKeyLoad (JWK) - %v", err)
+	} else if ecKey.Curve != elliptic.P256() || hex.EncodeToString(ecKey.D.Bytes()) != "f3bd0c07a81fb932781ed52752f60cc89a6be5e51934fe01938ddb55d8f77801" {
+		t.Errorf("FAIL - The EC JWK did not load as the RFC 7517 key")
+	}
+	edKey, err := Ed25519PrivKeyLoad("testdata/jwks_ed25519.json")
+	if err != nil {
+		t.Fatalf("FAIL - Ed25519PrivKeyLoad (JWK Set) - %v", err)
+	} else if hex.EncodeToString(edKey.Seed()) != "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60" {
+		t.Errorf("FAIL - The Ed25519 JWK did not load as the RFC 8037 key")
+	}
+}
+
+// Loading the wrong kind of key returns a KeyMismatchError; unreadable input returns a KeyFormatError
+func TestLoadKeyErrors(t *testing.T) {
+
+	edKey, _ := Ed25519KeyGen()
+	edPKCS8, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	edPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edPKCS8})
+	key, err := ParseKey(edPEM, "edKey.pem")
+	if err != nil {
+		t.Fatalf("FAIL - ParseKey - %v", err)
+	}
+
+	var mismatch *KeyMismatchError
+	if _, err = key.RSAPrivate(); !errors.As(err, &mismatch) || mismatch.Source != "edKey.pem" {
+		t.Errorf("FAIL - RSAPrivate of an Ed25519 key returned %v", err)
+	}
+	if _, err = key.ECDHPrivate(); !errors.As(err, &mismatch) {
+		t.Errorf("FAIL - ECDHPrivate of an Ed25519 key returned %v", err)
+	}
+	pubKey := &Key{Public: key.Public, Algorithm: KeyEd25519, Source: "edPub.pem"}
+	if _, err = pubKey.PrivateKey(); !errors.As(err, &mismatch) {
+		t.Errorf("FAIL - PrivateKey of a public key returned %v", err)
+	}
+	if _, err = pubKey.Ed25519Public(); err != nil {
+		t.Errorf("FAIL - Ed25519Public - %v", err)
+	}
+
+	// A P-256 ecdh key is not an X25519 key; nor are malformed PEMs, JWKs, or plain text keys at all
+	p256Key, _ := ecdh.P256().GenerateKey(rand.Reader)
+	p256PKIX, _ := x509.MarshalPKIXPublicKey(p256Key.PublicKey())
+	var format *KeyFormatError
+	for name, data := range map[string][]byte{
+		"empty":        {},
+		"text":         []byte("LegitString"),
+		"bad PEM body": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("LegitString")}),
+		"CSR":          pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: []byte("LegitString")}),
+		"JWK Set":      []byte(`{"keys":[]}`),
+		"JWK kty":      []byte(`{"kty":"oct","k":"AAAA"}`),
+		"JWK point":    []byte(`{"kty":"EC","crv":"P-256","x":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA","y":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}`),
+	} {
+		if _, err = ParseKey(data, name); !errors.As(err, &format) {
+			t.Errorf("FAIL - ParseKey (%s) returned %v", name, err)
+		}
+	}
+	if key, err = ParseKey(p256PKIX, "p256"); err != nil || key.Algorithm != KeyEC {
+		t.Errorf("FAIL - A P-256 PKIX key must load as EC - %v", err)
+	}
+	if _, err = key.X25519Public(); !errors.As(err, &mismatch) {
+		t.Errorf("FAIL - X25519Public of a P-256 key returned %v", err)
+	}
+	if _, err = EccPubKeyLoad("missing.pem"); err == nil {
+		t.Errorf("FAIL - EccPubKeyLoad of a missing file did not fail")
+	}
+}
+
+// The public key of an X.509 certificate is loaded as a public key
+func TestLoadKeyCertificate(t *testing.T) {
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "foil"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &ecKey.PublicKey, ecKey)
+	if err != nil {
+		t.Fatalf("FAIL - CreateCertificate - %v", err)
+	}
+
+	key, err := ParseKey(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), "cert.pem")
+	if err != nil || key.Format != FormatX509 || key.Private != nil {
+		t.Fatalf("FAIL - ParseKey (certificate) - %v", err)
+	}
+	pubKey, err := key.ECPublic()
+	if err != nil || !pubKey.Equal(&ecKey.PublicKey) {
+		t.Errorf("FAIL - ECPublic (certificate) - %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+*	This package contains mechanisms that will allow for VRF and OPRF calculations.
+*
+*	OPRF: https://eprint.iacr.org/2017/111
+*
+*	RSA-VRF: https://eprint.iacr.org/2017/099.pdf
+*
+*		-Brian
+ */
+
+package cryptospecials
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"foil/helpers"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshPublicKey converts an RSA, EC, or Ed25519 private or public key into an ssh public key
+func sshPublicKey(key interface{}) (ssh.PublicKey, error) {
+
+	key = helpers.PublicOf(key)
+	if _, ok := key.(*ecdh.PublicKey); ok {
+		return nil, errors.New("Error: X25519 keys can not be used with SSH")
+	}
+	sshKey, err := ssh.NewPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("Error: The key can not be used with SSH: %v", err)
+	}
+
+	return sshKey, nil
+}
+
+//SSHFingerprint is an exportable function
+/*
+*  SSHFingerprint returns the "SHA256:..." fingerprint that ssh-keygen -l shows for an RSA,
+*  EC (P-256, P-384, P-521), or Ed25519 private or public key.
+ */
+func SSHFingerprint(key interface{}) (string, error) {
+
+	sshKey, err := sshPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	return ssh.FingerprintSHA256(sshKey), nil
+}
+
+//MarshalAuthorizedKey is an exportable function
+// MarshalAuthorizedKey writes a private or public key as an authorized_keys line, e.g. "ssh-ed25519 AAAA... comment"
+func MarshalAuthorizedKey(key interface{}, comment string) ([]byte, error) {
+
+	sshKey, err := sshPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	line := ssh.MarshalAuthorizedKey(sshKey)
+	if len(comment) > 0 {
+		line = append(bytes.TrimSuffix(line, []byte("\n")), []byte(" "+comment+"\n")...)
+	}
+
+	return line, nil
+}
+
+//MarshalOpenSSHPrivateKey is an exportable function
+/*
+*  MarshalOpenSSHPrivateKey writes an RSA, EC, or Ed25519 private key as an "OPENSSH PRIVATE KEY"
+*  PEM, the format written by ssh-keygen. A passphrase encrypts the key the way ssh-keygen does
+*  (bcrypt_pbkdf and AES-256-CTR); nil leaves it unencrypted.
+ */
+func MarshalOpenSSHPrivateKey(privKey crypto.PrivateKey, comment string, passphrase []byte) ([]byte, error) {
+
+	var (
+		block *pem.Block
+		err   error
+	)
+
+	_, err = sshPublicKey(privKey)
+	if err != nil {
+		return nil, err
+	}
+	if passphrase != nil {
+		if len(passphrase) == 0 {
+			return nil, errors.New("Error: The passphrase must not be empty")
+		}
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(privKey, comment, passphrase)
+	} else {
+		block, err = ssh.MarshalPrivateKey(privKey, comment)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error: %v", err)
+	}
+
+	return pem.EncodeToMemory(block), nil
+}
+
+//OpenSSHKeySave is an exportable function
+/*
+*  OpenSSHKeySave saves privKey in OpenSSH format at savePriv (0600) and its authorized_keys
+*  line at savePub (0644), as ssh-keygen does. Either path may be empty to skip that file; a
+*  public key may be given when savePriv is empty. Both encodings are returned.
+ */
+func OpenSSHKeySave(key interface{}, comment string, passphrase []byte, savePriv string, savePub string) (privBytes []byte, pubBytes []byte, err error) {
+
+	if IsPrivateKey(key) {
+		privBytes, err = MarshalOpenSSHPrivateKey(key, comment, passphrase)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if len(savePriv) > 0 {
+		return nil, nil, errors.New("Error: A private key is required to save an OpenSSH private key")
+	}
+	pubBytes, err = MarshalAuthorizedKey(key, comment)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, file := range []struct {
+		path string
+		data []byte
+		perm os.FileMode
+	}{{savePriv, privBytes, 0600}, {savePub, pubBytes, 0644}} {
+		if len(file.path) == 0 {
+			continue
+		}
+		err = writeKeyFile(file.path, file.data, file.perm)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return privBytes, pubBytes, nil
+}
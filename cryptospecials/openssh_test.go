@@ -0,0 +1,70 @@
+package cryptospecials
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/elliptic"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Save keys in OpenSSH format and load them back; the fingerprints must match ssh-keygen -l
+func TestOpenSSHKeySave(t *testing.T) {
+
+	defer func() { PassphraseFunc = nil }()
+	defer os.Remove("sshKey")
+	defer os.Remove("sshKey.pub")
+	PassphraseFunc = func(string) ([]byte, error) { return []byte("LegitPassphrase"), nil }
+
+	rsaKey, _ := RSAKeyGen(2048)
+	ecKey, _ := EccPrivKeyGen(elliptic.P256())
+	edKey, _ := Ed25519KeyGen()
+	// The first save replaces a world readable file
+	ioutil.WriteFile("sshKey", []byte("public"), 0644)
+	for _, privKey := range []interface{}{rsaKey, ecKey, edKey} {
+		for _, passphrase := range [][]byte{nil, []byte("LegitPassphrase")} {
+			_, pubBytes, err := OpenSSHKeySave(privKey, "foil@test", passphrase, "sshKey", "sshKey.pub")
+			if err != nil {
+				t.Fatalf("FAIL - OpenSSHKeySave (%T) - %v", privKey, err)
+			}
+			if !bytes.HasSuffix(pubBytes, []byte(" foil@test\n")) {
+				t.Errorf("FAIL - The authorized_keys line has no comment: %s", pubBytes)
+			}
+			if info, err := os.Stat("sshKey"); err != nil || info.Mode().Perm() != 0600 {
+				t.Errorf("FAIL - The OpenSSH private key is not 0600")
+			}
+			privKeyLoad, err := LoadKey("sshKey")
+			if err != nil || privKeyLoad.Format != FormatOpenSSH || !privKey.(interface{ Equal(crypto.PrivateKey) bool }).Equal(privKeyLoad.Private) {
+				t.Errorf("FAIL - LoadKey of the OpenSSH private key (%T) - %v", privKey, err)
+			}
+			pubKeyLoad, err := LoadKey("sshKey.pub")
+			if err != nil || !pubKeyLoad.Public.(interface{ Equal(crypto.PublicKey) bool }).Equal(privKeyLoad.Public) {
+				t.Errorf("FAIL - LoadKey of the authorized_keys line (%T) - %v", privKey, err)
+			}
+		}
+	}
+
+	xKey, _ := X25519KeyGen()
+	if _, _, err := OpenSSHKeySave(xKey, "", nil, "", ""); err == nil {
+		t.Errorf("FAIL - OpenSSHKeySave accepted an X25519 key")
+	}
+	if _, _, err := OpenSSHKeySave(edKey.Public(), "", nil, "sshKey", ""); err == nil {
+		t.Errorf("FAIL - OpenSSHKeySave saved a public key as a private key")
+	}
+
+	// 'ssh-keygen -l -f' of the fixtures
+	for name, want := range map[string]string{
+		"testdata/openssh_ed25519.pub": "SHA256:4OyI/1fpVmiXmAjfGUE/DUGrw3Ymp5SJKWoWDitWq+g",
+		"testdata/openssh_ecdsa.pub":   "SHA256:L0WP/jXSn9rGQcDin0k+IXpZ6gp4PrPN9XoO8eR7Evs",
+	} {
+		key, err := LoadKey(name)
+		if err != nil {
+			t.Fatalf("FAIL - LoadKey (%s) - %v", name, err)
+		}
+		fingerprint, err := SSHFingerprint(key.Public)
+		if err != nil || fingerprint != want {
+			t.Errorf("FAIL - SSHFingerprint (%s) = %s; want %s", name, fingerprint, want)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+/*
+*	This package contains mechanisms that will allow for VRF and OPRF calculations.
+*
+*	OPRF: https://eprint.iacr.org/2017/111
+*
+*	RSA-VRF: https://eprint.iacr.org/2017/099.pdf
+*
+*		-Brian
+ */
+
+package cryptospecials
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+
+	"foil/helpers"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+/*
+*  Password protected private keys are PKCS#8 EncryptedPrivateKeyInfo (RFC 5958) PEMs of type
+*  "ENCRYPTED PRIVATE KEY" using PBES2 (RFC 8018) with AES-256-CBC. The key is derived with
+*  PBKDF2-HMAC-SHA256 or scrypt (RFC 7914). These are the PEMs written by 'openssl pkcs8 -topk8'
+*  and 'openssl genpkey -aes-256-cbc' and both directions interoperate.
+ */
+
+// Key derivation functions for encrypted private keys
+const (
+	PKCS8PBKDF2 = "pbkdf2"
+	PKCS8Scrypt = "scrypt"
+)
+
+/*
+*  Work factors for newly encrypted private keys. scrypt uses the OpenSSL defaults (16 MiB)
+*  because OpenSSL refuses to decrypt keys that need more than 32 MiB.
+ */
+const (
+	pkcs8PBKDF2Iterations = 600000
+	pkcs8ScryptN          = 1 << 14
+	pkcs8ScryptR          = 8
+	pkcs8ScryptP          = 1
+	pkcs8SaltSize         = 16
+)
+
+//ErrPassphraseRequired is an exportable VARIABLE
+// ErrPassphraseRequired is returned when an encrypted private key is loaded without a PassphraseFunc
+var ErrPassphraseRequired = errors.New("Error: The private key is encrypted; a passphrase is required")
+
+//ErrIncorrectPassphrase is an exportable VARIABLE
+// ErrIncorrectPassphrase is returned when an encrypted private key does not decrypt
+var ErrIncorrectPassphrase = errors.New("Error: Unable to decrypt the private key - incorrect passphrase or corrupted PEM")
+
+//PassphraseFunc is an exportable VARIABLE
+/*
+*  PassphraseFunc supplies the passphrase for the encrypted private key PEM at sourcePath. Every
+*  private key loader in this package calls it when it reads an "ENCRYPTED PRIVATE KEY" PEM. It
+*  is nil by default, in which case encrypted keys fail to load with ErrPassphraseRequired.
+ */
+var PassphraseFunc func(sourcePath string) ([]byte, error)
+
+var (
+	oidPBES2        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidScrypt       = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+	oidHMACSHA1     = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACSHA256   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACSHA384   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACSHA512   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC    = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC    = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC    = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	aesCBCKeySizes  = map[string]int{oidAES128CBC.String(): 16, oidAES192CBC.String(): 24, oidAES256CBC.String(): 32}
+	pbkdf2PRFHashes = map[string]func() hash.Hash{
+		oidHMACSHA1.String():   sha1.New,
+		oidHMACSHA256.String(): sha256.New,
+		oidHMACSHA384.String(): sha512.New384,
+		oidHMACSHA512.String(): sha512.New,
+	}
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algo          pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+type scryptParams struct {
+	Salt                     []byte
+	CostParameter            int
+	BlockSize                int
+	ParallelizationParameter int
+	KeyLength                int `asn1:"optional"`
+}
+
+//EncryptPKCS8PrivateKey is an exportable function
+/*
+*  EncryptPKCS8PrivateKey marshals privKey as PKCS#8 and encrypts it under passphrase with
+*  PBES2, AES-256-CBC, and kdf (PKCS8PBKDF2 or PKCS8Scrypt). The result is an "ENCRYPTED
+*  PRIVATE KEY" PEM block.
+ */
+func EncryptPKCS8PrivateKey(privKey crypto.PrivateKey, passphrase []byte, kdf string) (*pem.Block, error) {
+
+	var (
+		kdfAlgo pkix.AlgorithmIdentifier
+		key     []byte
+	)
+
+	if len(passphrase) == 0 {
+		return nil, errors.New("Error: The passphrase must not be empty")
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privKey)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, pkcs8SaltSize)
+	iv := make([]byte, aes.BlockSize)
+	_, err = rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	_, err = rand.Read(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kdf {
+	case PKCS8PBKDF2:
+		key = pbkdf2.Key(passphrase, salt, pkcs8PBKDF2Iterations, 32, sha256.New)
+		kdfAlgo.Algorithm = oidPBKDF2
+		kdfAlgo.Parameters.FullBytes, err = asn1.Marshal(pbkdf2Params{
+			Salt:           salt,
+			IterationCount: pkcs8PBKDF2Iterations,
+			KeyLength:      32,
+			PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACSHA256, Parameters: asn1.NullRawValue},
+		})
+	case PKCS8Scrypt:
+		key, err = scrypt.Key(passphrase, salt, pkcs8ScryptN, pkcs8ScryptR, pkcs8ScryptP, 32)
+		if err != nil {
+			return nil, err
+		}
+		kdfAlgo.Algorithm = oidScrypt
+		kdfAlgo.Parameters.FullBytes, err = asn1.Marshal(scryptParams{
+			Salt:                     salt,
+			CostParameter:            pkcs8ScryptN,
+			BlockSize:                pkcs8ScryptR,
+			ParallelizationParameter: pkcs8ScryptP,
+			KeyLength:                32,
+		})
+	default:
+		return nil, fmt.Errorf("Error: Unknown key encryption KDF %q; use pbkdf2 or scrypt", kdf)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// AES-256-CBC with PKCS#7 padding
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padLen := aes.BlockSize - len(der)%aes.BlockSize
+	cipherText := append(der, bytes.Repeat([]byte{byte(padLen)}, padLen)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(cipherText, cipherText)
+
+	encAlgo := pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC}
+	encAlgo.Parameters.FullBytes, err = asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{KeyDerivationFunc: kdfAlgo, EncryptionScheme: encAlgo})
+	if err != nil {
+		return nil, err
+	}
+	info, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algo:          pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: cipherText,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: info}, nil
+}
+
+//DecryptPKCS8PrivateKey is an exportable function
+/*
+*  DecryptPKCS8PrivateKey decrypts the DER of an "ENCRYPTED PRIVATE KEY" PEM and returns the
+*  PKCS#8 DER of the private key. PBES2 with PBKDF2 (HMAC-SHA1/256/384/512) or scrypt and
+*  AES-128/192/256-CBC is supported.
+ */
+func DecryptPKCS8PrivateKey(der []byte, passphrase []byte) ([]byte, error) {
+
+	var (
+		info   encryptedPrivateKeyInfo
+		params pbes2Params
+		key    []byte
+	)
+
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil || len(rest) > 0 {
+		return nil, errors.New("Error: Unable to parse the encrypted private key")
+	} else if !info.Algo.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("Error: Unsupported private key encryption %s; only PBES2 is supported", info.Algo.Algorithm)
+	}
+	_, err = asn1.Unmarshal(info.Algo.Parameters.FullBytes, &params)
+	if err != nil {
+		return nil, errors.New("Error: Unable to parse the PBES2 parameters")
+	}
+
+	// The cipher must be AES-CBC with a 16 BYTE IV
+	keySize, ok := aesCBCKeySizes[params.EncryptionScheme.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("Error: Unsupported private key cipher %s; only AES-CBC is supported", params.EncryptionScheme.Algorithm)
+	}
+	var iv []byte
+	_, err = asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv)
+	if err != nil || len(iv) != aes.BlockSize {
+		return nil, errors.New("Error: The AES-CBC IV of the private key is not valid")
+	}
+
+	kdfParams := params.KeyDerivationFunc.Parameters.FullBytes
+	// The work factors are bounded as in container headers so that a hostile PEM cannot exhaust the host
+	switch {
+	case params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2):
+		var p pbkdf2Params
+		_, err = asn1.Unmarshal(kdfParams, &p)
+		if err != nil {
+			return nil, errors.New("Error: Unable to parse the PBKDF2 parameters")
+		} else if p.IterationCount < 1 || p.IterationCount > helpers.MaxPBKDF2Iterations {
+			return nil, fmt.Errorf("Error: PBKDF2 iterations must be between 1 and %d", helpers.MaxPBKDF2Iterations)
+		} else if p.KeyLength != 0 && p.KeyLength != keySize {
+			return nil, errors.New("Error: The PBKDF2 key length does not match the cipher")
+		}
+		prf := sha1.New
+		if len(p.PRF.Algorithm) > 0 {
+			prf, ok = pbkdf2PRFHashes[p.PRF.Algorithm.String()]
+			if !ok {
+				return nil, fmt.Errorf("Error: Unsupported PBKDF2 PRF %s", p.PRF.Algorithm)
+			}
+		}
+		key = pbkdf2.Key(passphrase, p.Salt, p.IterationCount, keySize, prf)
+	case params.KeyDerivationFunc.Algorithm.Equal(oidScrypt):
+		var p scryptParams
+		_, err = asn1.Unmarshal(kdfParams, &p)
+		if err != nil {
+			return nil, errors.New("Error: Unable to parse the scrypt parameters")
+		} else if p.BlockSize < 1 || p.ParallelizationParameter < 1 || p.CostParameter < 2 {
+			return nil, errors.New("Error: The scrypt parameters are not valid")
+		} else if p.ParallelizationParameter > helpers.MaxScryptP {
+			return nil, fmt.Errorf("Error: scrypt p must be at most %d", helpers.MaxScryptP)
+		} else if int64(p.CostParameter)*int64(p.BlockSize)/8 > helpers.MaxKDFMemory {
+			return nil, fmt.Errorf("Error: scrypt N*r requires more than %d KiB of memory", helpers.MaxKDFMemory)
+		} else if p.KeyLength != 0 && p.KeyLength != keySize {
+			return nil, errors.New("Error: The scrypt key length does not match the cipher")
+		}
+		key, err = scrypt.Key(passphrase, p.Salt, p.CostParameter, p.BlockSize, p.ParallelizationParameter, keySize)
+		if err != nil {
+			return nil, fmt.Errorf("Error: scrypt: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("Error: Unsupported private key KDF %s; use PBKDF2 or scrypt", params.KeyDerivationFunc.Algorithm)
+	}
+
+	// Decrypt and strip the PKCS#7 padding; bad padding almost always means a wrong passphrase
+	cipherText := info.EncryptedData
+	if len(cipherText) == 0 || len(cipherText)%aes.BlockSize != 0 {
+		return nil, ErrIncorrectPassphrase
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plainText := make([]byte, len(cipherText))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plainText, cipherText)
+	padLen := int(plainText[len(plainText)-1])
+	if padLen == 0 || padLen > aes.BlockSize || !bytes.Equal(plainText[len(plainText)-padLen:], bytes.Repeat([]byte{byte(padLen)}, padLen)) {
+		return nil, ErrIncorrectPassphrase
+	}
+	plainText = plainText[:len(plainText)-padLen]
+	_, err = x509.ParsePKCS8PrivateKey(plainText)
+	if err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	return plainText, nil
+}
+
+//EncryptedKeySave is an exportable function
+/*
+*  EncryptedKeySave encrypts privKey with EncryptPKCS8PrivateKey and saves the PEM at savePath
+*  with 0600 permissions. The PEM is also returned so that it can be printed; savePath may be
+*  empty to skip the file.
+ */
+func EncryptedKeySave(privKey crypto.PrivateKey, passphrase []byte, kdf string, savePath string) (pemBytes []byte, err error) {
+
+	block, err := EncryptPKCS8PrivateKey(privKey, passphrase, kdf)
+	if err != nil {
+		return nil, err
+	}
+	if len(savePath) > 0 {
+		err = writePEM(savePath, block.Type, block.Bytes, 0600)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pem.EncodeToMemory(block), nil
+}
+
+/*
+*  privateKeyDER returns the DER of a private key PEM block read from sourcePath. Encrypted
+*  PKCS#8 blocks are decrypted with the passphrase from PassphraseFunc.
+ */
+func privateKeyDER(block *pem.Block, sourcePath string) ([]byte, error) {
+
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		if PassphraseFunc == nil {
+			return nil, ErrPassphraseRequired
+		}
+		passphrase, err := PassphraseFunc(sourcePath)
+		if err != nil {
+			return nil, err
+		}
+		return DecryptPKCS8PrivateKey(block.Bytes, passphrase)
+	}
+	if block.Headers["Proc-Type"] == "4,ENCRYPTED" {
+		return nil, errors.New("Error: Legacy OpenSSL PEM encryption is not supported; convert the key with 'openssl pkcs8 -topk8 -v2 aes-256-cbc'")
+	}
+
+	return block.Bytes, nil
+}
@@ -0,0 +1,113 @@
+package cryptospecials
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"errors"
+	"os"
+	"testing"
+)
+
+// Encrypt RSA, EC, and Ed25519 keys with each KDF and load them back through the loaders
+func TestEncryptedKeySaveLoad(t *testing.T) {
+
+	defer func() { PassphraseFunc = nil }()
+	defer os.Remove("encPriv.pem")
+	passphrase := []byte("LegitPassphrase")
+	PassphraseFunc = func(string) ([]byte, error) { return passphrase, nil }
+
+	rsaKey, _ := RSAKeyGen(2048)
+	ecKey, _ := EccPrivKeyGen(elliptic.P384())
+	edKey, _ := Ed25519KeyGen()
+
+	for _, kdf := range []string{PKCS8PBKDF2, PKCS8Scrypt} {
+		_, err := EncryptedKeySave(rsaKey, passphrase, kdf, "encPriv.pem")
+		if err != nil {
+			t.Fatalf("FAIL - EncryptedKeySave (RSA, %s) - %v", kdf, err)
+		}
+		if info, err := os.Stat("encPriv.pem"); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("FAIL - Encrypted private key PEM is not 0600")
+		}
+		source := "encPriv.pem"
+		loadRSAKey, err := RSAPrivKeyLoad(&source, false)
+		if err != nil || !loadRSAKey.Equal(rsaKey) {
+			t.Errorf("FAIL - RSAPrivKeyLoad (%s) - %v", kdf, err)
+		}
+
+		EncryptedKeySave(ecKey, passphrase, kdf, "encPriv.pem")
+		loadECKey, err := EccPrivKeyLoad("encPriv.pem")
+		if err != nil || !loadECKey.Equal(ecKey) {
+			t.Errorf("FAIL - EccPrivKeyLoad (%s) - %v", kdf, err)
+		}
+
+		EncryptedKeySave(edKey, passphrase, kdf, "encPriv.pem")
+		loadEdKey, err := Ed25519PrivKeyLoad("encPriv.pem")
+		if err != nil || !loadEdKey.Equal(edKey) {
+			t.Errorf("FAIL - Ed25519PrivKeyLoad (%s) - %v", kdf, err)
+		}
+	}
+
+	// A wrong passphrase and a missing PassphraseFunc must both fail to load the key
+	PassphraseFunc = func(string) ([]byte, error) { return []byte("WrongPassphrase"), nil }
+	if _, err := Ed25519PrivKeyLoad("encPriv.pem"); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Errorf("FAIL - Expected ErrIncorrectPassphrase; got %v", err)
+	}
+	PassphraseFunc = nil
+	if _, err := Ed25519PrivKeyLoad("encPriv.pem"); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("FAIL - Expected ErrPassphraseRequired; got %v", err)
+	}
+	if _, err := EncryptedKeySave(edKey, nil, PKCS8PBKDF2, ""); err == nil {
+		t.Errorf("FAIL - Encrypted a private key with an empty passphrase")
+	}
+}
+
+// Load keys encrypted by 'openssl pkcs8 -topk8' with PBKDF2 (HMAC-SHA256 and HMAC-SHA1) and scrypt
+func TestEncryptedKeyOpenSSL(t *testing.T) {
+
+	defer func() { PassphraseFunc = nil }()
+	PassphraseFunc = func(string) ([]byte, error) { return []byte("foiltest"), nil }
+
+	pubKey, err := EccPubKeyLoad("testdata/openssl_pub.pem")
+	if err != nil {
+		t.Fatalf("FAIL - EccPubKeyLoad - %v", err)
+	}
+	for _, name := range []string{"openssl_pbkdf2_sha256.pem", "openssl_pbkdf2_sha1.pem", "openssl_scrypt.pem"} {
+		privKey, err := EccPrivKeyLoad("testdata/" + name)
+		if err != nil {
+			t.Errorf("FAIL - EccPrivKeyLoad (%s) - %v", name, err)
+		} else if !privKey.PublicKey.Equal(pubKey) {
+			t.Errorf("FAIL - %s does not hold the expected key", name)
+		}
+	}
+}
+
+// Keys whose work factors would exhaust the host are rejected before the KDF runs
+func TestEncryptedKeyHostileParams(t *testing.T) {
+
+	edKey, _ := Ed25519KeyGen()
+	block, err := EncryptPKCS8PrivateKey(edKey, []byte("LegitPassphrase"), PKCS8Scrypt)
+	if err != nil {
+		t.Fatalf("FAIL - EncryptPKCS8PrivateKey - %v", err)
+	}
+
+	var (
+		info   encryptedPrivateKeyInfo
+		params pbes2Params
+		p      scryptParams
+	)
+	asn1.Unmarshal(block.Bytes, &info)
+	asn1.Unmarshal(info.Algo.Parameters.FullBytes, &params)
+	asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &p)
+
+	for _, hostile := range []scryptParams{
+		{Salt: p.Salt, CostParameter: 1 << 14, BlockSize: 8, ParallelizationParameter: 1 << 26},
+		{Salt: p.Salt, CostParameter: 1 << 21, BlockSize: 8, ParallelizationParameter: 1},
+	} {
+		params.KeyDerivationFunc.Parameters.FullBytes, _ = asn1.Marshal(hostile)
+		info.Algo.Parameters.FullBytes, _ = asn1.Marshal(params)
+		der, _ := asn1.Marshal(info)
+		if _, err = DecryptPKCS8PrivateKey(der, []byte("LegitPassphrase")); err == nil || errors.Is(err, ErrIncorrectPassphrase) {
+			t.Errorf("FAIL - scrypt N=%d, r=%d, p=%d was not rejected: %v", hostile.CostParameter, hostile.BlockSize, hostile.ParallelizationParameter, err)
+		}
+	}
+}
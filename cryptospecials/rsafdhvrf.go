@@ -0,0 +1,301 @@
+/*
+*	This package contains mechanisms that will allow for VRF and OPRF calculations.
+*
+*	OPRF: https://eprint.iacr.org/2017/111
+*
+*	RSA-VRF: https://eprint.iacr.org/2017/099.pdf
+*
+*	RSA-FDH-VRF: https://www.rfc-editor.org/rfc/rfc9381#section-4
+*
+*		-Brian
+ */
+
+package cryptospecials
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+
+	"foil/helpers"
+)
+
+// RSA-FDH-VRF suites of RFC 9381 supported by RSAFDHVRF
+const (
+	SuiteRSAFDHVRFSHA256 = "RSA-FDH-VRF-SHA256"
+	SuiteRSAFDHVRFSHA384 = "RSA-FDH-VRF-SHA384"
+	SuiteRSAFDHVRFSHA512 = "RSA-FDH-VRF-SHA512"
+)
+
+//RSAFDHVRF is an exportable struct
+/*
+*  RSAFDHVRF is the RSA full domain hash VRF of RFC 9381 section 4. Unlike RSAVRF (based on
+*  the NSEC5 paper), its proofs (pi) and outputs (beta) are byte strings that any other RFC 9381
+*  implementation of the same suite produces and verifies. Suite names one of the Suite
+*  constants.
+ */
+type RSAFDHVRF struct {
+	Suite string
+	Proof []byte
+	Alpha []byte
+	Beta  []byte
+}
+
+// rsaFDHVRFSuite holds the suite_string and hash (for MGF1 and proof_to_hash) of a suite
+type rsaFDHVRFSuite struct {
+	name        string
+	suiteString byte
+	newHash     func() hash.Hash
+}
+
+var rsaFDHVRFSuiteList = []rsaFDHVRFSuite{
+	{name: SuiteRSAFDHVRFSHA256, suiteString: 0x01, newHash: sha256.New},
+	{name: SuiteRSAFDHVRFSHA384, suiteString: 0x02, newHash: sha512.New384},
+	{name: SuiteRSAFDHVRFSHA512, suiteString: 0x03, newHash: sha512.New},
+}
+
+//RSAFDHVRFSuites is an exportable function
+// RSAFDHVRFSuites lists the names of the supported RSA-FDH-VRF suites
+func RSAFDHVRFSuites() []string {
+
+	var names []string
+
+	for _, suite := range rsaFDHVRFSuiteList {
+		names = append(names, suite.name)
+	}
+
+	return names
+}
+
+//IsRSAFDHVRFSuite is an exportable function
+// IsRSAFDHVRFSuite reports whether name is an RSA-FDH-VRF suite; names are not case sensitive
+func IsRSAFDHVRFSuite(name string) bool {
+
+	_, err := rsaFDHVRFSuiteByName(name)
+	return err == nil
+}
+
+// rsaFDHVRFSuiteByName finds a suite; names are not case sensitive
+func rsaFDHVRFSuiteByName(name string) (rsaFDHVRFSuite, error) {
+
+	for _, suite := range rsaFDHVRFSuiteList {
+		if strings.EqualFold(name, suite.name) {
+			return suite, nil
+		}
+	}
+
+	return rsaFDHVRFSuite{}, fmt.Errorf("Error: Unknown RSA-FDH-VRF suite %q; use [%s]", name, strings.Join(RSAFDHVRFSuites(), ", "))
+}
+
+/*
+*  encode is the EM of RSAFDHVRF_prove and RSAFDHVRF_verify (RFC 9381 sections 4.1 and 4.3):
+*  MGF1(suite_string || 0x01 || MGF_salt || alpha, k - 1) where k is the BYTE length of N and
+*  MGF_salt = I2OSP(k, 4) || I2OSP(N, k). EM is one BYTE shorter than N so OS2IP(EM) < N.
+ */
+func (suite rsaFDHVRFSuite) encode(pubKey *rsa.PublicKey, alpha []byte) []byte {
+
+	k := pubKey.Size()
+	seed := []byte{suite.suiteString, 0x01, byte(k >> 24), byte(k >> 16), byte(k >> 8), byte(k)}
+	seed = append(seed, pubKey.N.FillBytes(make([]byte, k))...)
+	seed = append(seed, alpha...)
+
+	em := make([]byte, k-1)
+	mgf1XOR(em, suite.newHash(), seed)
+
+	return em
+}
+
+// proofToHash is RSAFDHVRF_proof_to_hash (RFC 9381 section 4.2): Hash(suite_string || 0x02 || pi)
+func (suite rsaFDHVRFSuite) proofToHash(pi []byte) []byte {
+
+	h := suite.newHash()
+	h.Write([]byte{suite.suiteString, 0x02})
+	h.Write(pi)
+
+	return h.Sum(nil)
+}
+
+// checkRSAVRFKey rejects keys that can not give proofs: no modulus, or one too small for MGF1
+func checkRSAVRFKey(pubKey *rsa.PublicKey) error {
+
+	if pubKey == nil || pubKey.N == nil || pubKey.E < 2 {
+		return errors.New("Error: No RSA public key supplied")
+	}
+	if pubKey.Size() < 2 {
+		return errors.New("Error: The RSA modulus is too small for RSA-FDH-VRF")
+	}
+
+	return nil
+}
+
+//Generate is an exportable method
+/*
+*  Generate computes the RFC 9381 proof pi = RSASP1(K, OS2IP(EM)) of alpha and its output beta
+*  for the suite vrf.Suite with an RSA private key. pi is as long as N (256 BYTES for RSA-2048)
+*  and beta is as long as the hash of the suite. Proofs are deterministic.
+ */
+func (vrf RSAFDHVRF) Generate(alpha []byte, privKey crypto.PrivateKey, verbose bool) (proof []byte, beta []byte, err error) {
+
+	suite, err := rsaFDHVRFSuiteByName(vrf.Suite)
+	if err != nil {
+		return nil, nil, err
+	}
+	rsaPrivKey, ok := privKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("Error: %s requires an RSA private key", suite.name)
+	}
+	if err = checkRSAVRFKey(&rsaPrivKey.PublicKey); err != nil {
+		return nil, nil, err
+	}
+	if rsaPrivKey.D == nil || rsaPrivKey.D.Sign() == 0 {
+		return nil, nil, errors.New("Error: No private key supplied")
+	}
+
+	// pi = I2OSP(m^d mod N, k); D is an RSA Secret!
+	em := suite.encode(&rsaPrivKey.PublicKey, alpha)
+	m := new(big.Int).SetBytes(em)
+	s, err := rsaSP1(rsaPrivKey, m)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof = s.FillBytes(make([]byte, rsaPrivKey.Size()))
+	beta = suite.proofToHash(proof)
+
+	if verbose {
+		fmt.Fprintf(helpers.Messages, "\n\n****RSAFDHVRF.Generate****\n\n")
+		fmt.Fprintf(helpers.Messages, "Suite: %s\n\n", suite.name)
+		fmt.Fprintf(helpers.Messages, "EM = MGF1(...) (hex): %x\n\n", em)
+		fmt.Fprintf(helpers.Messages, "Proof (hex): %x\n\n", proof)
+		fmt.Fprintf(helpers.Messages, "Beta (hex): %x\n\n", beta)
+	}
+
+	return proof, beta, nil
+}
+
+/*
+*  rsaSP1 computes m^d mod N for a message the caller may choose. math/big is not constant time,
+*  so m is blinded with a random r (m * r^e) and unblinded with r^-1 afterwards; the exponent is
+*  applied with the CRT values of the key when it has two primes. s^e == m is checked so that a
+*  fault can not leak a factor of N.
+ */
+func rsaSP1(privKey *rsa.PrivateKey, m *big.Int) (*big.Int, error) {
+
+	var (
+		r    *big.Int
+		rInv *big.Int
+		err  error
+	)
+
+	n := privKey.N
+	e := big.NewInt(int64(privKey.E))
+	for rInv == nil {
+		r, err = rand.Int(rand.Reader, n)
+		if err != nil {
+			return nil, err
+		}
+		if r.Sign() > 0 {
+			rInv = new(big.Int).ModInverse(r, n)
+		}
+	}
+	c := new(big.Int).Exp(r, e, n)
+	c.Mul(c, m).Mod(c, n)
+
+	var s *big.Int
+	if len(privKey.Primes) == 2 {
+		if privKey.Precomputed.Dp == nil {
+			privKey.Precompute()
+		}
+		p, q := privKey.Primes[0], privKey.Primes[1]
+		m1 := new(big.Int).Exp(c, privKey.Precomputed.Dp, p)
+		m2 := new(big.Int).Exp(c, privKey.Precomputed.Dq, q)
+		h := m1.Sub(m1, m2)
+		h.Mul(h, privKey.Precomputed.Qinv).Mod(h, p)
+		s = h.Mul(h, q).Add(h, m2)
+	} else {
+		s = new(big.Int).Exp(c, privKey.D, n)
+	}
+	s.Mul(s, rInv).Mod(s, n)
+
+	if new(big.Int).Exp(s, e, n).Cmp(m) != 0 {
+		return nil, errors.New("Error: The RSA signature failed its check; the private key may be corrupt")
+	}
+
+	return s, nil
+}
+
+//ProofToHash is an exportable method
+/*
+*  ProofToHash is RSAFDHVRF_proof_to_hash: it returns beta for a proof without verifying it.
+*  Use Verify unless the proof is already known to be valid.
+ */
+func (vrf RSAFDHVRF) ProofToHash(proof []byte) ([]byte, error) {
+
+	suite, err := rsaFDHVRFSuiteByName(vrf.Suite)
+	if err != nil {
+		return nil, err
+	}
+
+	return suite.proofToHash(proof), nil
+}
+
+//Verify is an exportable method
+/*
+*  Verify checks the RFC 9381 proof of alpha with the prover's RSA public key (RSAVP1 and a
+*  comparison with EM) and that beta is the output of the proof. An invalid proof or a beta that
+*  does not match returns false.
+ */
+func (vrf RSAFDHVRF) Verify(alpha []byte, beta []byte, proof []byte, pubKey crypto.PublicKey, verbose bool) (validity bool, err error) {
+
+	suite, err := rsaFDHVRFSuiteByName(vrf.Suite)
+	if err != nil {
+		return false, err
+	}
+	var rsaPubKey *rsa.PublicKey
+	switch key := pubKey.(type) {
+	case *rsa.PublicKey:
+		rsaPubKey = key
+	case *rsa.PrivateKey:
+		rsaPubKey = &key.PublicKey
+	default:
+		return false, fmt.Errorf("Error: %s requires an RSA public key", suite.name)
+	}
+	if err = checkRSAVRFKey(rsaPubKey); err != nil {
+		return false, err
+	}
+	if len(proof) != rsaPubKey.Size() {
+		return false, fmt.Errorf("Error: An %s proof for this key is %d BYTES, not %d", suite.name, rsaPubKey.Size(), len(proof))
+	}
+
+	// RSAVP1: s must be below N; m = s^e mod N must fit in k - 1 BYTES and equal EM
+	s := new(big.Int).SetBytes(proof)
+	if s.Cmp(rsaPubKey.N) >= 0 {
+		return false, nil
+	}
+	m := new(big.Int).Exp(s, big.NewInt(int64(rsaPubKey.E)), rsaPubKey.N)
+	em := suite.encode(rsaPubKey, alpha)
+	if m.BitLen() > 8*len(em) {
+		return false, nil
+	}
+	emPrime := m.FillBytes(make([]byte, len(em)))
+
+	betaPrime := suite.proofToHash(proof)
+	if verbose {
+		fmt.Fprintf(helpers.Messages, "EM - Calculated   (hex): %x\n", em)
+		fmt.Fprintf(helpers.Messages, "EM - From proof   (hex): %x\n", emPrime)
+		fmt.Fprintf(helpers.Messages, "Beta - Calculated (hex): %x\n", betaPrime)
+		fmt.Fprintf(helpers.Messages, "Beta - Provided   (hex): %x\n\n", beta)
+	}
+	if !hmac.Equal(em, emPrime) {
+		return false, nil
+	}
+
+	return hmac.Equal(beta, betaPrime), nil
+}
@@ -0,0 +1,123 @@
+package cryptospecials
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+/*
+*  RFC 9381 gives no RSA-FDH-VRF test vectors. These were made for testdata/rsa2048.pem with a
+*  separate (Python) implementation of RFC 9381 section 4 that uses the key from 'openssl rsa
+*  -text'; foil must give the same pi and beta.
+ */
+func TestRSAFDHVRFVectors(t *testing.T) {
+
+	privPath, pubPath := "testdata/rsa2048.pem", "testdata/rsa2048.pem.pub"
+	privKey, err := RSAPrivKeyLoad(&privPath, false)
+	if err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+	pubKey, err := RSAPubKeyLoad(&pubPath, false)
+	if err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+
+	for _, vector := range []struct{ suite, pi, beta string }{
+		{SuiteRSAFDHVRFSHA256,
+			"6dd10325a4d410a436d46d7b954fa490c9d6c82b347f5df70b8f4fba64a873135d81373728da01d3a1ca8da91f475810bbcd779d79bb44984b11f6ff921b921fc2faa52d8e7a3b7e7ffa63b08a496e07bf9375786f4d80b7b13d5e7d89dc3b48d9523db0c0b97d88e3e8dba470f6f83ee5c239525a83ec621503b7273b49702bd087dfc4fc3475365f29144aa0bc2e2607bdf86669e143d4e8aa79d160e5093b828b27bfa1f59c661ce6d187929dd52d6191ca03cf3e899afb9a17180aabcbba2c5b891fdf6ef22f830f2d199914da64172d34449ffda97fe036c073cc532a202fd5dd33886bb5dd4ca231d5f6eb2e5704058fdc9f17ed684edcaaf8aba3d470",
+			"72f8728919db9c209aa90c6f75d36593fe9e73c9c49590b7f2de83dafcc40d5c"},
+		{SuiteRSAFDHVRFSHA384,
+			"619c52ffa6debe5426999de8fa3255e45bd0be7ed74604be6a68ca37d2357757295488463ad85566b98e3272c720d0e175c71bddd4897698aa6a377124b290c280bd2390704e3ee808dc0b8113272e143971e1f0620e0b5c9f7d05715ceddd39d46b0b94de3e370997f7b3eadf8e8ca41c1869ad7ec8acc4d5b681a11a4851898a67d14ccb0c16469f524b81015dd68c4220e1984e76b1fdbfd35873dedff36e3390775235b7ae0728fcee19dc21e7a6f574ad53daf91b1b52a6557e2f1314fc15e38f776c001035e08d227c9f776570c4ddbd40175872d2f01892dddebe22731762bd11b76ffbe6a47198ac3b86cca05dbd2616b6e1c58dd546b1bcde83b5f6",
+			"2e679eee591ccb02b2c911d43fd8a265dd55145eed235129d2ee0d81fc47051fa8fee2c5f4fce85b267fde5dec50592a"},
+		{SuiteRSAFDHVRFSHA512,
+			"24c7c929eefa5fb2b1b5544442a4721cd8cc69470d7bdc7f9492ef08286854414c97d841e20a8292ef9ed0f6ef29def0d5ea02c7f9d714fbbd274f7f13ad09fbcc0ca5d9f3a1cdf2c44aa0363cf4bedc0ead49aff1a9368ef2da85d32da46f9b769d7ccd8a4730693d595dde7b1a90163093e340a5549936c9dea5d4231d2561dbc1a1cecbcdce77b8e0f91cef1eaa212f353d4977a3df5611eaed65ccef170d3aaa5686dfeaeca60035a39e17a226aa3cc94e18c0723052d39f9fac65a4a96148c945e48abac62a2ed01b3416704203f86df2bbeacf62be47a4ec23f1690a10cc7e51677545b04332dc560f2a1f43f9a8a5a44a3c5a4d910817e3af59ddcd41",
+			"ebde1e11a8dd2c1cb169102b08b552e03416f3b4db9feee460c425f2ecfa46010c66ae7aae97be1ae651905bb7c21eed6f609c417698dd25b05c276cb09b6a9a"},
+	} {
+		vrf := RSAFDHVRF{Suite: vector.suite}
+		pi, beta, err := vrf.Generate([]byte("sample"), privKey, false)
+		if err != nil {
+			t.Fatalf("FAIL - %s Generate - %v", vector.suite, err)
+		}
+		if hex.EncodeToString(pi) != vector.pi {
+			t.Errorf("FAIL - %s pi is %x", vector.suite, pi)
+		}
+		if hex.EncodeToString(beta) != vector.beta {
+			t.Errorf("FAIL - %s beta is %x", vector.suite, beta)
+		}
+
+		valid, err := vrf.Verify([]byte("sample"), beta, pi, pubKey, false)
+		if err != nil || !valid {
+			t.Errorf("FAIL - The %s proof did not verify - %v", vector.suite, err)
+		}
+		if valid, _ = vrf.Verify([]byte("Sample"), beta, pi, pubKey, false); valid {
+			t.Errorf("FAIL - The %s proof verified for another alpha", vector.suite)
+		}
+		for _, i := range []int{0, 100, 255} {
+			bad := append([]byte(nil), pi...)
+			bad[i] ^= 0x01
+			if valid, _ = vrf.Verify([]byte("sample"), beta, bad, pubKey, false); valid {
+				t.Errorf("FAIL - A %s proof changed at BYTE %d verified", vector.suite, i)
+			}
+		}
+		if valid, _ = vrf.Verify([]byte("sample"), beta[1:], pi, pubKey, false); valid {
+			t.Errorf("FAIL - A truncated %s beta verified", vector.suite)
+		}
+		if _, err = vrf.Verify([]byte("sample"), beta, pi[1:], pubKey, false); err == nil {
+			t.Errorf("FAIL - A truncated %s proof was accepted", vector.suite)
+		}
+	}
+
+	// A proof of one suite is not a proof of another; other keys and unknown suites are rejected
+	pi, beta, _ := RSAFDHVRF{Suite: SuiteRSAFDHVRFSHA256}.Generate([]byte("sample"), privKey, false)
+	if valid, _ := (RSAFDHVRF{Suite: SuiteRSAFDHVRFSHA512}).Verify([]byte("sample"), beta, pi, pubKey, false); valid {
+		t.Errorf("FAIL - A SHA-256 proof verified as a SHA-512 proof")
+	}
+	_, edKey, _ := ed25519.GenerateKey(nil)
+	if _, _, err = (RSAFDHVRF{Suite: SuiteRSAFDHVRFSHA256}).Generate([]byte("sample"), edKey, false); err == nil {
+		t.Errorf("FAIL - Generate accepted an Ed25519 key")
+	}
+	if _, _, err = (RSAFDHVRF{Suite: "RSA-FDH-VRF-SHA1"}).Generate([]byte("sample"), privKey, false); err == nil {
+		t.Errorf("FAIL - Generate accepted an unknown suite")
+	}
+	if !IsRSAFDHVRFSuite("rsa-fdh-vrf-sha384") || IsRSAFDHVRFSuite(SuiteECVRFP256SHA256TAI) {
+		t.Errorf("FAIL - IsRSAFDHVRFSuite")
+	}
+}
+
+/*
+*  The proof is computed with a blinded CRT exponentiation; the random blinding must not change
+*  pi, a key without CRT values must give the same pi, and a faulty CRT value must be caught.
+ */
+func TestRSAFDHVRFBlinding(t *testing.T) {
+
+	privPath := "testdata/rsa2048.pem"
+	privKey, err := RSAPrivKeyLoad(&privPath, false)
+	if err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+	vrf := RSAFDHVRF{Suite: SuiteRSAFDHVRFSHA256}
+	want := "6dd10325a4d410a436d46d7b954fa490c9d6c82b347f5df70b8f4fba64a873135d81373728da01d3a1ca8da91f475810"
+
+	for i := 0; i < 4; i++ {
+		pi, _, err := vrf.Generate([]byte("sample"), privKey, false)
+		if err != nil || hex.EncodeToString(pi[:48]) != want {
+			t.Errorf("FAIL - Blinded proof %d is %x - %v", i, pi[:48], err)
+		}
+	}
+
+	noCRT := *privKey
+	noCRT.Primes = nil
+	noCRT.Precomputed = rsa.PrecomputedValues{}
+	if pi, _, err := vrf.Generate([]byte("sample"), &noCRT, false); err != nil || hex.EncodeToString(pi[:48]) != want {
+		t.Errorf("FAIL - The proof without CRT values is %x - %v", pi, err)
+	}
+
+	faulty := *privKey
+	faulty.Precomputed.Dp = new(big.Int).Add(privKey.Precomputed.Dp, big.NewInt(2))
+	if _, _, err = vrf.Generate([]byte("sample"), &faulty, false); err == nil {
+		t.Errorf("FAIL - A faulty CRT exponent gave a proof")
+	}
+}
@@ -21,7 +21,9 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"os"
+
+	"foil/helpers"
 )
 
 // RSAKeyGen is an exportable function
@@ -67,13 +69,13 @@ func RSAKeySave(privKey *rsa.PrivateKey, savePubKey bool, printStdIn bool, dest
 		}
 		pemBytes = pem.EncodeToMemory(
 			&pem.Block{
-				Type:  "RSA PUBLIC KEY",
+				Type:  "PUBLIC KEY",
 				Bytes: derBytes,
 			},
 		)
 		if verbose {
-			fmt.Printf("Der Bytes (hex): %x\n", derBytes)
-			fmt.Println("Pem Bytes:", pemBytes)
+			fmt.Fprintf(helpers.Messages, "Der Bytes (hex): %x\n", derBytes)
+			fmt.Fprintln(helpers.Messages, "Pem Bytes:", pemBytes)
 		}
 	} else {
 		derBytes = x509.MarshalPKCS1PrivateKey(privKey)
@@ -84,22 +86,26 @@ func RSAKeySave(privKey *rsa.PrivateKey, savePubKey bool, printStdIn bool, dest
 			},
 		)
 		if verbose {
-			fmt.Printf("\n\n******RSAKeySave******\n\n")
-			fmt.Printf("SECRET - Der Bytes:\n\n%x\n\n", derBytes)
-			fmt.Printf("SECRET - Pem Bytes:\n\n%x\n\n", pemBytes)
+			fmt.Fprintf(helpers.Messages, "\n\n******RSAKeySave******\n\n")
+			fmt.Fprintf(helpers.Messages, "SECRET - Der Bytes:\n\n%x\n\n", derBytes)
+			fmt.Fprintf(helpers.Messages, "SECRET - Pem Bytes:\n\n%x\n\n", pemBytes)
 		}
 	}
 
 	// This logic allows the RSA key to be printed to stdin & saved to file
 	if *dest == "" && printStdIn == false {
-		fmt.Println("No destination provided. Saving file as: ./IAMArsaKey.pem")
+		fmt.Fprintln(helpers.Messages, "No destination provided. Saving file as: ./IAMArsaKey.pem")
 		*dest = "IAMArsaKey.pem"
 	} else if *dest == "" && printStdIn == true {
-		fmt.Printf("%s\n", pemBytes)
+		fmt.Fprintf(helpers.Messages, "%s\n", pemBytes)
 	}
-	// Write the PEM to file
+	// Write the PEM to file; only the owner may read a private key
 	if len(*dest) > 0 {
-		err = ioutil.WriteFile(*dest, pemBytes, 0644)
+		perm := os.FileMode(0600)
+		if savePubKey {
+			perm = 0644
+		}
+		err = writeKeyFile(*dest, pemBytes, perm)
 		if err != nil {
 			return fmt.Errorf("Error: %v", err)
 		}
@@ -109,67 +115,42 @@ func RSAKeySave(privKey *rsa.PrivateKey, savePubKey bool, printStdIn bool, dest
 }
 
 // RSAPrivKeyLoad is an exportable function
+// RSAPrivKeyLoad loads an RSA private key in any format read by LoadKey
 func RSAPrivKeyLoad(source *string, verbose bool) (privKey *rsa.PrivateKey, err error) {
 
-	var (
-		rawPem []byte
-	)
-
-	// Read raw PEM file
-	rawPem, err = ioutil.ReadFile(*source)
+	key, err := LoadKey(*source)
 	if err != nil {
-		return nil, fmt.Errorf("Error: %v", err)
-	}
-	// Decode raw PEM into private key
-	pemData, _ := pem.Decode(rawPem)
-	if pemData == nil {
-		return nil, errors.New("Error: Unable to parse PEM file - it may be empty or not in PEM format")
+		return nil, err
 	}
-	privKey, err = x509.ParsePKCS1PrivateKey(pemData.Bytes)
+	privKey, err = key.RSAPrivate()
 	if err != nil {
-		return nil, fmt.Errorf("Error: %v", err)
+		return nil, err
 	}
 	if verbose {
-		fmt.Printf("\n\n******RSAPrivKeyLoad******\n\n")
-		fmt.Printf("SECRET - rawPem: \n\n%s\n\n", rawPem)
-		fmt.Printf("SECRET - pemData: \n\n%x\n\n", pemData)
-		fmt.Printf("SECRET - privKey: \n\n%v\n\n", privKey)
+		fmt.Fprintf(helpers.Messages, "\n\n******RSAPrivKeyLoad******\n\n")
+		fmt.Fprintf(helpers.Messages, "Key format: %s\n\n", key.Format)
+		fmt.Fprintf(helpers.Messages, "SECRET - privKey: \n\n%v\n\n", privKey)
 	}
 	return privKey, nil
 }
 
 // RSAPubKeyLoad is an exportable function
+// RSAPubKeyLoad loads an RSA public key (or the public half of a private key) in any format read by LoadKey
 func RSAPubKeyLoad(source *string, verbose bool) (*rsa.PublicKey, error) {
-	var (
-		rawPem []byte
-		err    error
-	)
 
-	// Read raw PEM file
-	rawPem, err = ioutil.ReadFile(*source)
+	key, err := LoadKey(*source)
 	if err != nil {
-		return nil, fmt.Errorf("Error: %v", err)
-	}
-	// Decode raw PEM into private key
-	pemData, _ := pem.Decode(rawPem)
-	if pemData == nil {
-		return nil, errors.New("Error: Unable to parse PEM file - it may be empty or not in PEM format")
+		return nil, err
 	}
-	pubKey, err := x509.ParsePKIXPublicKey(pemData.Bytes)
+	pubKey, err := key.RSAPublic()
 	if err != nil {
-		return nil, fmt.Errorf("Error: %v", err)
+		return nil, err
 	}
 	if verbose {
-		fmt.Printf("\n\n******RSAPrivKeyLoad Verbose Output******\n\n")
-		fmt.Printf("Input PEM: \n\n%s\n", rawPem)
-		fmt.Printf("Raw PEM Data: \n\n%x\n\n", pemData)
-		fmt.Printf("pubKey: \n\n%s\n\n", pubKey)
-	}
-	// Extract the pub key and ensure it is of type RSA
-	switch pubKey := pubKey.(type) {
-	case *rsa.PublicKey:
-		return pubKey, nil
-	default:
-		return nil, errors.New("Key type is not RSA")
+		fmt.Fprintf(helpers.Messages, "\n\n******RSAPubKeyLoad Verbose Output******\n\n")
+		fmt.Fprintf(helpers.Messages, "Key format: %s\n\n", key.Format)
+		fmt.Fprintf(helpers.Messages, "pubKey: \n\n%v\n\n", pubKey)
 	}
+
+	return pubKey, nil
 }
@@ -2,6 +2,8 @@ package cryptospecials
 
 import (
 	"crypto/rsa"
+	"io/ioutil"
+	"os"
 	"testing"
 )
 
@@ -103,6 +105,16 @@ func TestRSAKeySave(t *testing.T) {
 		t.Errorf("FAIL - %v\n", err)
 	}
 
+	// A private key saved over an existing world readable file is only readable by the owner
+	dest = t.TempDir() + "/existing.pem"
+	ioutil.WriteFile(dest, []byte("public"), 0644)
+	err = RSAKeySave(privKey, false, false, &dest, false)
+	if info, _ := os.Stat(dest); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("FAIL - The private key file is not 0600 - %v", err)
+	}
+	if loaded, err := RSAPrivKeyLoad(&dest, false); err != nil || !loaded.Equal(privKey) {
+		t.Errorf("FAIL - The saved private key did not load - %v", err)
+	}
 }
 
 func TestRSAKeyLoad(t *testing.T) {
@@ -17,9 +17,12 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+
+	"foil/helpers"
 )
 
 //RSAVRF is an exportable struct
+// RSAVRF is the VRF of the NSEC5 paper; RSAFDHVRF is the RFC 9381 RSA-FDH-VRF
 type RSAVRF struct {
 	Proof []byte
 	Alpha []byte
@@ -72,23 +75,23 @@ func (vrf RSAVRF) Generate(alpha []byte, rsaPrivKey *rsa.PrivateKey, verbose boo
 	beta = hash256.Sum(nil) //Outputs in big-endian
 
 	if verbose {
-		fmt.Printf("\n\n****RSAVRF.generate****\n\n")
+		fmt.Fprintf(helpers.Messages, "\n\n****RSAVRF.generate****\n\n")
 
-		fmt.Printf("RSA Pub Mod - N (big Int):\n\n%v\n\n", rsaPrivKey.N)
-		fmt.Printf("RSA Pub Exp - E (int):\n\n%v\n\n", rsaPrivKey.E)
-		fmt.Printf("SECRET - RSA Priv Exp - D (big Int):\n\n%v\n\n", rsaPrivKey.D)
+		fmt.Fprintf(helpers.Messages, "RSA Pub Mod - N (big Int):\n\n%v\n\n", rsaPrivKey.N)
+		fmt.Fprintf(helpers.Messages, "RSA Pub Exp - E (int):\n\n%v\n\n", rsaPrivKey.E)
+		fmt.Fprintf(helpers.Messages, "SECRET - RSA Priv Exp - D (big Int):\n\n%v\n\n", rsaPrivKey.D)
 
-		fmt.Printf("Alpha (string): \n\n%s\n\n", alpha)
-		fmt.Printf("Alpha (hex): \n\n%x\n\n", alpha)
+		fmt.Fprintf(helpers.Messages, "Alpha (string): \n\n%s\n\n", alpha)
+		fmt.Fprintf(helpers.Messages, "Alpha (hex): \n\n%x\n\n", alpha)
 
-		fmt.Printf("Proof (big Int):\n\n%v\n\n", proof)
-		fmt.Printf("Proof (hex): \n\n%x\n\n", proof.Bytes())
-		fmt.Printf("H(proof) (hex): \n\n%x\n\n", hash256.Sum(nil))
-		fmt.Printf("Beta = H(proof) (hex): \n\n%x\n\n", beta)
+		fmt.Fprintf(helpers.Messages, "Proof (big Int):\n\n%v\n\n", proof)
+		fmt.Fprintf(helpers.Messages, "Proof (hex): \n\n%x\n\n", proof.Bytes())
+		fmt.Fprintf(helpers.Messages, "H(proof) (hex): \n\n%x\n\n", hash256.Sum(nil))
+		fmt.Fprintf(helpers.Messages, "Beta = H(proof) (hex): \n\n%x\n\n", beta)
 
-		fmt.Printf("Length of MGF1 output (Should be < len(N)): \n\n%d\n\n", len(output))
-		fmt.Printf("MGF1 Output (hex): \n\n%x\n\n", output)
-		fmt.Printf("MGF1 Output as big.Int: \n\n%v\n\n", outInt)
+		fmt.Fprintf(helpers.Messages, "Length of MGF1 output (Should be < len(N)): \n\n%d\n\n", len(output))
+		fmt.Fprintf(helpers.Messages, "MGF1 Output (hex): \n\n%x\n\n", output)
+		fmt.Fprintf(helpers.Messages, "MGF1 Output as big.Int: \n\n%v\n\n", outInt)
 	}
 
 	return proof.Bytes(), beta, nil
@@ -146,24 +149,24 @@ func (vrf RSAVRF) Verify(alpha []byte, beta []byte, proof []byte, pubKey *rsa.Pu
 	mgf1Check.Exp(&intCheck, e, pubKey.N)
 
 	if verbose {
-		fmt.Printf("****VRF.verify - Verbose Output****\n")
+		fmt.Fprintf(helpers.Messages, "****VRF.verify - Verbose Output****\n")
 
-		fmt.Println("RSA Pub Mod - N (big Int):", pubKey.N)
-		fmt.Println("RSA Pub Exp - E (int)", e)
+		fmt.Fprintln(helpers.Messages, "RSA Pub Mod - N (big Int):", pubKey.N)
+		fmt.Fprintln(helpers.Messages, "RSA Pub Exp - E (int)", e)
 
-		fmt.Println("vrf.proof (big Int)", intCheck)
-		fmt.Printf("H(vrf.proof) - should be equal to Beta (hex): %x\n", betaCheck)
-		fmt.Printf("Beta (hex): %x\n", beta)
+		fmt.Fprintln(helpers.Messages, "vrf.proof (big Int)", intCheck)
+		fmt.Fprintf(helpers.Messages, "H(vrf.proof) - should be equal to Beta (hex): %x\n", betaCheck)
+		fmt.Fprintf(helpers.Messages, "Beta (hex): %x\n", beta)
 
-		fmt.Printf("MGF1(Alpha) (hex): %x\n", mgf1Alpha)
-		fmt.Println("MGF1Check (Big Int):", mgf1Check)
-		fmt.Printf("MGF1Check (hex): %x\n", mgf1Check.Bytes())
+		fmt.Fprintf(helpers.Messages, "MGF1(Alpha) (hex): %x\n", mgf1Alpha)
+		fmt.Fprintln(helpers.Messages, "MGF1Check (Big Int):", mgf1Check)
+		fmt.Fprintf(helpers.Messages, "MGF1Check (hex): %x\n", mgf1Check.Bytes())
 	}
 
 	// Check: compare the bytes of betaCheck = H(vrf.proof) ?= beta
 	if bytes.Compare(betaCheck, beta) != 0 {
 		if verbose {
-			fmt.Println("FAIL - Could not verify: Beta == H(proof)")
+			fmt.Fprintln(helpers.Messages, "FAIL - Could not verify: Beta == H(proof)")
 		}
 		return false, nil
 	}
@@ -171,7 +174,7 @@ func (vrf RSAVRF) Verify(alpha []byte, beta []byte, proof []byte, pubKey *rsa.Pu
 	//Check: compare the bytes of (mgf1Check == trial_MGF1(alpha)) ?= (mgf1Alpha = MGF1(alpha))
 	if bytes.Compare(mgf1Check.Bytes(), mgf1Alpha) != 0 {
 		if verbose {
-			fmt.Println("FAIL - Could not verify: trial_MGF1(alpha) == MGF1(alpha)")
+			fmt.Fprintln(helpers.Messages, "FAIL - Could not verify: trial_MGF1(alpha) == MGF1(alpha)")
 		}
 		return false, nil
 	}
@@ -0,0 +1,221 @@
+/*
+*	This package contains mechanisms that will allow for VRF and OPRF calculations.
+*
+*	OPRF: https://eprint.iacr.org/2017/111
+*
+*	RSA-VRF: https://eprint.iacr.org/2017/099.pdf
+*
+*		-Brian
+ */
+
+package cryptospecials
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Suite names of the VRFs of the NSEC5 paper (RSAVRF and ECCVRF) in a VRFEnvelope
+const (
+	SuiteFoilRSAVRF = "FOIL-RSAVRF"
+	SuiteFoilECCVRF = "FOIL-ECCVRF"
+)
+
+// Formats of a VRFEnvelope
+const (
+	VRFFormatJSON = "json"
+	VRFFormatCBOR = "cbor"
+)
+
+//VRFEnvelope is an exportable struct
+/*
+*  VRFEnvelope holds a VRF proof with what a verifier needs to check it: the suite (an RFC 9381
+*  suite, SuiteFoilRSAVRF, or SuiteFoilECCVRF), the SPKI SHA-256 fingerprint (hex) of the
+*  prover's key, alpha, beta, and the proof. An ECCVRF proof is the binary encoding of Proof
+*  (see Proof.MarshalBinary); every other proof is the proof as Generate returns it. In JSON the
+*  BYTE strings are base64.
+ */
+type VRFEnvelope struct {
+	Suite          string `json:"suite" cbor:"suite"`
+	KeyFingerprint string `json:"key_fingerprint" cbor:"key_fingerprint"`
+	Alpha          []byte `json:"alpha" cbor:"alpha"`
+	Beta           []byte `json:"beta" cbor:"beta"`
+	Proof          []byte `json:"proof" cbor:"proof"`
+}
+
+//MarshalBinary is an exportable method
+/*
+*  MarshalBinary encodes a Proof as X, Y, C, and S in that order, each as a 2-BYTE big-endian
+*  length followed by the big-endian value without leading zeros (zero is empty). There is one
+*  encoding for each Proof.
+ */
+func (eccProof Proof) MarshalBinary() ([]byte, error) {
+
+	var encoded []byte
+
+	for i, value := range []*big.Int{eccProof.X, eccProof.Y, eccProof.C, eccProof.S} {
+		if value == nil || value.Sign() < 0 {
+			return nil, fmt.Errorf("Error: Proof value %d is missing or negative", i+1)
+		}
+		valueBytes := value.Bytes()
+		if len(valueBytes) > 0xffff {
+			return nil, fmt.Errorf("Error: Proof value %d is too long", i+1)
+		}
+		encoded = append(encoded, byte(len(valueBytes)>>8), byte(len(valueBytes)))
+		encoded = append(encoded, valueBytes...)
+	}
+
+	return encoded, nil
+}
+
+//UnmarshalBinary is an exportable method
+// UnmarshalBinary decodes the encoding of MarshalBinary; any other encoding is an error
+func (eccProof *Proof) UnmarshalBinary(data []byte) error {
+
+	var values [4]*big.Int
+
+	for i := range values {
+		if len(data) < 2 {
+			return errors.New("Error: The proof is truncated")
+		}
+		valueLen := int(data[0])<<8 | int(data[1])
+		data = data[2:]
+		if len(data) < valueLen {
+			return errors.New("Error: The proof is truncated")
+		}
+		if valueLen > 0 && data[0] == 0 {
+			return fmt.Errorf("Error: Proof value %d has a leading zero", i+1)
+		}
+		values[i] = new(big.Int).SetBytes(data[:valueLen])
+		data = data[valueLen:]
+	}
+	if len(data) > 0 {
+		return fmt.Errorf("Error: %d BYTES follow the proof", len(data))
+	}
+	eccProof.X, eccProof.Y, eccProof.C, eccProof.S = values[0], values[1], values[2], values[3]
+
+	return nil
+}
+
+// check rejects envelopes that can not be verified
+func (env VRFEnvelope) check() error {
+
+	if len(env.Suite) == 0 {
+		return errors.New("Error: The VRF envelope has no suite")
+	}
+	if len(env.Beta) == 0 || len(env.Proof) == 0 {
+		return errors.New("Error: The VRF envelope has no beta or proof")
+	}
+
+	return nil
+}
+
+//EncodeVRFEnvelope is an exportable function
+/*
+*  EncodeVRFEnvelope encodes env as JSON (VRFFormatJSON) or CBOR (VRFFormatCBOR). CBOR is the
+*  deterministic (core) encoding of RFC 8949 section 4.2.1, a map with text keys named as in JSON.
+ */
+func EncodeVRFEnvelope(env VRFEnvelope, format string) ([]byte, error) {
+
+	if err := env.check(); err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(format) {
+	case VRFFormatJSON:
+		encoded, err := json.MarshalIndent(env, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(encoded, '\n'), nil
+	case VRFFormatCBOR:
+		encMode, err := cbor.CoreDetEncOptions().EncMode()
+		if err != nil {
+			return nil, err
+		}
+		return encMode.Marshal(env)
+	}
+
+	return nil, fmt.Errorf("Error: Unknown VRF envelope format %q; use %s or %s", format, VRFFormatJSON, VRFFormatCBOR)
+}
+
+/*
+*  checkJSONValue reads the next JSON value from decoder and rejects objects that have a member
+*  twice. encoding/json keeps the last of duplicate members and matches names to fields without
+*  regard to case, so names are compared the same way.
+ */
+func checkJSONValue(decoder *json.Decoder) error {
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for decoder.More() {
+		if delim == '{' {
+			name, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			folded := strings.ToLower(name.(string))
+			if seen[folded] {
+				return fmt.Errorf("Error: The JSON VRF envelope has the member %q more than once", name)
+			}
+			seen[folded] = true
+		}
+		if err = checkJSONValue(decoder); err != nil {
+			return err
+		}
+	}
+	_, err = decoder.Token()
+
+	return err
+}
+
+//DecodeVRFEnvelope is an exportable function
+/*
+*  DecodeVRFEnvelope reads a JSON or CBOR envelope; JSON is a document that starts with '{'.
+*  Unknown or duplicate members are errors.
+ */
+func DecodeVRFEnvelope(data []byte) (VRFEnvelope, error) {
+
+	var env VRFEnvelope
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&env); err != nil {
+			return VRFEnvelope{}, fmt.Errorf("Error: The VRF envelope is not valid JSON: %v", err)
+		}
+		if decoder.More() {
+			return VRFEnvelope{}, errors.New("Error: Data follows the JSON VRF envelope")
+		}
+		if err := checkJSONValue(json.NewDecoder(bytes.NewReader(trimmed))); err != nil {
+			return VRFEnvelope{}, err
+		}
+	} else {
+		decMode, err := cbor.DecOptions{
+			DupMapKey:         cbor.DupMapKeyEnforcedAPF,
+			ExtraReturnErrors: cbor.ExtraDecErrorUnknownField,
+		}.DecMode()
+		if err != nil {
+			return VRFEnvelope{}, err
+		}
+		if err = decMode.Unmarshal(data, &env); err != nil {
+			return VRFEnvelope{}, fmt.Errorf("Error: The VRF envelope is neither JSON nor valid CBOR: %v", err)
+		}
+	}
+
+	return env, env.check()
+}
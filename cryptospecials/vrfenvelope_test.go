@@ -0,0 +1,99 @@
+package cryptospecials
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestProofBinary(t *testing.T) {
+
+	eccProof := Proof{X: big.NewInt(0x0102), Y: big.NewInt(0), C: big.NewInt(0xff), S: new(big.Int).Lsh(big.NewInt(1), 255)}
+	encoded, err := eccProof.MarshalBinary()
+	if err != nil {
+		t.Fatalf("FAIL - MarshalBinary - %v", err)
+	}
+	if !bytes.Equal(encoded[:9], []byte{0x00, 0x02, 0x01, 0x02, 0x00, 0x00, 0x00, 0x01, 0xff}) || len(encoded) != 9+2+32 {
+		t.Errorf("FAIL - The encoding is %x", encoded)
+	}
+
+	var decoded Proof
+	if err = decoded.UnmarshalBinary(encoded); err != nil {
+		t.Fatalf("FAIL - UnmarshalBinary - %v", err)
+	}
+	if decoded.X.Cmp(eccProof.X) != 0 || decoded.Y.Sign() != 0 || decoded.C.Cmp(eccProof.C) != 0 || decoded.S.Cmp(eccProof.S) != 0 {
+		t.Errorf("FAIL - The decoded proof is %v", decoded)
+	}
+
+	// Truncated proofs, trailing BYTES, and leading zeros are not encodings of a Proof
+	for _, bad := range [][]byte{
+		encoded[:len(encoded)-1],
+		encoded[:5],
+		append(append([]byte(nil), encoded...), 0x00),
+		{0x00, 0x02, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	} {
+		if decoded.UnmarshalBinary(bad) == nil {
+			t.Errorf("FAIL - %x was decoded", bad)
+		}
+	}
+	if _, err = (Proof{X: big.NewInt(1)}).MarshalBinary(); err == nil {
+		t.Errorf("FAIL - A Proof without Y, C, and S was encoded")
+	}
+}
+
+func TestVRFEnvelope(t *testing.T) {
+
+	env := VRFEnvelope{
+		Suite:          SuiteECVRFP256SHA256TAI,
+		KeyFingerprint: "5a7a78cca4a0f420d9bc62bb669c3c2759e39f723d3ae10dcbe0f0815a07ecd4",
+		Alpha:          []byte("sample"),
+		Beta:           []byte{0xa3, 0xad},
+		Proof:          []byte{0x03, 0x5b, 0x5c},
+	}
+
+	for _, format := range []string{VRFFormatJSON, VRFFormatCBOR} {
+		encoded, err := EncodeVRFEnvelope(env, format)
+		if err != nil {
+			t.Fatalf("FAIL - %s EncodeVRFEnvelope - %v", format, err)
+		}
+		decoded, err := DecodeVRFEnvelope(encoded)
+		if err != nil {
+			t.Fatalf("FAIL - %s DecodeVRFEnvelope - %v", format, err)
+		}
+		if decoded.Suite != env.Suite || decoded.KeyFingerprint != env.KeyFingerprint || !bytes.Equal(decoded.Alpha, env.Alpha) ||
+			!bytes.Equal(decoded.Beta, env.Beta) || !bytes.Equal(decoded.Proof, env.Proof) {
+			t.Errorf("FAIL - The %s envelope decoded as %v", format, decoded)
+		}
+		if again, _ := EncodeVRFEnvelope(decoded, format); !bytes.Equal(again, encoded) {
+			t.Errorf("FAIL - The %s encoding is not deterministic", format)
+		}
+	}
+
+	// The CBOR encoding is a deterministic map with the members sorted by length, then BYTES
+	encoded, _ := EncodeVRFEnvelope(env, VRFFormatCBOR)
+	if !bytes.HasPrefix(encoded, []byte{0xa5, 0x64, 'b', 'e', 't', 'a'}) {
+		t.Errorf("FAIL - The CBOR envelope starts %x", encoded[:6])
+	}
+
+	// Unknown or duplicate members, missing proofs, and other formats are rejected
+	if _, err := DecodeVRFEnvelope([]byte(`{"suite":"ECVRF-P256-SHA256-TAI","beta":"o60=","proof":"A1tc","pi":""}`)); err == nil {
+		t.Errorf("FAIL - An unknown JSON member was accepted")
+	}
+	for _, duplicate := range []string{
+		`{"suite":"ECVRF-P256-SHA256-TAI","beta":"o60=","proof":"A1tc","proof":"A1td"}`,
+		`{"suite":"ECVRF-P256-SHA256-TAI","beta":"o60=","proof":"A1tc","Suite":"ECVRF-P256-SHA256-TAI"}`,
+	} {
+		if _, err := DecodeVRFEnvelope([]byte(duplicate)); err == nil {
+			t.Errorf("FAIL - A duplicate JSON member was accepted: %s", duplicate)
+		}
+	}
+	if _, err := DecodeVRFEnvelope([]byte(`{"suite":"ECVRF-P256-SHA256-TAI","beta":"o60="}`)); err == nil {
+		t.Errorf("FAIL - An envelope without a proof was accepted")
+	}
+	if _, err := DecodeVRFEnvelope([]byte("suite")); err == nil {
+		t.Errorf("FAIL - Text that is not JSON was accepted")
+	}
+	if _, err := EncodeVRFEnvelope(env, "xml"); err == nil {
+		t.Errorf("FAIL - The format xml was accepted")
+	}
+}
@@ -0,0 +1,476 @@
+/*
+*	This package contains mechanisms that will allow for VRF and OPRF calculations.
+*
+*	OPRF: https://eprint.iacr.org/2017/111
+*
+*	RSA-VRF: https://eprint.iacr.org/2017/099.pdf
+*
+*		-Brian
+ */
+
+package cryptospecials
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//CertificateOptions is an exportable STRUCT
+/*
+*  CertificateOptions holds the fields of a CSR or self-signed certificate. The SANs are set
+*  with AddSANs and the key usages with ParseKeyUsage. NotBefore, NotAfter, and IsCA only
+*  apply to certificates.
+ */
+type CertificateOptions struct {
+	Subject        pkix.Name
+	DNSNames       []string
+	IPAddresses    []net.IP
+	EmailAddresses []string
+	URIs           []*url.URL
+	KeyUsage       x509.KeyUsage
+	ExtKeyUsage    []x509.ExtKeyUsage
+	NotBefore      time.Time
+	NotAfter       time.Time
+	IsCA           bool
+}
+
+// Key usage names, as used by OpenSSL and RFC 5280, in the order of the keyUsage bits
+var keyUsageNames = []struct {
+	name  string
+	usage x509.KeyUsage
+}{
+	{"digitalSignature", x509.KeyUsageDigitalSignature},
+	{"contentCommitment", x509.KeyUsageContentCommitment},
+	{"keyEncipherment", x509.KeyUsageKeyEncipherment},
+	{"dataEncipherment", x509.KeyUsageDataEncipherment},
+	{"keyAgreement", x509.KeyUsageKeyAgreement},
+	{"keyCertSign", x509.KeyUsageCertSign},
+	{"cRLSign", x509.KeyUsageCRLSign},
+	{"encipherOnly", x509.KeyUsageEncipherOnly},
+	{"decipherOnly", x509.KeyUsageDecipherOnly},
+}
+
+// Extended key usage names and OIDs (RFC 5280 section 4.2.1.12)
+var extKeyUsageNames = []struct {
+	name  string
+	usage x509.ExtKeyUsage
+	oid   asn1.ObjectIdentifier
+}{
+	{"serverAuth", x509.ExtKeyUsageServerAuth, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 1}},
+	{"clientAuth", x509.ExtKeyUsageClientAuth, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 2}},
+	{"codeSigning", x509.ExtKeyUsageCodeSigning, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 3}},
+	{"emailProtection", x509.ExtKeyUsageEmailProtection, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 4}},
+	{"timeStamping", x509.ExtKeyUsageTimeStamping, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}},
+	{"OCSPSigning", x509.ExtKeyUsageOCSPSigning, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 9}},
+}
+
+var (
+	oidKeyUsage    = asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidExtKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+)
+
+// Subject attribute names accepted by ParseSubject
+var subjectAttributes = map[string]func(name *pkix.Name, value string){
+	"CN":           func(name *pkix.Name, value string) { name.CommonName = value },
+	"O":            func(name *pkix.Name, value string) { name.Organization = append(name.Organization, value) },
+	"OU":           func(name *pkix.Name, value string) { name.OrganizationalUnit = append(name.OrganizationalUnit, value) },
+	"C":            func(name *pkix.Name, value string) { name.Country = append(name.Country, value) },
+	"ST":           func(name *pkix.Name, value string) { name.Province = append(name.Province, value) },
+	"L":            func(name *pkix.Name, value string) { name.Locality = append(name.Locality, value) },
+	"STREET":       func(name *pkix.Name, value string) { name.StreetAddress = append(name.StreetAddress, value) },
+	"POSTALCODE":   func(name *pkix.Name, value string) { name.PostalCode = append(name.PostalCode, value) },
+	"SERIALNUMBER": func(name *pkix.Name, value string) { name.SerialNumber = value },
+}
+
+//ParseSubject is an exportable function
+/*
+*  ParseSubject reads a distinguished name written as OpenSSL does ("/CN=foil/O=Example/C=US")
+*  or as RFC 4514 does ("CN=foil, O=Example, C=US"). CN, O, OU, C, ST, L, STREET, POSTALCODE,
+*  and SERIALNUMBER are accepted; escaped separators are not.
+ */
+func ParseSubject(subject string) (pkix.Name, error) {
+
+	var (
+		name  pkix.Name
+		parts []string
+	)
+
+	subject = strings.TrimSpace(subject)
+	if len(subject) == 0 {
+		return name, nil
+	}
+	if strings.HasPrefix(subject, "/") {
+		parts = strings.Split(subject[1:], "/")
+	} else {
+		parts = strings.Split(subject, ",")
+	}
+	for _, part := range parts {
+		pair := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(pair) != 2 || len(strings.TrimSpace(pair[1])) == 0 {
+			return pkix.Name{}, fmt.Errorf("Error: %q is not a subject attribute; use [NAME]=[value]", part)
+		}
+		set, ok := subjectAttributes[strings.ToUpper(strings.TrimSpace(pair[0]))]
+		if !ok {
+			return pkix.Name{}, fmt.Errorf("Error: Unknown subject attribute %q; use CN, O, OU, C, ST, L, STREET, POSTALCODE, or SERIALNUMBER", pair[0])
+		}
+		set(&name, strings.TrimSpace(pair[1]))
+	}
+
+	return name, nil
+}
+
+//AddSANs is an exportable method
+/*
+*  AddSANs adds subject alternative names to opts. A name may be prefixed with its type as
+*  OpenSSL does (DNS:, IP:, email:, URI:); otherwise IP addresses, names with an "@", and
+*  names with "://" are IP, email, and URI SANs and everything else is a DNS name.
+ */
+func (opts *CertificateOptions) AddSANs(sans []string) error {
+
+	for _, san := range sans {
+		san = strings.TrimSpace(san)
+		kind, value := "", san
+		if i := strings.Index(san, ":"); i > 0 {
+			switch strings.ToUpper(san[:i]) {
+			case "DNS", "IP", "EMAIL", "URI":
+				kind, value = strings.ToUpper(san[:i]), san[i+1:]
+			}
+		}
+		if len(kind) == 0 {
+			switch {
+			case net.ParseIP(san) != nil:
+				kind = "IP"
+			case strings.Contains(san, "://"):
+				kind = "URI"
+			case strings.Contains(san, "@"):
+				kind = "EMAIL"
+			default:
+				kind = "DNS"
+			}
+		}
+		if len(value) == 0 {
+			return fmt.Errorf("Error: The SAN %q is empty", san)
+		}
+
+		switch kind {
+		case "DNS":
+			opts.DNSNames = append(opts.DNSNames, value)
+		case "IP":
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return fmt.Errorf("Error: %q is not an IP address", value)
+			}
+			opts.IPAddresses = append(opts.IPAddresses, ip)
+		case "EMAIL":
+			opts.EmailAddresses = append(opts.EmailAddresses, value)
+		case "URI":
+			uri, err := url.Parse(value)
+			if err != nil || len(uri.Scheme) == 0 {
+				return fmt.Errorf("Error: %q is not a URI", value)
+			}
+			opts.URIs = append(opts.URIs, uri)
+		}
+	}
+
+	return nil
+}
+
+//ParseKeyUsage is an exportable method
+/*
+*  ParseKeyUsage sets the key usages of opts from names such as digitalSignature, keyEncipherment,
+*  keyCertSign (keyUsage), and serverAuth, clientAuth, codeSigning (extendedKeyUsage). The names
+*  are not case sensitive.
+ */
+func (opts *CertificateOptions) ParseKeyUsage(names []string) error {
+
+next:
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		for _, ku := range keyUsageNames {
+			if strings.EqualFold(name, ku.name) {
+				opts.KeyUsage |= ku.usage
+				continue next
+			}
+		}
+		for _, eku := range extKeyUsageNames {
+			if strings.EqualFold(name, eku.name) {
+				opts.ExtKeyUsage = append(opts.ExtKeyUsage, eku.usage)
+				continue next
+			}
+		}
+		return fmt.Errorf("Error: Unknown key usage %q", name)
+	}
+
+	return nil
+}
+
+//KeyUsageNames is an exportable function
+// KeyUsageNames names the keyUsage bits and extended key usages, e.g. for display
+func KeyUsageNames(usage x509.KeyUsage, extUsage []x509.ExtKeyUsage) []string {
+
+	var names []string
+
+	for _, ku := range keyUsageNames {
+		if usage&ku.usage != 0 {
+			names = append(names, ku.name)
+		}
+	}
+	for _, usage := range extUsage {
+		name := fmt.Sprintf("unknown (%d)", usage)
+		for _, eku := range extKeyUsageNames {
+			if eku.usage == usage {
+				name = eku.name
+			}
+		}
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// reverseBits reverses a byte; the keyUsage BIT STRING stores the first usage in the top bit
+func reverseBits(b byte) byte {
+
+	var r byte
+
+	for i := 0; i < 8; i++ {
+		r = r<<1 | b&1
+		b >>= 1
+	}
+
+	return r
+}
+
+// keyUsageExtensions encodes the keyUsage and extendedKeyUsage extensions that CSRs request
+func keyUsageExtensions(opts *CertificateOptions) ([]pkix.Extension, error) {
+
+	var extensions []pkix.Extension
+
+	if opts.KeyUsage != 0 {
+		bits := []byte{reverseBits(byte(opts.KeyUsage)), reverseBits(byte(opts.KeyUsage >> 8))}
+		if bits[1] == 0 {
+			bits = bits[:1]
+		}
+		bitLength := len(bits) * 8
+		for last := bits[len(bits)-1]; last&1 == 0; last >>= 1 {
+			bitLength--
+		}
+		value, err := asn1.Marshal(asn1.BitString{Bytes: bits, BitLength: bitLength})
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidKeyUsage, Critical: true, Value: value})
+	}
+	if len(opts.ExtKeyUsage) > 0 {
+		var oids []asn1.ObjectIdentifier
+		for _, usage := range opts.ExtKeyUsage {
+			for _, eku := range extKeyUsageNames {
+				if eku.usage == usage {
+					oids = append(oids, eku.oid)
+				}
+			}
+		}
+		value, err := asn1.Marshal(oids)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtKeyUsage, Value: value})
+	}
+
+	return extensions, nil
+}
+
+//CSRKeyUsage is an exportable function
+// CSRKeyUsage reads the keyUsage and extendedKeyUsage requested by a CSR
+func CSRKeyUsage(csr *x509.CertificateRequest) (usage x509.KeyUsage, extUsage []x509.ExtKeyUsage, err error) {
+
+	for _, extension := range csr.Extensions {
+		if extension.Id.Equal(oidKeyUsage) {
+			var bits asn1.BitString
+			_, err = asn1.Unmarshal(extension.Value, &bits)
+			if err != nil {
+				return 0, nil, err
+			}
+			for i := 0; i < 9; i++ {
+				if bits.At(i) != 0 {
+					usage |= 1 << uint(i)
+				}
+			}
+		} else if extension.Id.Equal(oidExtKeyUsage) {
+			var oids []asn1.ObjectIdentifier
+			_, err = asn1.Unmarshal(extension.Value, &oids)
+			if err != nil {
+				return 0, nil, err
+			}
+			for _, oid := range oids {
+				for _, eku := range extKeyUsageNames {
+					if eku.oid.Equal(oid) {
+						extUsage = append(extUsage, eku.usage)
+					}
+				}
+			}
+		}
+	}
+
+	return usage, extUsage, nil
+}
+
+// signerOf checks that a private key can sign certificates (X25519 keys can not)
+func signerOf(privKey crypto.PrivateKey) (crypto.Signer, error) {
+
+	signer, ok := privKey.(crypto.Signer)
+	if _, isX25519 := privKey.(*ecdh.PrivateKey); isX25519 || !ok {
+		return nil, errors.New("Error: X25519 keys can not sign; use an RSA, EC, or Ed25519 private key")
+	}
+
+	return signer, nil
+}
+
+//CreateCSR is an exportable function
+/*
+*  CreateCSR makes a PKCS#10 certificate signing request signed by privKey (RSA, EC, or Ed25519)
+*  and returns its DER. The key usages of opts are requested as extensions.
+ */
+func CreateCSR(privKey crypto.PrivateKey, opts *CertificateOptions) ([]byte, error) {
+
+	signer, err := signerOf(privKey)
+	if err != nil {
+		return nil, err
+	}
+	extensions, err := keyUsageExtensions(opts)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.CertificateRequest{
+		Subject:         opts.Subject,
+		DNSNames:        opts.DNSNames,
+		IPAddresses:     opts.IPAddresses,
+		EmailAddresses:  opts.EmailAddresses,
+		URIs:            opts.URIs,
+		ExtraExtensions: extensions,
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, signer)
+	if err != nil {
+		return nil, fmt.Errorf("Error: %v", err)
+	}
+
+	return der, nil
+}
+
+//CreateSelfSigned is an exportable function
+/*
+*  CreateSelfSigned makes an X.509 certificate for the public key of privKey, signed by privKey,
+*  and returns its DER. The serial number is a random 128-bit value. Without key usages a CA
+*  gets keyCertSign, cRLSign, and digitalSignature and other certificates get digitalSignature
+*  (and keyEncipherment for RSA keys).
+ */
+func CreateSelfSigned(privKey crypto.PrivateKey, opts *CertificateOptions) ([]byte, error) {
+
+	signer, err := signerOf(privKey)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.NotAfter.After(opts.NotBefore) {
+		return nil, errors.New("Error: The certificate must expire after it becomes valid")
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	usage := opts.KeyUsage
+	if usage == 0 && opts.IsCA {
+		usage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+	} else if usage == 0 {
+		usage = x509.KeyUsageDigitalSignature
+		if _, ok := signer.Public().(*rsa.PublicKey); ok {
+			usage |= x509.KeyUsageKeyEncipherment
+		}
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               opts.Subject,
+		Issuer:                opts.Subject,
+		NotBefore:             opts.NotBefore,
+		NotAfter:              opts.NotAfter,
+		DNSNames:              opts.DNSNames,
+		IPAddresses:           opts.IPAddresses,
+		EmailAddresses:        opts.EmailAddresses,
+		URIs:                  opts.URIs,
+		KeyUsage:              usage,
+		ExtKeyUsage:           opts.ExtKeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  opts.IsCA,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	if err != nil {
+		return nil, fmt.Errorf("Error: %v", err)
+	}
+
+	return der, nil
+}
+
+//ParseCertificates is an exportable function
+/*
+*  ParseCertificates reads every certificate and CSR in a PEM file (e.g. a chain) or a single
+*  DER certificate or CSR.
+ */
+func ParseCertificates(data []byte, sourcePath string) (certs []*x509.Certificate, csrs []*x509.CertificateRequest, err error) {
+
+	rest := bytes.TrimSpace(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Error: %s holds a certificate that is not valid: %v", sourcePath, err)
+			}
+			certs = append(certs, cert)
+		case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+			csr, err := x509.ParseCertificateRequest(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Error: %s holds a CSR that is not valid: %v", sourcePath, err)
+			}
+			csrs = append(csrs, csr)
+		}
+	}
+	if len(certs) > 0 || len(csrs) > 0 {
+		return certs, csrs, nil
+	}
+
+	// A single DER certificate or CSR
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return []*x509.Certificate{cert}, nil, nil
+	}
+	if csr, err := x509.ParseCertificateRequest(data); err == nil {
+		return nil, []*x509.CertificateRequest{csr}, nil
+	}
+
+	return nil, nil, fmt.Errorf("Error: %s holds no certificates or CSRs", sourcePath)
+}
+
+//CertificateKey is an exportable function
+// CertificateKey returns the public key of a certificate or CSR as a Key
+func CertificateKey(pubKey crypto.PublicKey, sourcePath string) (*Key, error) {
+
+	return newKey(sourcePath, FormatX509, nil, pubKey)
+}
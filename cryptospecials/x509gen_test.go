@@ -0,0 +1,111 @@
+package cryptospecials
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+// Both the OpenSSL and the RFC 4514 forms of a subject must give the same name
+func TestParseSubject(t *testing.T) {
+
+	for _, subject := range []string{"/CN=foil/O=Example/OU=Test/C=US", "CN=foil, O=Example, OU=Test, C=US"} {
+		name, err := ParseSubject(subject)
+		if err != nil {
+			t.Errorf("FAIL - ParseSubject(%q) - %v", subject, err)
+			continue
+		}
+		if name.String() != "CN=foil,OU=Test,O=Example,C=US" {
+			t.Errorf("FAIL - ParseSubject(%q) = %q", subject, name.String())
+		}
+	}
+	for _, subject := range []string{"CN", "CN=", "XX=foil"} {
+		if _, err := ParseSubject(subject); err == nil {
+			t.Errorf("FAIL - ParseSubject(%q) did not fail", subject)
+		}
+	}
+}
+
+// CSRs and self-signed certificates must hold the requested names and usages for each key type
+func TestCreateCertificates(t *testing.T) {
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	rsaKey, _ := RSAKeyGen(2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	var opts CertificateOptions
+	opts.Subject, _ = ParseSubject("CN=foil.example")
+	if err := opts.AddSANs([]string{"foil.example", "IP:::1", "10.0.0.1", "ops@foil.example", "URI:spiffe://foil/test"}); err != nil {
+		t.Fatalf("FAIL - AddSANs - %v", err)
+	}
+	if err := opts.ParseKeyUsage([]string{"digitalSignature", "keyagreement", "serverAuth", "clientAuth"}); err != nil {
+		t.Fatalf("FAIL - ParseKeyUsage - %v", err)
+	}
+	opts.NotBefore = time.Now().Truncate(time.Second)
+	opts.NotAfter = opts.NotBefore.Add(24 * time.Hour)
+	if len(opts.DNSNames) != 1 || len(opts.IPAddresses) != 2 || len(opts.EmailAddresses) != 1 || len(opts.URIs) != 1 {
+		t.Errorf("FAIL - The SANs were not sorted by type: %v %v %v %v", opts.DNSNames, opts.IPAddresses, opts.EmailAddresses, opts.URIs)
+	}
+	wantUsage := x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement
+
+	for _, privKey := range []interface{}{ecKey, rsaKey, edKey} {
+		der, err := CreateCSR(privKey, &opts)
+		if err != nil {
+			t.Fatalf("FAIL - CreateCSR(%T) - %v", privKey, err)
+		}
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil || csr.CheckSignature() != nil {
+			t.Fatalf("FAIL - The %T CSR is not valid - %v", privKey, err)
+		}
+		usage, extUsage, err := CSRKeyUsage(csr)
+		if err != nil || usage != wantUsage || len(extUsage) != 2 {
+			t.Errorf("FAIL - The %T CSR requests %v %v - %v", privKey, usage, extUsage, err)
+		}
+		if csr.Subject.CommonName != "foil.example" || len(csr.IPAddresses) != 2 || csr.URIs[0].String() != "spiffe://foil/test" {
+			t.Errorf("FAIL - The %T CSR does not hold the subject and SANs", privKey)
+		}
+
+		der, err = CreateSelfSigned(privKey, &opts)
+		if err != nil {
+			t.Fatalf("FAIL - CreateSelfSigned(%T) - %v", privKey, err)
+		}
+		certs, _, err := ParseCertificates(der, "DER")
+		if err != nil || len(certs) != 1 {
+			t.Fatalf("FAIL - ParseCertificates(%T) - %v", privKey, err)
+		}
+		cert := certs[0]
+		if cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) != nil || cert.KeyUsage != wantUsage || len(cert.ExtKeyUsage) != 2 || cert.IsCA {
+			t.Errorf("FAIL - The %T certificate is not valid or has the wrong usages", privKey)
+		}
+		if !cert.NotBefore.Equal(opts.NotBefore) || !cert.NotAfter.Equal(opts.NotAfter) {
+			t.Errorf("FAIL - The %T certificate validity is %v to %v", privKey, cert.NotBefore, cert.NotAfter)
+		}
+	}
+
+	// A CA gets keyCertSign by default
+	ca := CertificateOptions{IsCA: true, NotBefore: opts.NotBefore, NotAfter: opts.NotAfter}
+	ca.Subject.CommonName = "foil CA"
+	der, err := CreateSelfSigned(ecKey, &ca)
+	if err != nil {
+		t.Fatalf("FAIL - CreateSelfSigned (CA) - %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	if !cert.IsCA || cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		t.Errorf("FAIL - The CA certificate can not sign certificates")
+	}
+
+	// X25519 keys can not sign and a certificate must not expire before it is valid
+	xKey, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	if _, err = CreateCSR(xKey, &opts); err == nil {
+		t.Errorf("FAIL - CreateCSR accepted an X25519 key")
+	}
+	ca.NotAfter = ca.NotBefore
+	if _, err = CreateSelfSigned(ecKey, &ca); err == nil {
+		t.Errorf("FAIL - CreateSelfSigned accepted an empty validity period")
+	}
+}
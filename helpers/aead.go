@@ -0,0 +1,81 @@
+/*
+*  The AEADs available to foil containers. AES-256-GCM is the default. ChaCha20-Poly1305
+*  (RFC 8439) is a fast alternative on hosts without AES hardware. XChaCha20-Poly1305
+*  extends the nonce to 192 bits so that random nonces may be used for a practically
+*  unlimited number of messages under a single key. All three use 256-bit keys so the
+*  same key/password handling (CliKeyLogic, CliHeaderKeyLogic) applies to each.
+*  AES-256-GCM-SIV (see aesgcmsiv.go) is offered for callers that supply their own nonces.
+ */
+
+package helpers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+//CipherName is an exportable FUNCTION
+// CipherName returns a human readable name for a cipher ID
+func CipherName(id uint8) string {
+
+	switch id {
+	case CipherAES256GCM:
+		return "AES-256-GCM"
+	case CipherChaCha20Poly1305:
+		return "ChaCha20-Poly1305"
+	case CipherXChaCha20Poly1305:
+		return "XChaCha20-Poly1305"
+	case CipherAES256GCMSIV:
+		return "AES-256-GCM-SIV"
+	}
+
+	return fmt.Sprintf("unknown (%d)", id)
+}
+
+//CipherFromName is an exportable FUNCTION
+// CipherFromName maps the value of the --cipher flag onto a cipher ID
+func CipherFromName(name string) (uint8, error) {
+
+	switch name {
+	case "aes-256-gcm":
+		return CipherAES256GCM, nil
+	case "chacha20-poly1305":
+		return CipherChaCha20Poly1305, nil
+	case "xchacha20-poly1305":
+		return CipherXChaCha20Poly1305, nil
+	case "aes-256-gcm-siv":
+		return CipherAES256GCMSIV, nil
+	}
+
+	return 0, fmt.Errorf("Error: Unknown cipher \"%s\"; choose aes-256-gcm, aes-256-gcm-siv, chacha20-poly1305, or xchacha20-poly1305", name)
+}
+
+//NewAEAD is an exportable FUNCTION
+// NewAEAD returns the AEAD identified by cipherID keyed with a 256-bit key
+func NewAEAD(cipherID uint8, key []byte) (cipher.AEAD, error) {
+
+	if len(key) != 32 {
+		return nil, fmt.Errorf("Error: %s requires a 256-bit key", CipherName(cipherID))
+	}
+
+	switch cipherID {
+	case CipherAES256GCM:
+		aesBlock, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("Critial error in NewAEAD - NewCipher: %v", err)
+		}
+		return cipher.NewGCM(aesBlock)
+	case CipherChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	case CipherXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	case CipherAES256GCMSIV:
+		return NewGCMSIV(key)
+	}
+
+	return nil, errors.New("Error: Unsupported cipher " + CipherName(cipherID))
+}
@@ -62,7 +62,7 @@ func GetAESRandomBytes(randomSlice []byte, verbose bool) error {
 
 	// Print the random bytes to stdIn if verbose is set to TRUE
 	if verbose {
-		fmt.Printf("GetAESRandomBytes - Random bytes of length %d: %x\n", numRead, randomSlice)
+		fmt.Fprintf(Messages, "GetAESRandomBytes - Random bytes of length %d: %x\n", numRead, randomSlice)
 	}
 
 	// SUCCESS
@@ -99,10 +99,10 @@ func KeyFromPassword(password *string, salt []byte, securityParameter int, verbo
 
 	// If verbose mode is activated, print the following to StdOut
 	if verbose {
-		fmt.Printf("KeyFromPassword - Number of hashes (SHA-256): %d\n", securityParameter)
-		fmt.Printf("KeyFromPassword - Salt (hex): %x\n", salt)
-		fmt.Printf("SECRET - KeyFromPassword - Password: \"%s\"\n", *password)
-		fmt.Printf("SECRET - KeyFromPassword - key (hex): %x\n", KeyExpand)
+		fmt.Fprintf(Messages, "KeyFromPassword - Number of hashes (SHA-256): %d\n", securityParameter)
+		fmt.Fprintf(Messages, "KeyFromPassword - Salt (hex): %x\n", salt)
+		fmt.Fprintf(Messages, "SECRET - KeyFromPassword - Password: \"%s\"\n", *password)
+		fmt.Fprintf(Messages, "SECRET - KeyFromPassword - key (hex): %x\n", KeyExpand)
 	}
 
 	return KeyExpand
@@ -129,7 +129,7 @@ func AESCore(iv []byte, key []byte, adata *string, inputText []byte, operation *
 	*  Check to determine if ADATA is present. If ADATA is presenet, then convert the ADATA string into a BYTE
 	*  slice as required by Go's AES implementation.
 	 */
-	if len(byteAdata) > 0 {
+	if adata != nil && len(*adata) > 0 {
 		hasAdata = true
 		byteAdata = []byte(*adata)
 	} else {
@@ -142,15 +142,15 @@ func AESCore(iv []byte, key []byte, adata *string, inputText []byte, operation *
 	*  for debugging purposes when verbose mode is insufficient.
 	 */
 	if verbose {
-		fmt.Printf("AESCore - Does ADATA exist: %t\n", hasAdata)
+		fmt.Fprintf(Messages, "AESCore - Does ADATA exist: %t\n", hasAdata)
 		if byteAdata == nil {
-			fmt.Println("AESCore - byteAdata was nil")
+			fmt.Fprintln(Messages, "AESCore - byteAdata was nil")
 		} else {
-			fmt.Printf("AESCore - byteAdata contained the following ADATA: %s", byteAdata)
+			fmt.Fprintf(Messages, "AESCore - byteAdata contained the following ADATA (hex): %x\n", byteAdata)
 		}
-		fmt.Printf("AESCore - The iv/nonce used was (hex): %x\n", iv)
-		fmt.Printf("SECRET - AESCore - The key used was (hex): %x\n", key)
-		//fmt.Printf("AESCore - Input Text: %x\n", inputText)
+		fmt.Fprintf(Messages, "AESCore - The iv/nonce used was (hex): %x\n", iv)
+		fmt.Fprintf(Messages, "SECRET - AESCore - The key used was (hex): %x\n", key)
+		//fmt.Fprintf(Messages, "AESCore - Input Text: %x\n", inputText)
 	}
 
 	// Initialize a new instance of Go's AES cipher
@@ -190,8 +190,8 @@ func AESCore(iv []byte, key []byte, adata *string, inputText []byte, operation *
 	*  for debugging purposes when verbose mode is insufficient.
 	 */
 	if verbose {
-		fmt.Printf("AESCore - %s-ion completed.\n", operation)
-		//fmt.Printf("The ouptut text is (hex): %x\n", outputText)
+		fmt.Fprintf(Messages, "AESCore - %s-ion completed.\n", *operation)
+		//fmt.Fprintf(Messages, "The ouptut text is (hex): %x\n", outputText)
 	}
 
 	// SUCCESS: Return the output of the Decryption/Encryption if there were no errors
@@ -0,0 +1,283 @@
+/*
+*  AES-GCM-SIV (RFC 8452) is a nonce misuse-resistant AEAD. The tag is computed over the
+*  plaintext (with POLYVAL) before encryption and is then used as the initial counter, so
+*  encrypting twice under the same key and nonce leaks only whether the two plaintexts
+*  (and ADATA) were equal. Per-nonce authentication and encryption keys are derived from
+*  the key-generating key on every call. Only the standard library AES block cipher is
+*  used; POLYVAL is computed through its relationship with GHASH (RFC 8452 Appendix A)
+*  using branch-free bit arithmetic. It is considerably slower than hardware AES-GCM and
+*  is intended for inputs where a nonce may be supplied externally.
+ */
+
+package helpers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+//GCMSIVNonceSize is an exportable CONSTANT
+// The size of the AES-GCM-SIV nonce in BYTES
+const GCMSIVNonceSize = 12
+
+//GCMSIVTagSize is an exportable CONSTANT
+// The size of the AES-GCM-SIV tag in BYTES
+const GCMSIVTagSize = 16
+
+// RFC 8452 sec. 6 limits both the plaintext and the ADATA to 2^36 BYTES
+const gcmSIVMaxInput = 1 << 36
+
+type aesGCMSIV struct {
+	block   cipher.Block
+	keySize int
+}
+
+//NewGCMSIV is an exportable FUNCTION
+/*
+*  NewGCMSIV returns AES-GCM-SIV keyed with key. The key must be 16 BYTES (AES-128-GCM-SIV)
+*  or 32 BYTES (AES-256-GCM-SIV).
+ */
+func NewGCMSIV(key []byte) (cipher.AEAD, error) {
+
+	if len(key) != 16 && len(key) != 32 {
+		return nil, errors.New("Error: AES-GCM-SIV requires a 128 or 256-bit key")
+	}
+	aesBlock, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGCMSIV{block: aesBlock, keySize: len(key)}, nil
+}
+
+func (g *aesGCMSIV) NonceSize() int {
+	return GCMSIVNonceSize
+}
+
+func (g *aesGCMSIV) Overhead() int {
+	return GCMSIVTagSize
+}
+
+/*
+*  deriveKeys derives the message authentication key and message encryption key for nonce
+*  from the key-generating key (RFC 8452 sec. 4). Only the first 8 BYTES of each AES output
+*  are used.
+ */
+func (g *aesGCMSIV) deriveKeys(nonce []byte) ([]byte, cipher.Block) {
+
+	var (
+		in  [BlockSize]byte
+		out [BlockSize]byte
+	)
+
+	derived := make([]byte, 0, 16+g.keySize)
+	copy(in[4:], nonce)
+	for i := uint32(0); len(derived) < cap(derived); i++ {
+		binary.LittleEndian.PutUint32(in[:4], i)
+		g.block.Encrypt(out[:], in[:])
+		derived = append(derived, out[:8]...)
+	}
+
+	// The AES key size was checked in NewGCMSIV so this cannot fail
+	encBlock, _ := aes.NewCipher(derived[16:])
+
+	return derived[:16], encBlock
+}
+
+// tag computes the tag over the padded ADATA and plaintext (RFC 8452 sec. 4)
+func (g *aesGCMSIV) tag(authKey []byte, encBlock cipher.Block, nonce, plaintext, adata []byte) [GCMSIVTagSize]byte {
+
+	var (
+		lengths [BlockSize]byte
+		s       [BlockSize]byte
+		out     [GCMSIVTagSize]byte
+	)
+
+	p := newPolyval(authKey)
+	p.updatePadded(adata)
+	p.updatePadded(plaintext)
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(adata))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext))*8)
+	p.update(lengths[:])
+
+	p.sum(s[:])
+	for i := range nonce {
+		s[i] ^= nonce[i]
+	}
+	s[15] &= 0x7f
+	encBlock.Encrypt(out[:], s[:])
+
+	return out
+}
+
+// gcmSIVCTR encrypts in into out with the 32-bit little-endian counter of RFC 8452 sec. 4
+func gcmSIVCTR(encBlock cipher.Block, tag []byte, out, in []byte) {
+
+	var (
+		counter   [BlockSize]byte
+		keyStream [BlockSize]byte
+	)
+
+	copy(counter[:], tag)
+	counter[15] |= 0x80
+	for len(in) > 0 {
+		encBlock.Encrypt(keyStream[:], counter[:])
+		n := subtle.XORBytes(out, in, keyStream[:])
+		out, in = out[n:], in[n:]
+		binary.LittleEndian.PutUint32(counter[:4], binary.LittleEndian.Uint32(counter[:4])+1)
+	}
+}
+
+func (g *aesGCMSIV) Seal(dst, nonce, plaintext, adata []byte) []byte {
+
+	if len(nonce) != GCMSIVNonceSize {
+		panic("foil/helpers: incorrect nonce length given to AES-GCM-SIV")
+	}
+	if uint64(len(plaintext)) > gcmSIVMaxInput || uint64(len(adata)) > gcmSIVMaxInput {
+		panic("foil/helpers: message too large for AES-GCM-SIV")
+	}
+
+	authKey, encBlock := g.deriveKeys(nonce)
+	tag := g.tag(authKey, encBlock, nonce, plaintext, adata)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+GCMSIVTagSize)
+	gcmSIVCTR(encBlock, tag[:], out, plaintext)
+	copy(out[len(plaintext):], tag[:])
+
+	return ret
+}
+
+func (g *aesGCMSIV) Open(dst, nonce, ciphertext, adata []byte) ([]byte, error) {
+
+	if len(nonce) != GCMSIVNonceSize {
+		panic("foil/helpers: incorrect nonce length given to AES-GCM-SIV")
+	}
+	if len(ciphertext) < GCMSIVTagSize || uint64(len(ciphertext)) > gcmSIVMaxInput+GCMSIVTagSize ||
+		uint64(len(adata)) > gcmSIVMaxInput {
+		return nil, errors.New("Error: AES-GCM-SIV message authentication failed")
+	}
+
+	authKey, encBlock := g.deriveKeys(nonce)
+	tag := ciphertext[len(ciphertext)-GCMSIVTagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-GCMSIVTagSize]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	gcmSIVCTR(encBlock, tag, out, ciphertext)
+	expected := g.tag(authKey, encBlock, nonce, out, adata)
+	if subtle.ConstantTimeCompare(expected[:], tag) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errors.New("Error: AES-GCM-SIV message authentication failed")
+	}
+
+	return ret, nil
+}
+
+// sliceForAppend extends in by n BYTES; returning the whole slice and the new tail
+func sliceForAppend(in []byte, n int) ([]byte, []byte) {
+
+	var head []byte
+
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+
+	return head, head[len(in):]
+}
+
+/*
+*  polyval accumulates POLYVAL(H, X_1, ..., X_n). Field elements are held in the GHASH
+*  representation: POLYVAL(H, X) = ByteReverse(GHASH(mulX_GHASH(ByteReverse(H)),
+*  ByteReverse(X))). Loading the little-endian POLYVAL BYTES as two little-endian words,
+*  most significant word first, is that byte reversal.
+ */
+type polyval struct {
+	h fieldElement
+	y fieldElement
+}
+
+// A GHASH field element; hi holds the first (most significant) 64 bits
+type fieldElement struct {
+	hi, lo uint64
+}
+
+func newPolyval(key []byte) *polyval {
+	return &polyval{h: mulX(loadElement(key))}
+}
+
+func loadElement(b []byte) fieldElement {
+	return fieldElement{hi: binary.LittleEndian.Uint64(b[8:16]), lo: binary.LittleEndian.Uint64(b[:8])}
+}
+
+// shiftRight multiplies x by the GHASH generator; i.e. one step of SP 800-38D Algorithm 1
+func shiftRight(x fieldElement) fieldElement {
+
+	carry := x.lo & 1
+	x.lo = x.lo>>1 | x.hi<<63
+	x.hi = x.hi>>1 ^ (0xe100000000000000 & -carry)
+
+	return x
+}
+
+// mulX is mulX_GHASH of RFC 8452 Appendix A
+func mulX(x fieldElement) fieldElement {
+	return shiftRight(x)
+}
+
+// gfMul multiplies x and y in GF(2^128) with the GHASH bit order; it does not branch on secrets
+func gfMul(x, y fieldElement) fieldElement {
+
+	var z fieldElement
+
+	for i := 0; i < 128; i++ {
+		var bit uint64
+		if i < 64 {
+			bit = x.hi >> (63 - i) & 1
+		} else {
+			bit = x.lo >> (127 - i) & 1
+		}
+		z.hi ^= y.hi & -bit
+		z.lo ^= y.lo & -bit
+		y = shiftRight(y)
+	}
+
+	return z
+}
+
+// update absorbs one 16 BYTE block
+func (p *polyval) update(block []byte) {
+
+	x := loadElement(block)
+	p.y.hi ^= x.hi
+	p.y.lo ^= x.lo
+	p.y = gfMul(p.y, p.h)
+}
+
+// updatePadded absorbs in, zero padded to a multiple of 16 BYTES
+func (p *polyval) updatePadded(in []byte) {
+
+	var last [BlockSize]byte
+
+	for len(in) >= BlockSize {
+		p.update(in[:BlockSize])
+		in = in[BlockSize:]
+	}
+	if len(in) > 0 {
+		copy(last[:], in)
+		p.update(last[:])
+	}
+}
+
+// sum writes the POLYVAL result into out
+func (p *polyval) sum(out []byte) {
+
+	binary.LittleEndian.PutUint64(out[:8], p.y.lo)
+	binary.LittleEndian.PutUint64(out[8:16], p.y.hi)
+}
@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+/*
+*  Test AES-GCM-SIV against the POLYVAL example of RFC 8452 Appendix A and the test vectors
+*  of Appendix C (AES-128 and AES-256, with and without AAD, including the counter wrap test
+*  of C.3).
+ */
+func TestGCMSIVVectors(t *testing.T) {
+
+	h, _ := hex.DecodeString("25629347589242761d31f826ba4b757b")
+	x, _ := hex.DecodeString("4f4f95668c83dfb6401762bb2d01a262d1a24ddd2721d006bbe45f20d3c9f362")
+	sum := make([]byte, 16)
+	p := newPolyval(h)
+	p.updatePadded(x)
+	p.sum(sum)
+	if hex.EncodeToString(sum) != "f7a3b47b846119fae5b7866cf5e5b77e" {
+		t.Errorf("FAIL - POLYVAL: %x", sum)
+	}
+
+	vectors := []struct {
+		key, nonce, plaintext, aad, result string
+	}{
+		{"01000000000000000000000000000000", "030000000000000000000000", "", "",
+			"dc20e2d83f25705bb49e439eca56de25"},
+		{"01000000000000000000000000000000", "030000000000000000000000", "0100000000000000", "",
+			"b5d839330ac7b786578782fff6013b815b287c22493a364c"},
+		{"01000000000000000000000000000000", "030000000000000000000000", "0200000000000000", "01",
+			"1e6daba35669f4273b0a1a2560969cdf790d99759abd1508"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "", "",
+			"07f5f4169bbf55a8400cd47ea6fd400f"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "0100000000000000", "",
+			"c2ef328e5c71c83b843122130f7364b761e0b97427e3df28"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "010000000000000000000000", "",
+			"9aab2aeb3faa0a34aea8e2b18ca50da9ae6559e48fd10f6e5c9ca17e"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "0200000000000000", "01",
+			"1de22967237a813291213f267e3b452f02d01ae33e4ec854"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "020000000000000000000000", "01",
+			"163d6f9cc1b346cd453a2e4cc1a4a19ae800941ccdc57cc8413c277f"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "02000000000000000000000000000000", "01",
+			"c91545823cc24f17dbb0e9e807d5ec17b292d28ff61189e8e49f3875ef91aff7"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "02000000", "010000000000000000000000",
+			"22b3f4cd1835e517741dfddccfa07fa4661b74cf"},
+		{"0000000000000000000000000000000000000000000000000000000000000000", "000000000000000000000000", "000000000000000000000000000000004db923dc793ee6497c76dcc03a98e108", "",
+			"f3f80f2cf0cb2dd9c5984fcda908456cc537703b5ba70324a6793a7bf218d3eaffffffff000000000000000000000000"},
+	}
+
+	for i, v := range vectors {
+		key, _ := hex.DecodeString(v.key)
+		nonce, _ := hex.DecodeString(v.nonce)
+		plaintext, _ := hex.DecodeString(v.plaintext)
+		aad, _ := hex.DecodeString(v.aad)
+
+		aead, err := NewGCMSIV(key)
+		if err != nil {
+			t.Fatalf("FAIL - NewGCMSIV: %v", err)
+		}
+		sealed := aead.Seal(nil, nonce, plaintext, aad)
+		if hex.EncodeToString(sealed) != v.result {
+			t.Errorf("FAIL - Vector %d: expected %s; received %x", i, v.result, sealed)
+		}
+		opened, err := aead.Open(nil, nonce, sealed, aad)
+		if err != nil || !bytes.Equal(opened, plaintext) {
+			t.Errorf("FAIL - Vector %d did not open: %v", i, err)
+		}
+	}
+}
+
+/*
+*  Test that repeating a nonce reveals only plaintext equality and that modified ciphertext
+*  or ADATA is rejected.
+ */
+func TestGCMSIVMisuse(t *testing.T) {
+
+	key := make([]byte, 32)
+	GetAESRandomBytes(key, false)
+	nonce := make([]byte, GCMSIVNonceSize)
+	adata := []byte("I love encryption")
+	aead, _ := NewGCMSIV(key)
+
+	first := aead.Seal(nil, nonce, []byte("Attack at dawn!"), adata)
+	second := aead.Seal(nil, nonce, []byte("Attack at dawn!"), adata)
+	third := aead.Seal(nil, nonce, []byte("Attack at dusk!"), adata)
+	if !bytes.Equal(first, second) {
+		t.Errorf("FAIL - Equal messages under a repeated nonce should be equal")
+	}
+	if bytes.Equal(first[:4], third[:4]) || bytes.Equal(first[len(first)-16:], third[len(third)-16:]) {
+		t.Errorf("FAIL - Different messages under a repeated nonce share a keystream or tag")
+	}
+
+	_, err := aead.Open(nil, nonce, first, []byte("I love decryption"))
+	if err == nil {
+		t.Errorf("FAIL - Wrong ADATA was not detected")
+	}
+	first[0] ^= 0x01
+	_, err = aead.Open(nil, nonce, first, adata)
+	if err == nil {
+		t.Errorf("FAIL - Modified ciphertext was not detected")
+	}
+}
@@ -0,0 +1,296 @@
+/*
+*  Welcome to the streaming side of the AES cli encryption/decryption helper core. The
+*  functions contained herein split the input into fixed-size segments and seal each
+*  segment independently with the STREAM construction (Hoang, Reyhanitabar, Rogaway,
+*  Vizar: https://eprint.iacr.org/2015/189). Only one segment is held in memory at a
+*  time so inputs of any size may be d/encrypted with constant memory.
+*
+*  The nonce of segment i is: noncePrefix || uint32_be(i) || lastFlag
+*
+*  lastFlag is 0x01 for the final segment and 0x00 otherwise. Because the counter and
+*  last flag are authenticated as part of the nonce, dropping, reordering, or truncating
+*  segments results in an authentication failure.
+ */
+
+package helpers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+//StreamChunkSize is an exportable CONSTANT
+/*
+*  StreamChunkSize is the default number of plaintext BYTES sealed in each segment of a
+*  stream. 64KB keeps memory usage small while the 16 byte tag overhead stays negligible.
+ */
+const StreamChunkSize = 64 * 1024
+
+//MaxStreamChunkSize is an exportable CONSTANT
+/*
+*  MaxStreamChunkSize is the largest segment size accepted. Each segment is far below
+*  the GCM limit of 2^32 blocks per nonce so MaxFileSize does not apply to streams.
+ */
+const MaxStreamChunkSize = 16 * 1024 * 1024
+
+// streamNonceOverhead is the number of nonce BYTES used by the segment counter and last flag
+const streamNonceOverhead = 5
+
+//StreamNoncePrefixSize is an exportable FUNCTION
+/*
+*  StreamNoncePrefixSize returns the number of random BYTES needed for the nonce prefix
+*  of a stream sealed with aead; e.g. 7 BYTES for AES-256-GCM's 12 BYTE nonce.
+ */
+func StreamNoncePrefixSize(aead cipher.AEAD) int {
+	return aead.NonceSize() - streamNonceOverhead
+}
+
+//GetStreamNoncePrefix is an exportable FUNCTION
+/*
+*  GetStreamNoncePrefix returns a random nonce prefix sized for aead. Random is read from
+*  the go package 'crypto/rand'; see GetAESRandomBytes for more information.
+ */
+func GetStreamNoncePrefix(aead cipher.AEAD, verbose bool) ([]byte, error) {
+
+	prefix := make([]byte, StreamNoncePrefixSize(aead))
+	_, err := io.ReadFull(rand.Reader, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if verbose {
+		fmt.Fprintf(Messages, "GetStreamNoncePrefix - Nonce prefix of length %d: %x\n", len(prefix), prefix)
+	}
+
+	return prefix, nil
+}
+
+/*
+*  streamNonce builds the nonce for segment 'counter'. The prefix is copied so that the
+*  caller's slice is never modified.
+ */
+func streamNonce(nonce []byte, prefix []byte, counter uint32, last bool) []byte {
+
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[len(prefix):], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	} else {
+		nonce[len(nonce)-1] = 0
+	}
+
+	return nonce
+}
+
+// checkStreamParameters validates the parameters shared by StreamEncrypt and StreamDecrypt
+func checkStreamParameters(aead cipher.AEAD, noncePrefix []byte, chunkSize int) error {
+
+	if aead == nil {
+		return errors.New("Error: No AEAD supplied to stream")
+	} else if len(noncePrefix) != StreamNoncePrefixSize(aead) {
+		return fmt.Errorf("Error: Stream nonce prefix must be %d bytes", StreamNoncePrefixSize(aead))
+	} else if chunkSize <= 0 || chunkSize > MaxStreamChunkSize {
+		return fmt.Errorf("Error: Stream chunk size must be between 1 and %d bytes", MaxStreamChunkSize)
+	}
+
+	return nil
+}
+
+//StreamEncrypt is an exportable FUNCTION
+/*
+*  StreamEncrypt reads plaintext from src until EOF and writes sealed segments to dst.
+*  Every segment except the last carries exactly chunkSize BYTES of plaintext; the last
+*  segment may be empty. adata is authenticated with every segment. StreamEncrypt does
+*  NOT write the nonce prefix; the caller must store it alongside the ciphertext.
+ */
+func StreamEncrypt(aead cipher.AEAD, noncePrefix []byte, adata []byte, chunkSize int, dst io.Writer, src io.Reader, verbose bool) error {
+
+	var (
+		n       int
+		carry   int
+		last    bool
+		counter uint32
+		err     error
+	)
+
+	err = checkStreamParameters(aead, noncePrefix, chunkSize)
+	if err != nil {
+		return err
+	}
+
+	/*
+	*  One extra BYTE is read past each segment to determine whether the segment is the
+	*  last one. The extra BYTE is carried over to the start of the next segment.
+	 */
+	buf := make([]byte, chunkSize+1)
+	out := make([]byte, 0, chunkSize+aead.Overhead())
+	nonce := make([]byte, aead.NonceSize())
+
+	for {
+		n, err = io.ReadFull(src, buf[carry:])
+		n += carry
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			last = true
+		} else if err != nil {
+			return fmt.Errorf("Error: Reading stream input: %v", err)
+		}
+
+		if last {
+			out = aead.Seal(out[:0], streamNonce(nonce, noncePrefix, counter, true), buf[:n], adata)
+		} else {
+			out = aead.Seal(out[:0], streamNonce(nonce, noncePrefix, counter, false), buf[:chunkSize], adata)
+		}
+		_, err = dst.Write(out)
+		if err != nil {
+			return fmt.Errorf("Error: Writing stream output: %v", err)
+		}
+
+		if last {
+			break
+		}
+		if counter == math.MaxUint32 {
+			return errors.New("Error: Stream segment counter exhausted; use a larger chunk size")
+		}
+		buf[0] = buf[chunkSize]
+		carry = 1
+		counter++
+	}
+
+	if verbose {
+		fmt.Fprintf(Messages, "StreamEncrypt - Segments sealed: %d\n", uint64(counter)+1)
+		fmt.Fprintf(Messages, "StreamEncrypt - Segment size (bytes): %d\n", chunkSize)
+	}
+
+	return nil
+}
+
+//StreamDecrypt is an exportable FUNCTION
+/*
+*  StreamDecrypt reads sealed segments from src until EOF and writes the plaintext of each
+*  authenticated segment to dst. chunkSize, noncePrefix, and adata must match the values
+*  given to StreamEncrypt. NOTE: plaintext is released one segment at a time; if an error
+*  is returned, any output already written must be discarded by the caller.
+ */
+func StreamDecrypt(aead cipher.AEAD, noncePrefix []byte, adata []byte, chunkSize int, dst io.Writer, src io.Reader, verbose bool) error {
+
+	var (
+		n       int
+		carry   int
+		last    bool
+		counter uint32
+		err     error
+	)
+
+	err = checkStreamParameters(aead, noncePrefix, chunkSize)
+	if err != nil {
+		return err
+	}
+
+	sealedSize := chunkSize + aead.Overhead()
+	buf := make([]byte, sealedSize+1)
+	out := make([]byte, 0, chunkSize)
+	nonce := make([]byte, aead.NonceSize())
+
+	for {
+		n, err = io.ReadFull(src, buf[carry:])
+		n += carry
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			last = true
+		} else if err != nil {
+			return fmt.Errorf("Error: Reading stream input: %v", err)
+		}
+
+		if last {
+			if n < aead.Overhead() {
+				return errors.New("Error: Stream is truncated; final segment is missing")
+			}
+			out, err = aead.Open(out[:0], streamNonce(nonce, noncePrefix, counter, true), buf[:n], adata)
+		} else {
+			out, err = aead.Open(out[:0], streamNonce(nonce, noncePrefix, counter, false), buf[:sealedSize], adata)
+		}
+		if err != nil {
+			return fmt.Errorf("Error: Stream segment %d failed authentication: %v", counter, err)
+		}
+		_, err = dst.Write(out)
+		if err != nil {
+			return fmt.Errorf("Error: Writing stream output: %v", err)
+		}
+
+		if last {
+			break
+		}
+		if counter == math.MaxUint32 {
+			return errors.New("Error: Stream segment counter exhausted")
+		}
+		buf[0] = buf[sealedSize]
+		carry = 1
+		counter++
+	}
+
+	if verbose {
+		fmt.Fprintf(Messages, "StreamDecrypt - Segments opened: %d\n", uint64(counter)+1)
+	}
+
+	return nil
+}
+
+//StreamCore is an exportable FUNCTION
+/*
+*  StreamCore is the streaming counterpart of AESCore. When encrypting, a random nonce
+*  prefix is generated and written to dst before the sealed segments (much like AESCore
+*  writes the IV/nonce first). When decrypting, the nonce prefix is read from the start
+*  of src. chunkSize must be the same for encryption and decryption.
+ */
+func StreamCore(key []byte, adata *string, chunkSize int, dst io.Writer, src io.Reader, operation *string, verbose bool) error {
+
+	var (
+		byteAdata   []byte
+		noncePrefix []byte
+	)
+
+	if adata != nil && len(*adata) > 0 {
+		byteAdata = []byte(*adata)
+	}
+
+	// Initialize a new instance of Go's AES cipher with GCM mode
+	aesBlock, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("Critial error in StreamCore - NewCipher: %v", err)
+	}
+	aesGCM, err := cipher.NewGCM(aesBlock)
+	if err != nil {
+		return fmt.Errorf("Critial error in StreamCore - NewGCM: %v", err)
+	}
+
+	if verbose {
+		fmt.Fprintf(Messages, "StreamCore - Does ADATA exist: %t\n", len(byteAdata) > 0)
+		fmt.Fprintf(Messages, "SECRET - StreamCore - The key used was (hex): %x\n", key)
+	}
+
+	if *operation == "encrypt" {
+		noncePrefix, err = GetStreamNoncePrefix(aesGCM, verbose)
+		if err != nil {
+			return err
+		}
+		_, err = dst.Write(noncePrefix)
+		if err != nil {
+			return fmt.Errorf("Error: Writing stream output: %v", err)
+		}
+		return StreamEncrypt(aesGCM, noncePrefix, byteAdata, chunkSize, dst, src, verbose)
+	} else if *operation == "decrypt" {
+		noncePrefix = make([]byte, StreamNoncePrefixSize(aesGCM))
+		_, err = io.ReadFull(src, noncePrefix)
+		if err != nil {
+			return errors.New("Error: Stream is too short to contain a nonce prefix")
+		}
+		return StreamDecrypt(aesGCM, noncePrefix, byteAdata, chunkSize, dst, src, verbose)
+	}
+
+	return errors.New("StreamCore - Invalid cipher operation during Operation Check")
+}
@@ -0,0 +1,147 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"testing"
+)
+
+// Build an AES-256-GCM instance and nonce prefix for stream testing
+func testStreamAEAD(t *testing.T) (cipher.AEAD, []byte) {
+
+	key := make([]byte, 32)
+	err := GetAESRandomBytes(key, false)
+	if err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+	aesBlock, _ := aes.NewCipher(key)
+	aesGCM, _ := cipher.NewGCM(aesBlock)
+	prefix, err := GetStreamNoncePrefix(aesGCM, false)
+	if err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+
+	return aesGCM, prefix
+}
+
+/*
+*  Test StreamEncrypt/StreamDecrypt round trips on input sizes around the segment
+*  boundaries; including empty input which must still produce an (empty) final segment.
+ */
+func TestStreamRoundTrip(t *testing.T) {
+
+	var (
+		chunk      int
+		ciphertext bytes.Buffer
+		plaintext  bytes.Buffer
+	)
+
+	chunk = 64
+	aesGCM, prefix := testStreamAEAD(t)
+	adata := []byte("I love encryption")
+
+	for _, size := range []int{0, 1, chunk - 1, chunk, chunk + 1, 2 * chunk, 3*chunk + 7} {
+		input := bytes.Repeat([]byte{0xA5}, size)
+		ciphertext.Reset()
+		plaintext.Reset()
+
+		err := StreamEncrypt(aesGCM, prefix, adata, chunk, &ciphertext, bytes.NewReader(input), false)
+		if err != nil {
+			t.Errorf("FAIL - StreamEncrypt (%d bytes): %v", size, err)
+			continue
+		}
+		segments := (size + chunk - 1) / chunk
+		if segments == 0 {
+			segments = 1
+		}
+		if ciphertext.Len() != size+segments*aesGCM.Overhead() {
+			t.Errorf("FAIL - Expected %d segments for %d bytes; ciphertext length %d", segments, size, ciphertext.Len())
+		}
+
+		err = StreamDecrypt(aesGCM, prefix, adata, chunk, &plaintext, &ciphertext, false)
+		if err != nil {
+			t.Errorf("FAIL - StreamDecrypt (%d bytes): %v", size, err)
+			continue
+		}
+		if !bytes.Equal(plaintext.Bytes(), input) {
+			t.Errorf("FAIL - plaintext before and after stream enc/dec are not equivalent (%d bytes)", size)
+		}
+	}
+}
+
+/*
+*  Test that truncating, reordering, or tampering with segments is detected
+ */
+func TestStreamTamper(t *testing.T) {
+
+	var (
+		chunk      int
+		ciphertext bytes.Buffer
+	)
+
+	chunk = 32
+	aesGCM, prefix := testStreamAEAD(t)
+	input := bytes.Repeat([]byte("Attack at dawn! "), 8) // 4 full segments; the 4th is final
+	sealed := chunk + aesGCM.Overhead()
+
+	err := StreamEncrypt(aesGCM, prefix, nil, chunk, &ciphertext, bytes.NewReader(input), false)
+	if err != nil {
+		t.Fatalf("FAIL - StreamEncrypt: %v", err)
+	}
+	good := ciphertext.Bytes()
+
+	truncated := good[:2*sealed]
+	reordered := append(append(append([]byte{}, good[sealed:2*sealed]...), good[:sealed]...), good[2*sealed:]...)
+	flipped := append([]byte{}, good...)
+	flipped[5] ^= 0x01
+
+	for name, bad := range map[string][]byte{"truncated": truncated, "reordered": reordered, "flipped": flipped} {
+		err = StreamDecrypt(aesGCM, prefix, nil, chunk, &bytes.Buffer{}, bytes.NewReader(bad), false)
+		if err == nil {
+			t.Errorf("FAIL - Expected %s stream to fail authentication", name)
+		} else {
+			fmt.Printf("PASS - StreamDecrypt rejected %s stream: %v\n", name, err)
+		}
+	}
+}
+
+/*
+*  Test StreamCore enc/dec including the nonce prefix written ahead of the segments
+ */
+func TestStreamCore(t *testing.T) {
+
+	var (
+		ciphertext bytes.Buffer
+		plaintext  bytes.Buffer
+	)
+
+	password := "LegitPassword2"
+	adata := "I love encryption"
+	key := KeyFromPassword(&password, nil, 64, false)
+	input := []byte("Attack at dawn! I have special characters: !@#(%*U@#)$(_+!@#_|||&&DROPFILLSELECT.")
+
+	operationEnc := "encrypt"
+	err := StreamCore(key, &adata, 16, &ciphertext, bytes.NewReader(input), &operationEnc, false)
+	if err != nil {
+		t.Fatalf("FAIL - StreamCore encryption: %v", err)
+	}
+	operationDec := "decrypt"
+	err = StreamCore(key, &adata, 16, &plaintext, &ciphertext, &operationDec, false)
+	if err != nil {
+		t.Fatalf("FAIL - StreamCore decryption: %v", err)
+	}
+	if !bytes.Equal(plaintext.Bytes(), input) {
+		t.Errorf("FAIL - plaintext before and after StreamCore enc/dec are not equivalent.")
+	}
+
+	// The wrong ADATA must fail authentication
+	sealed := &bytes.Buffer{}
+	StreamCore(key, &adata, 16, sealed, bytes.NewReader(input), &operationEnc, false)
+	wrongAdata := "I love decryption"
+	err = StreamCore(key, &wrongAdata, 16, &bytes.Buffer{}, sealed, &operationDec, false)
+	if err == nil {
+		t.Errorf("FAIL - StreamCore decryption with the wrong ADATA succeeded")
+	}
+}
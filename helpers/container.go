@@ -0,0 +1,434 @@
+/*
+*  Welcome to the foil container format. Every ciphertext produced by 'foil aes enc' begins
+*  with a small self-describing header so that 'foil aes dec' can pick the cipher, KDF, and
+*  segment settings automatically; even if the defaults of this tool change in the future.
+*
+*  Header layout (all integers are big-endian):
+*
+*	magic        4 BYTES   "FOIL"
+*	version      1 BYTE    ContainerVersion
+*	cipher ID    1 BYTE    see Cipher* constants
+*	KDF ID       1 BYTE    see KDF* constants
+*	KDF params  12 BYTES   iterations || memory || parallelism (uint32 each)
+*	salt length  1 BYTE    followed by the salt
+*	nonce length 1 BYTE    followed by the nonce (one-shot) or nonce prefix (stream)
+*	chunk size   4 BYTES   0 for a single sealed payload; otherwise STREAM segment size
+*	recipients   1 BYTE    (version 2) the number of wrapped data keys that follow; each is
+*	                       type (1 BYTE) || length (2 BYTES) || wrapped key (see envelope.go)
+*
+*  The encoded header is authenticated as associated data with every sealed payload or
+*  segment so that it cannot be modified without detection.
+ */
+
+package helpers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+//ContainerMagic is an exportable VARIABLE
+// The magic BYTES found at the start of every foil container
+var ContainerMagic = []byte("FOIL")
+
+//ContainerVersion is an exportable CONSTANT
+// The current version of the container format written by foil; version 1 has no recipients
+const ContainerVersion = 2
+
+// Cipher IDs stored in the container header
+const (
+	CipherAES256GCM         uint8 = 1
+	CipherChaCha20Poly1305  uint8 = 2
+	CipherXChaCha20Poly1305 uint8 = 3
+	CipherAES256GCMSIV      uint8 = 4
+)
+
+// KDF IDs stored in the container header
+const (
+	KDFNone         uint8 = 0
+	KDFPBKDF2SHA256 uint8 = 1
+	KDFArgon2id     uint8 = 2
+	KDFScrypt       uint8 = 3
+)
+
+//LegacyPBKDF2Iterations is an exportable CONSTANT
+// The number of PBKDF2 iterations used by foil before the container format existed
+const LegacyPBKDF2Iterations = 64
+
+// The size of the fixed portion of the header (everything but the salt and nonce)
+const headerFixedSize = 4 + 1 + 1 + 1 + 12 + 1 + 1 + 4
+
+//ErrNotContainer is an exportable ERROR
+// ErrNotContainer is returned by ReadHeader when the input does not begin with ContainerMagic
+var ErrNotContainer = errors.New("Error: Input is not a foil container")
+
+//KDFParams is an exportable struct
+/*
+*  KDFParams holds the work factors of the password KDF. The meaning of each field depends
+*  on the KDF; unused fields are zero.
+*
+*	PBKDF2-SHA256: Iterations = iterations
+*	Argon2id     : Iterations = time (passes), Memory = KiB, Parallelism = threads
+*	scrypt       : Iterations = N (cost), Memory = r (block size), Parallelism = p
+ */
+type KDFParams struct {
+	Iterations  uint32
+	Memory      uint32
+	Parallelism uint32
+}
+
+//Header is an exportable struct
+type Header struct {
+	Version    uint8
+	Cipher     uint8
+	KDF        uint8
+	KDFParams  KDFParams
+	Salt       []byte
+	Nonce      []byte
+	ChunkSize  uint32
+	Recipients []Recipient
+}
+
+//KDFName is an exportable FUNCTION
+// KDFName returns a human readable name for a KDF ID
+func KDFName(id uint8) string {
+
+	switch id {
+	case KDFNone:
+		return "none (raw key)"
+	case KDFPBKDF2SHA256:
+		return "PBKDF2-SHA256"
+	case KDFArgon2id:
+		return "Argon2id"
+	case KDFScrypt:
+		return "scrypt"
+	}
+
+	return fmt.Sprintf("unknown (%d)", id)
+}
+
+//MarshalBinary is an exportable method
+// MarshalBinary encodes the header as described at the top of this file
+func (h *Header) MarshalBinary() ([]byte, error) {
+
+	if len(h.Salt) > 255 || len(h.Nonce) > 255 {
+		return nil, errors.New("Error: Header salt or nonce is too long")
+	}
+
+	out := make([]byte, 0, headerFixedSize+len(h.Salt)+len(h.Nonce))
+	out = append(out, ContainerMagic...)
+	out = append(out, h.Version, h.Cipher, h.KDF)
+	out = binary.BigEndian.AppendUint32(out, h.KDFParams.Iterations)
+	out = binary.BigEndian.AppendUint32(out, h.KDFParams.Memory)
+	out = binary.BigEndian.AppendUint32(out, h.KDFParams.Parallelism)
+	out = append(out, uint8(len(h.Salt)))
+	out = append(out, h.Salt...)
+	out = append(out, uint8(len(h.Nonce)))
+	out = append(out, h.Nonce...)
+	out = binary.BigEndian.AppendUint32(out, h.ChunkSize)
+	if h.Version < 2 {
+		if len(h.Recipients) > 0 {
+			return nil, errors.New("Error: Version 1 headers cannot hold recipients")
+		}
+		return out, nil
+	}
+
+	if len(h.Recipients) > 255 {
+		return nil, errors.New("Error: Too many recipients; at most 255 are supported")
+	}
+	out = append(out, uint8(len(h.Recipients)))
+	for _, r := range h.Recipients {
+		if len(r.WrappedKey) > 65535 {
+			return nil, errors.New("Error: Wrapped key is too long")
+		}
+		out = append(out, r.Type)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(r.WrappedKey)))
+		out = append(out, r.WrappedKey...)
+	}
+
+	return out, nil
+}
+
+//ReadHeader is an exportable FUNCTION
+/*
+*  ReadHeader reads and parses a container header from r. The raw header BYTES are also
+*  returned as they are needed to authenticate the payload. If r does not begin with
+*  ContainerMagic, ErrNotContainer is returned; callers that wish to fall back to the
+*  legacy (IV || ciphertext) format should Peek at the input first.
+ */
+func ReadHeader(r io.Reader) (*Header, []byte, error) {
+
+	var (
+		h   Header
+		raw []byte
+	)
+
+	swap := make([]byte, len(ContainerMagic)+3+12+1)
+	_, err := io.ReadFull(r, swap)
+	if err != nil {
+		return nil, nil, ErrNotContainer
+	}
+	if !bytes.Equal(swap[:len(ContainerMagic)], ContainerMagic) {
+		return nil, nil, ErrNotContainer
+	}
+	raw = append(raw, swap...)
+	swap = swap[len(ContainerMagic):]
+
+	h.Version, h.Cipher, h.KDF = swap[0], swap[1], swap[2]
+	if h.Version == 0 || h.Version > ContainerVersion {
+		return nil, nil, fmt.Errorf("Error: Unsupported container version %d", h.Version)
+	}
+	h.KDFParams.Iterations = binary.BigEndian.Uint32(swap[3:7])
+	h.KDFParams.Memory = binary.BigEndian.Uint32(swap[7:11])
+	h.KDFParams.Parallelism = binary.BigEndian.Uint32(swap[11:15])
+
+	// Salt, nonce (each with a one BYTE length), then the chunk size
+	h.Salt = make([]byte, swap[15])
+	swap = make([]byte, len(h.Salt)+1)
+	_, err = io.ReadFull(r, swap)
+	if err != nil {
+		return nil, nil, errors.New("Error: Container header is truncated")
+	}
+	raw = append(raw, swap...)
+	copy(h.Salt, swap)
+
+	h.Nonce = make([]byte, swap[len(swap)-1])
+	swap = make([]byte, len(h.Nonce)+4)
+	_, err = io.ReadFull(r, swap)
+	if err != nil {
+		return nil, nil, errors.New("Error: Container header is truncated")
+	}
+	raw = append(raw, swap...)
+	copy(h.Nonce, swap)
+	h.ChunkSize = binary.BigEndian.Uint32(swap[len(h.Nonce):])
+	if h.Version < 2 {
+		return &h, raw, nil
+	}
+
+	// Recipients (version 2); a count followed by type || length || wrapped key
+	swap = make([]byte, 1)
+	_, err = io.ReadFull(r, swap)
+	if err != nil {
+		return nil, nil, errors.New("Error: Container header is truncated")
+	}
+	raw = append(raw, swap...)
+	h.Recipients = make([]Recipient, swap[0])
+	for i := range h.Recipients {
+		swap = make([]byte, 3)
+		_, err = io.ReadFull(r, swap)
+		if err != nil {
+			return nil, nil, errors.New("Error: Container header is truncated")
+		}
+		raw = append(raw, swap...)
+		h.Recipients[i].Type = swap[0]
+		h.Recipients[i].WrappedKey = make([]byte, binary.BigEndian.Uint16(swap[1:]))
+		_, err = io.ReadFull(r, h.Recipients[i].WrappedKey)
+		if err != nil {
+			return nil, nil, errors.New("Error: Container header is truncated")
+		}
+		raw = append(raw, h.Recipients[i].WrappedKey...)
+	}
+
+	return &h, raw, nil
+}
+
+//String is an exportable method
+// String presents the header in a human readable form; used by 'foil aes inspect'
+func (h *Header) String() string {
+
+	var out bytes.Buffer
+
+	fmt.Fprintf(&out, "Format version : %d\n", h.Version)
+	fmt.Fprintf(&out, "Cipher         : %s\n", CipherName(h.Cipher))
+	fmt.Fprintf(&out, "KDF            : %s\n", KDFName(h.KDF))
+	switch h.KDF {
+	case KDFPBKDF2SHA256:
+		fmt.Fprintf(&out, "KDF iterations : %d\n", h.KDFParams.Iterations)
+	case KDFArgon2id:
+		fmt.Fprintf(&out, "KDF time       : %d\n", h.KDFParams.Iterations)
+		fmt.Fprintf(&out, "KDF memory     : %d KiB\n", h.KDFParams.Memory)
+		fmt.Fprintf(&out, "KDF parallelism: %d\n", h.KDFParams.Parallelism)
+	case KDFScrypt:
+		fmt.Fprintf(&out, "KDF cost (N)   : %d\n", h.KDFParams.Iterations)
+		fmt.Fprintf(&out, "KDF block (r)  : %d\n", h.KDFParams.Memory)
+		fmt.Fprintf(&out, "KDF parallel(p): %d\n", h.KDFParams.Parallelism)
+	}
+	if h.KDF != KDFNone {
+		fmt.Fprintf(&out, "Salt (hex)     : %x\n", h.Salt)
+	}
+	fmt.Fprintf(&out, "Nonce (hex)    : %x\n", h.Nonce)
+	if h.ChunkSize == 0 {
+		fmt.Fprintf(&out, "Payload        : single sealed payload\n")
+	} else {
+		fmt.Fprintf(&out, "Payload        : STREAM segments of %d bytes\n", h.ChunkSize)
+	}
+	for i, r := range h.Recipients {
+		fmt.Fprintf(&out, "Recipient %-5d: %s (%d byte wrapped key)\n", i+1, RecipientName(r.Type), len(r.WrappedKey))
+	}
+
+	return out.String()
+}
+
+//NewHeader is an exportable FUNCTION
+/*
+*  NewHeader builds a header for a new container and generates a random nonce (chunkSize
+*  of 0) or nonce prefix (STREAM) appropriate for the cipher. The caller fills in the KDF.
+ */
+func NewHeader(cipherID uint8, chunkSize int, verbose bool) (*Header, error) {
+
+	var (
+		h   Header
+		err error
+	)
+
+	if chunkSize < 0 || chunkSize > MaxStreamChunkSize {
+		return nil, fmt.Errorf("Error: Stream chunk size must be between 1 and %d bytes", MaxStreamChunkSize)
+	}
+
+	h.Version = ContainerVersion
+	h.Cipher = cipherID
+	h.ChunkSize = uint32(chunkSize)
+
+	// A throw-away AEAD is built to learn the nonce size of the cipher
+	aead, err := NewAEAD(cipherID, make([]byte, 32))
+	if err != nil {
+		return nil, err
+	}
+	if chunkSize == 0 {
+		h.Nonce = make([]byte, aead.NonceSize())
+		_, err = io.ReadFull(rand.Reader, h.Nonce)
+	} else {
+		h.Nonce, err = GetStreamNoncePrefix(aead, verbose)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &h, nil
+}
+
+// containerAdata joins the raw header and the user's ADATA into the associated data
+func containerAdata(rawHeader []byte, adata *string) []byte {
+
+	out := append([]byte{}, rawHeader...)
+	if adata != nil {
+		out = append(out, []byte(*adata)...)
+	}
+
+	return out
+}
+
+//SetNonce is an exportable method
+/*
+*  SetNonce replaces the random nonce of a single payload header with a caller supplied
+*  nonce. With AES-256-GCM-SIV a repeated nonce reveals only whether two messages were
+*  equal; with the other ciphers it destroys confidentiality and authenticity.
+ */
+func (h *Header) SetNonce(nonce []byte) error {
+
+	if h.ChunkSize != 0 {
+		return errors.New("Error: A nonce may only be supplied for a single sealed payload (not --stream)")
+	}
+	aead, err := NewAEAD(h.Cipher, make([]byte, 32))
+	if err != nil {
+		return err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return fmt.Errorf("Error: %s requires a %d BYTE nonce", CipherName(h.Cipher), aead.NonceSize())
+	}
+	h.Nonce = append([]byte{}, nonce...)
+
+	return nil
+}
+
+//ContainerEncrypt is an exportable FUNCTION
+/*
+*  ContainerEncrypt writes the encoded header to dst followed by the payload sealed with
+*  key. If h.ChunkSize is 0 the ENTIRE input is read into memory and sealed at once (and
+*  is subject to MaxFileSize); otherwise the input is sealed as STREAM segments.
+ */
+func ContainerEncrypt(key []byte, h *Header, adata *string, dst io.Writer, src io.Reader, verbose bool) error {
+
+	rawHeader, err := h.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	aead, err := NewAEAD(h.Cipher, key)
+	if err != nil {
+		return err
+	}
+	if verbose {
+		fmt.Fprintf(Messages, "ContainerEncrypt - Header (hex): %x\n", rawHeader)
+		fmt.Fprintf(Messages, "SECRET - ContainerEncrypt - The key used was (hex): %x\n", key)
+	}
+
+	_, err = dst.Write(rawHeader)
+	if err != nil {
+		return fmt.Errorf("Error: Writing container header: %v", err)
+	}
+
+	if h.ChunkSize > 0 {
+		return StreamEncrypt(aead, h.Nonce, containerAdata(rawHeader, adata), int(h.ChunkSize), dst, src, verbose)
+	}
+
+	if len(h.Nonce) != aead.NonceSize() {
+		return errors.New("Error: Container nonce is of the wrong length for the cipher")
+	}
+	inputText, err := ioutil.ReadAll(io.LimitReader(src, MaxFileSize+1))
+	if err != nil {
+		return fmt.Errorf("Error: Reading input: %v", err)
+	}
+	if len(inputText) > MaxFileSize {
+		return errors.New("Error: Input larger than 64GB; use --stream")
+	}
+	_, err = dst.Write(aead.Seal(nil, h.Nonce, inputText, containerAdata(rawHeader, adata)))
+	if err != nil {
+		return fmt.Errorf("Error: Writing container payload: %v", err)
+	}
+
+	return nil
+}
+
+//ContainerDecrypt is an exportable FUNCTION
+/*
+*  ContainerDecrypt opens the payload that follows a header previously parsed with
+*  ReadHeader. src must be positioned immediately after the header.
+ */
+func ContainerDecrypt(key []byte, h *Header, rawHeader []byte, adata *string, dst io.Writer, src io.Reader, verbose bool) error {
+
+	aead, err := NewAEAD(h.Cipher, key)
+	if err != nil {
+		return err
+	}
+	if verbose {
+		fmt.Fprintf(Messages, "ContainerDecrypt - Header (hex): %x\n", rawHeader)
+		fmt.Fprintf(Messages, "SECRET - ContainerDecrypt - The key used was (hex): %x\n", key)
+	}
+
+	if h.ChunkSize > 0 {
+		return StreamDecrypt(aead, h.Nonce, containerAdata(rawHeader, adata), int(h.ChunkSize), dst, src, verbose)
+	}
+
+	if len(h.Nonce) != aead.NonceSize() {
+		return errors.New("Error: Container nonce is of the wrong length for the cipher")
+	}
+	inputText, err := ioutil.ReadAll(src)
+	if err != nil {
+		return fmt.Errorf("Error: Reading input: %v", err)
+	}
+	outputText, err := aead.Open(nil, h.Nonce, inputText, containerAdata(rawHeader, adata))
+	if err != nil {
+		return fmt.Errorf("ContainerDecrypt - There was a decryption error: %v", err)
+	}
+	_, err = dst.Write(outputText)
+	if err != nil {
+		return fmt.Errorf("Error: Writing output: %v", err)
+	}
+
+	return nil
+}
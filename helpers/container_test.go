@@ -0,0 +1,179 @@
+package helpers
+
+import (
+	"bytes"
+	"testing"
+)
+
+/*
+*  Test that a header survives MarshalBinary -> ReadHeader unchanged and that the raw
+*  header BYTES returned by ReadHeader match the encoding.
+ */
+func TestHeaderRoundTrip(t *testing.T) {
+
+	var (
+		h *Header
+	)
+
+	h, err := NewHeader(CipherAES256GCM, StreamChunkSize, false)
+	if err != nil {
+		t.Fatalf("FAIL - NewHeader: %v", err)
+	}
+	h.KDF = KDFPBKDF2SHA256
+	h.KDFParams = KDFParams{Iterations: 1000, Memory: 2, Parallelism: 3}
+	h.Salt = []byte("sixteen byte salt")
+
+	encoded, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatalf("FAIL - MarshalBinary: %v", err)
+	}
+	parsed, raw, err := ReadHeader(bytes.NewReader(append(encoded, []byte("payload")...)))
+	if err != nil {
+		t.Fatalf("FAIL - ReadHeader: %v", err)
+	}
+	if !bytes.Equal(raw, encoded) {
+		t.Errorf("FAIL - Raw header BYTES do not match the encoding")
+	}
+	if parsed.Version != ContainerVersion || parsed.Cipher != h.Cipher || parsed.KDF != h.KDF ||
+		parsed.KDFParams != h.KDFParams || parsed.ChunkSize != h.ChunkSize ||
+		!bytes.Equal(parsed.Salt, h.Salt) || !bytes.Equal(parsed.Nonce, h.Nonce) {
+		t.Errorf("FAIL - Header before and after encoding are not equivalent:\n%s\n%s", h, parsed)
+	}
+
+	// Legacy (IV || ciphertext) input must be recognized as not being a container
+	_, _, err = ReadHeader(bytes.NewReader([]byte("0123456789ab legacy ciphertext")))
+	if err != ErrNotContainer {
+		t.Errorf("FAIL - Expected ErrNotContainer; received: %v", err)
+	}
+}
+
+/*
+*  Test ContainerEncrypt/ContainerDecrypt for every cipher and both payload types and
+*  ensure that a modified header is detected.
+ */
+func TestContainerEncDec(t *testing.T) {
+
+	var (
+		ciphertext bytes.Buffer
+		plaintext  bytes.Buffer
+	)
+
+	key := make([]byte, 32)
+	GetAESRandomBytes(key, false)
+	adata := "I love encryption"
+	input := bytes.Repeat([]byte("Attack at dawn! "), 100)
+
+	for _, cipherID := range []uint8{CipherAES256GCM, CipherChaCha20Poly1305, CipherXChaCha20Poly1305, CipherAES256GCMSIV} {
+		for _, size := range []int{0, 64} {
+			ciphertext.Reset()
+			plaintext.Reset()
+
+			h, err := NewHeader(cipherID, size, false)
+			if err != nil {
+				t.Fatalf("FAIL - NewHeader: %v", err)
+			}
+			err = ContainerEncrypt(key, h, &adata, &ciphertext, bytes.NewReader(input), false)
+			if err != nil {
+				t.Fatalf("FAIL - ContainerEncrypt: %v", err)
+			}
+			sealed := append([]byte{}, ciphertext.Bytes()...)
+
+			parsed, raw, err := ReadHeader(&ciphertext)
+			if err != nil {
+				t.Fatalf("FAIL - ReadHeader: %v", err)
+			}
+			err = ContainerDecrypt(key, parsed, raw, &adata, &plaintext, &ciphertext, false)
+			if err != nil {
+				t.Errorf("FAIL - ContainerDecrypt (%s, chunk size %d): %v", CipherName(cipherID), size, err)
+			}
+			if !bytes.Equal(plaintext.Bytes(), input) {
+				t.Errorf("FAIL - plaintext before and after container enc/dec are not equivalent")
+			}
+
+			// The wrong ADATA must fail authentication
+			wrongAdata := "I love decryption"
+			src := bytes.NewReader(sealed)
+			parsed, raw, _ = ReadHeader(src)
+			err = ContainerDecrypt(key, parsed, raw, &wrongAdata, &bytes.Buffer{}, src, false)
+			if err == nil {
+				t.Errorf("FAIL - Wrong ADATA was not detected (%s, chunk size %d)", CipherName(cipherID), size)
+			}
+
+			// Flip a bit in the KDF parameters; the header is authenticated so this must fail
+			sealed[8] ^= 0x01
+			src = bytes.NewReader(sealed)
+			parsed, raw, err = ReadHeader(src)
+			if err != nil {
+				t.Fatalf("FAIL - ReadHeader: %v", err)
+			}
+			err = ContainerDecrypt(key, parsed, raw, &adata, &bytes.Buffer{}, src, false)
+			if err == nil {
+				t.Errorf("FAIL - Modified header was not detected (%s, chunk size %d)", CipherName(cipherID), size)
+			}
+		}
+	}
+}
+
+/*
+*  Test that password protected headers receive a fresh random salt and that the key
+*  derived on decryption uses the salt and iterations stored in the header.
+ */
+func TestContainerPassword(t *testing.T) {
+
+	var (
+		ciphertext bytes.Buffer
+		plaintext  bytes.Buffer
+	)
+
+	password := "LegitPassword2"
+	wrongPassword := "LegitPassword3"
+	operationEnc := "encrypt"
+	operationDec := "decrypt"
+	emptyKey := ""
+	input := []byte("Attack at dawn!")
+
+	h1, _ := NewHeader(CipherAES256GCM, 0, false)
+	h2, _ := NewHeader(CipherAES256GCM, 0, false)
+	pbkdf2Params := KDFParams{Iterations: 1000}
+	if h1.SetPasswordKDF(KDFPBKDF2SHA256, pbkdf2Params, false) != nil || h2.SetPasswordKDF(KDFPBKDF2SHA256, pbkdf2Params, false) != nil {
+		t.Fatalf("FAIL - SetPasswordKDF failed")
+	}
+	if len(h1.Salt) != SaltSize || bytes.Equal(h1.Salt, h2.Salt) {
+		t.Errorf("FAIL - Expected distinct random salts of %d bytes", SaltSize)
+	}
+	if h1.SetPasswordKDF(KDFScrypt, KDFParams{Iterations: 1000}, false) == nil {
+		t.Errorf("FAIL - Expected a scrypt N that is not a power of two to be rejected")
+	}
+
+	key, ok := CliHeaderKeyLogic(h1, &password, &emptyKey, &operationEnc, false)
+	if !ok {
+		t.Fatalf("FAIL - CliHeaderKeyLogic failed on encryption")
+	}
+	err := ContainerEncrypt(key, h1, nil, &ciphertext, bytes.NewReader(input), false)
+	if err != nil {
+		t.Fatalf("FAIL - ContainerEncrypt: %v", err)
+	}
+	sealed := append([]byte{}, ciphertext.Bytes()...)
+
+	parsed, raw, err := ReadHeader(&ciphertext)
+	if err != nil {
+		t.Fatalf("FAIL - ReadHeader: %v", err)
+	}
+	if parsed.KDFParams.Iterations != 1000 || !bytes.Equal(parsed.Salt, h1.Salt) {
+		t.Errorf("FAIL - Salt or iterations were not stored in the header")
+	}
+	key, _ = CliHeaderKeyLogic(parsed, &password, &emptyKey, &operationDec, false)
+	err = ContainerDecrypt(key, parsed, raw, nil, &plaintext, &ciphertext, false)
+	if err != nil || !bytes.Equal(plaintext.Bytes(), input) {
+		t.Errorf("FAIL - Password container did not decrypt: %v", err)
+	}
+
+	// The wrong password must fail authentication
+	src := bytes.NewReader(sealed)
+	parsed, raw, _ = ReadHeader(src)
+	key, _ = CliHeaderKeyLogic(parsed, &wrongPassword, &emptyKey, &operationDec, false)
+	err = ContainerDecrypt(key, parsed, raw, nil, &bytes.Buffer{}, src, false)
+	if err == nil {
+		t.Errorf("FAIL - Wrong password was not detected")
+	}
+}
@@ -0,0 +1,231 @@
+/*
+*  Envelope (key-encryption-key) support for foil containers. Rather than a password or a
+*  hex key, the container is encrypted with a random 256-bit data key and the data key is
+*  wrapped for one or more recipients with their public keys. Each wrapped key is stored
+*  in the (authenticated) container header. The holder of a matching private key unwraps
+*  the data key by trial decryption; no key identifiers are stored.
+*
+*	RSA-OAEP : RSA-OAEP with SHA-256 and the label "foil data key"
+*	ECIES    : ephemeral ECDH on the curve of the recipient (P-256, P-384, P-521, or
+*	           X25519), HKDF-SHA256 over the shared
+*	           secret (salt = ephemeral public key || recipient public key), and
+*	           AES-256-GCM with a zero nonce (the wrap key is used once). The wrapped key
+*	           is the ephemeral public key (uncompressed on NIST curves) || sealed data key.
+ */
+
+package helpers
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Recipient types stored in the container header
+const (
+	RecipientRSAOAEP uint8 = 1
+	RecipientECIES   uint8 = 2
+)
+
+// The OAEP label and HKDF info used when wrapping data keys
+var (
+	oaepLabel = []byte("foil data key")
+	eciesInfo = []byte("foil ECIES data key wrap")
+)
+
+//Recipient is an exportable struct
+// Recipient holds a data key wrapped for a single public key
+type Recipient struct {
+	Type       uint8
+	WrappedKey []byte
+}
+
+//RecipientName is an exportable FUNCTION
+// RecipientName returns a human readable name for a recipient type
+func RecipientName(id uint8) string {
+
+	switch id {
+	case RecipientRSAOAEP:
+		return "RSA-OAEP-SHA256"
+	case RecipientECIES:
+		return "ECIES (ECDH, HKDF-SHA256, AES-256-GCM)"
+	}
+
+	return fmt.Sprintf("unknown (%d)", id)
+}
+
+// eciesWrapKey derives the single-use AES-256-GCM instance that wraps the data key
+func eciesWrapKey(shared, ephemeral, recipient []byte) (cipher.AEAD, error) {
+
+	wrapKey := make([]byte, 32)
+	salt := append(append([]byte{}, ephemeral...), recipient...)
+	_, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, eciesInfo), wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	aesBlock, err := aes.NewCipher(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(aesBlock)
+}
+
+//WrapKey is an exportable FUNCTION
+/*
+*  WrapKey wraps dataKey for pub, an *rsa.PublicKey (RSA-OAEP), an *ecdsa.PublicKey on a NIST
+*  curve (ECIES), or an X25519 *ecdh.PublicKey (ECIES).
+ */
+func WrapKey(dataKey []byte, pub crypto.PublicKey, verbose bool) (Recipient, error) {
+
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, dataKey, oaepLabel)
+		if err != nil {
+			return Recipient{}, fmt.Errorf("Error: RSA-OAEP: %v", err)
+		}
+		return Recipient{Type: RecipientRSAOAEP, WrappedKey: wrapped}, nil
+	case *ecdsa.PublicKey:
+		recipient, err := pub.ECDH()
+		if err != nil {
+			return Recipient{}, fmt.Errorf("Error: ECIES: %v", err)
+		}
+		return eciesWrap(dataKey, recipient, verbose)
+	case *ecdh.PublicKey:
+		return eciesWrap(dataKey, pub, verbose)
+	}
+
+	return Recipient{}, fmt.Errorf("Error: Unsupported recipient key type %T; use an RSA, EC, or X25519 public key", pub)
+}
+
+// eciesWrap wraps dataKey for an ecdh public key with a fresh ephemeral key on the same curve
+func eciesWrap(dataKey []byte, recipient *ecdh.PublicKey, verbose bool) (Recipient, error) {
+
+	ephemeral, err := recipient.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return Recipient{}, fmt.Errorf("Error: ECIES: %v", err)
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return Recipient{}, fmt.Errorf("Error: ECIES: %v", err)
+	}
+	aead, err := eciesWrapKey(shared, ephemeral.PublicKey().Bytes(), recipient.Bytes())
+	if err != nil {
+		return Recipient{}, fmt.Errorf("Error: ECIES: %v", err)
+	}
+	if verbose {
+		fmt.Fprintf(Messages, "WrapKey - ECIES ephemeral public key (hex): %x\n", ephemeral.PublicKey().Bytes())
+	}
+	wrapped := aead.Seal(ephemeral.PublicKey().Bytes(), make([]byte, aead.NonceSize()), dataKey, nil)
+
+	return Recipient{Type: RecipientECIES, WrappedKey: wrapped}, nil
+}
+
+//UnwrapKey is an exportable FUNCTION
+/*
+*  UnwrapKey recovers the data key wrapped in r with priv, an *rsa.PrivateKey, an
+*  *ecdsa.PrivateKey, or an X25519 *ecdh.PrivateKey. An error is returned if r was not
+*  wrapped for priv.
+ */
+func UnwrapKey(r Recipient, priv crypto.PrivateKey) ([]byte, error) {
+
+	switch priv := priv.(type) {
+	case *rsa.PrivateKey:
+		if r.Type != RecipientRSAOAEP {
+			break
+		}
+		return rsa.DecryptOAEP(sha256.New(), nil, priv, r.WrappedKey, oaepLabel)
+	case *ecdsa.PrivateKey:
+		if r.Type != RecipientECIES {
+			break
+		}
+		private, err := priv.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		return eciesUnwrap(r, private)
+	case *ecdh.PrivateKey:
+		if r.Type != RecipientECIES {
+			break
+		}
+		return eciesUnwrap(r, priv)
+	default:
+		return nil, fmt.Errorf("Error: Unsupported private key type %T; use an RSA, EC, or X25519 private key", priv)
+	}
+
+	return nil, errors.New("Error: Recipient was not wrapped for this type of key")
+}
+
+// eciesUnwrap recovers the data key of an ECIES recipient with an ecdh private key
+func eciesUnwrap(r Recipient, private *ecdh.PrivateKey) ([]byte, error) {
+
+	pointSize := len(private.PublicKey().Bytes())
+	if len(r.WrappedKey) < pointSize {
+		return nil, errors.New("Error: ECIES wrapped key is too short")
+	}
+	ephemeral, err := private.Curve().NewPublicKey(r.WrappedKey[:pointSize])
+	if err != nil {
+		return nil, err
+	}
+	shared, err := private.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := eciesWrapKey(shared, ephemeral.Bytes(), private.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Open(nil, make([]byte, aead.NonceSize()), r.WrappedKey[pointSize:], nil)
+}
+
+//AddRecipient is an exportable method
+// AddRecipient wraps dataKey for pub and appends it to the recipients of the header
+func (h *Header) AddRecipient(dataKey []byte, pub crypto.PublicKey, verbose bool) error {
+
+	if h.Version < 2 {
+		return errors.New("Error: Version 1 headers cannot hold recipients")
+	} else if h.KDF != KDFNone {
+		return errors.New("Error: A container may be protected by a password or by recipients; not both")
+	}
+	r, err := WrapKey(dataKey, pub, verbose)
+	if err != nil {
+		return err
+	}
+	h.Recipients = append(h.Recipients, r)
+
+	return nil
+}
+
+//OpenRecipients is an exportable method
+/*
+*  OpenRecipients tries priv against every recipient in the header and returns the data
+*  key from the first that unwraps (trial decryption).
+ */
+func (h *Header) OpenRecipients(priv crypto.PrivateKey, verbose bool) ([]byte, error) {
+
+	if len(h.Recipients) == 0 {
+		return nil, errors.New("Error: The container has no recipients")
+	}
+	for i, r := range h.Recipients {
+		dataKey, err := UnwrapKey(r, priv)
+		if err == nil && len(dataKey) == 32 {
+			if verbose {
+				fmt.Fprintf(Messages, "OpenRecipients - Unwrapped the data key of recipient %d\n", i+1)
+			}
+			return dataKey, nil
+		}
+	}
+
+	return nil, errors.New("Error: The private key does not match any recipient of the container")
+}
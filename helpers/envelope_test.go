@@ -0,0 +1,137 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+/*
+*  Test that a container encrypted for RSA, EC, and X25519 recipients can be opened by each of
+*  their private keys and by no other key.
+ */
+func TestEnvelopeRecipients(t *testing.T) {
+
+	var (
+		ciphertext bytes.Buffer
+	)
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	x25519Key, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	input := []byte("Attack at dawn!")
+
+	dataKey := make([]byte, 32)
+	GetAESRandomBytes(dataKey, false)
+	h, _ := NewHeader(CipherAES256GCM, 0, false)
+	for _, pub := range []crypto.PublicKey{&rsaKey.PublicKey, &p256Key.PublicKey, &p384Key.PublicKey, x25519Key.PublicKey()} {
+		err := h.AddRecipient(dataKey, pub, false)
+		if err != nil {
+			t.Fatalf("FAIL - AddRecipient: %v", err)
+		}
+	}
+	err := ContainerEncrypt(dataKey, h, nil, &ciphertext, bytes.NewReader(input), false)
+	if err != nil {
+		t.Fatalf("FAIL - ContainerEncrypt: %v", err)
+	}
+	sealed := ciphertext.Bytes()
+
+	for _, priv := range []crypto.PrivateKey{rsaKey, p256Key, p384Key, x25519Key} {
+		plaintext := &bytes.Buffer{}
+		src := bytes.NewReader(sealed)
+		parsed, raw, err := ReadHeader(src)
+		if err != nil || len(parsed.Recipients) != 4 {
+			t.Fatalf("FAIL - ReadHeader did not return 4 recipients: %v", err)
+		}
+		key, err := parsed.OpenRecipients(priv, false)
+		if err != nil {
+			t.Errorf("FAIL - OpenRecipients (%T): %v", priv, err)
+			continue
+		}
+		err = ContainerDecrypt(key, parsed, raw, nil, plaintext, src, false)
+		if err != nil || !bytes.Equal(plaintext.Bytes(), input) {
+			t.Errorf("FAIL - Envelope container did not decrypt (%T): %v", priv, err)
+		}
+	}
+
+	parsed, _, _ := ReadHeader(bytes.NewReader(sealed))
+	_, err = parsed.OpenRecipients(otherKey, false)
+	if err == nil {
+		t.Errorf("FAIL - A key that is not a recipient unwrapped the data key")
+	}
+
+	// Recipients and passwords are mutually exclusive
+	h, _ = NewHeader(CipherAES256GCM, 0, false)
+	h.SetPasswordKDF(KDFPBKDF2SHA256, KDFParams{Iterations: 1000}, false)
+	if h.AddRecipient(dataKey, &p256Key.PublicKey, false) == nil {
+		t.Errorf("FAIL - A password header accepted a recipient")
+	}
+}
+
+/*
+*  Test that version 1 headers (without recipients) are still read and that a recipient
+*  list survives MarshalBinary -> ReadHeader
+ */
+func TestHeaderVersions(t *testing.T) {
+
+	h, _ := NewHeader(CipherAES256GCM, 0, false)
+	h.Version = 1
+	encoded, _ := h.MarshalBinary()
+	parsed, raw, err := ReadHeader(bytes.NewReader(encoded))
+	if err != nil || parsed.Version != 1 || !bytes.Equal(raw, encoded) {
+		t.Errorf("FAIL - Version 1 header was not read: %v", err)
+	}
+	h.Recipients = []Recipient{{Type: RecipientECIES, WrappedKey: []byte("wrapped")}}
+	_, err = h.MarshalBinary()
+	if err == nil {
+		t.Errorf("FAIL - A version 1 header was encoded with recipients")
+	}
+
+	h.Version = ContainerVersion
+	h.Recipients = append(h.Recipients, Recipient{Type: RecipientRSAOAEP, WrappedKey: bytes.Repeat([]byte{7}, 300)})
+	encoded, _ = h.MarshalBinary()
+	parsed, _, err = ReadHeader(bytes.NewReader(encoded))
+	if err != nil || len(parsed.Recipients) != 2 || parsed.Recipients[1].Type != RecipientRSAOAEP ||
+		!bytes.Equal(parsed.Recipients[1].WrappedKey, h.Recipients[1].WrappedKey) {
+		t.Errorf("FAIL - Recipients before and after encoding are not equivalent: %v", err)
+	}
+	_, _, err = ReadHeader(bytes.NewReader(encoded[:len(encoded)-1]))
+	if err == nil {
+		t.Errorf("FAIL - A truncated recipient was not detected")
+	}
+}
+
+/*
+*  Test that ReadKeyFile accepts raw and hex keys and rejects anything else
+ */
+func TestReadKeyFile(t *testing.T) {
+
+	key := make([]byte, 32)
+	GetAESRandomBytes(key, false)
+	file := "keyfile_test.out"
+	defer os.Remove(file)
+
+	for _, contents := range [][]byte{key, []byte(hex.EncodeToString(key) + "\n")} {
+		ioutil.WriteFile(file, contents, 0600)
+		read, err := ReadKeyFile(file, false)
+		if err != nil || !bytes.Equal(read, key) {
+			t.Errorf("FAIL - ReadKeyFile: %v", err)
+		}
+	}
+
+	ioutil.WriteFile(file, []byte(hex.EncodeToString(key[:16])), 0600)
+	_, err := ReadKeyFile(file, false)
+	if err == nil {
+		t.Errorf("FAIL - ReadKeyFile accepted a 128-bit key")
+	}
+}
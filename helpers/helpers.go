@@ -13,11 +13,59 @@ package helpers
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 )
 
+//StdIOPath is an exportable CONSTANT
+// Giving StdIOPath as --in or --out reads raw BYTES from StdIn or writes raw BYTES to StdOut
+const StdIOPath = "-"
+
+//StdOut is an exportable VARIABLE
+// StdOut receives output (keys, ciphertext, beta); tests may replace it to capture output
+var StdOut io.Writer = os.Stdout
+
+//Messages is an exportable VARIABLE
+/*
+*  Messages receives every human readable message (warnings, verbose output, prompts). It is
+*  StdOut unless PipeMessagesToStdErr has been called; tests may replace it to capture messages.
+ */
+var Messages io.Writer = os.Stdout
+
+//PipeMessagesToStdErr is an exportable FUNCTION
+/*
+*  PipeMessagesToStdErr sends every human readable message to StdErr so that raw output
+*  written to StdOut (--out -) can be safely piped. os.Stdout itself is left alone.
+ */
+func PipeMessagesToStdErr() {
+	Messages = os.Stderr
+}
+
+//ReadInput is an exportable FUNCTION
+// ReadInput reads the ENTIRE file at path into memory; StdIOPath reads raw BYTES from StdIn
+func ReadInput(path string) ([]byte, error) {
+
+	var (
+		inputText []byte
+		err       error
+	)
+
+	if path == StdIOPath {
+		inputText, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		inputText, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error: Reading input: %v", err)
+	}
+
+	return inputText, nil
+}
+
 //CliInputFileLogic is an exportable FUNCTION
 /*
 *  cliInputFileLogic determines from where the input text will be drawn. The logic that
@@ -32,6 +80,7 @@ func CliInputFileLogic(cliStdin *string, cliFileSource *string, operation *strin
 		err       error
 		iv        []byte
 		inputText []byte
+		fileStats os.FileInfo
 	)
 
 	/*
@@ -44,25 +93,38 @@ func CliInputFileLogic(cliStdin *string, cliFileSource *string, operation *strin
 	 */
 	if len(*cliStdin) > 0 {
 		inputText = []byte(*cliStdin)
+	} else if *cliFileSource == StdIOPath {
+		inputText, err = ioutil.ReadAll(io.LimitReader(os.Stdin, MaxFileSize+1))
+		if err != nil {
+			fmt.Fprintln(Messages, "Error: Reading StdIn\nError:", err)
+			return nil, []byte{}, false
+		} else if len(inputText) > MaxFileSize {
+			fmt.Fprintln(Messages, "Error: Input larger than 64GB; Input must be smaller than 64GB or use --stream")
+			return nil, []byte{}, false
+		}
 	} else if len(*cliFileSource) > 0 {
-		fileStats, _ := os.Stat(*cliFileSource)
+		fileStats, err = os.Stat(*cliFileSource)
+		if err != nil {
+			fmt.Fprintln(Messages, "Error: Reading file:", *cliFileSource, "\nError:", err)
+			return nil, []byte{}, false
+		}
 		if fileStats.Size() > MaxFileSize {
-			fmt.Println("Error: Source file larger than 64GB; File must be smaller than 64GB")
+			fmt.Fprintln(Messages, "Error: Source file larger than 64GB; File must be smaller than 64GB or use --stream")
 			return nil, []byte{}, false
 		}
 		inputText, err = ioutil.ReadFile(*cliFileSource)
 		if err != nil {
-			fmt.Println("Error: Reading file:", *cliFileSource, "\nError:", err)
+			fmt.Fprintln(Messages, "Error: Reading file:", *cliFileSource, "\nError:", err)
 			return nil, []byte{}, false
 		}
 	} else {
-		fmt.Println("Error: Unknown error occured in cliInputFileLogic")
+		fmt.Fprintln(Messages, "Error: Unknown error occured in cliInputFileLogic")
 		return nil, []byte{}, false
 	}
 
 	// Check to determine if an empty file was passed to the application. If so, Warn the user.
 	if inputText == nil || len(inputText) == 0 {
-		fmt.Println("WARNING: The input source is of length zero")
+		fmt.Fprintln(Messages, "WARNING: The input source is of length zero")
 	}
 
 	/*
@@ -75,7 +137,7 @@ func CliInputFileLogic(cliStdin *string, cliFileSource *string, operation *strin
 		iv = make([]byte, 12)
 		err := GetAESRandomBytes(iv, verbose)
 		if err != nil {
-			fmt.Println(err)
+			fmt.Fprintln(Messages, err)
 		}
 	} else if *operation == "decrypt" {
 		iv = inputText[:12]
@@ -103,21 +165,27 @@ func CliOutputFileLogic(outputText []byte, cliStdOut *bool, cliFileDestination *
 	 */
 	if *cliStdOut {
 		if *operation == "decrypt" {
-			fmt.Println("Output plaintext:", string(outputText))
+			fmt.Fprintln(Messages, "Output plaintext:", string(outputText))
 		} else if *operation == "encrypt" {
-			fmt.Printf("Output ciphertext (hex): %x\n", outputText)
+			fmt.Fprintf(Messages, "Output ciphertext (hex): %x\n", outputText)
 		} else {
-			fmt.Println("Error: Unknown error in displaying output text to StdIn")
+			fmt.Fprintln(Messages, "Error: Unknown error in displaying output text to StdIn")
+			return false
+		}
+	} else if *cliFileDestination == StdIOPath {
+		_, err := StdOut.Write(outputText)
+		if err != nil {
+			fmt.Fprintln(Messages, "Error in writing output to StdOut:", err)
 			return false
 		}
 	} else if len(*cliFileDestination) > 0 {
 		err := ioutil.WriteFile(*cliFileDestination, outputText, 0644)
 		if err != nil {
-			fmt.Println("Error in saving output to file:", err)
+			fmt.Fprintln(Messages, "Error in saving output to file:", err)
 			return false
 		}
 	} else {
-		fmt.Println("Please specify a destination for d/encryption")
+		fmt.Fprintln(Messages, "Please specify a destination for d/encryption")
 		return false
 	}
 
@@ -125,12 +193,172 @@ func CliOutputFileLogic(outputText []byte, cliStdOut *bool, cliFileDestination *
 	return true
 }
 
+//CliStreamInputLogic is an exportable FUNCTION
+/*
+*  CliStreamInputLogic is the streaming counterpart of CliInputFileLogic. Rather than
+*  reading the ENTIRE input into memory it returns a reader over the input source. If
+*  the -textin flag is used for decryption, the string is expected to be hex. If the
+*  input is StdIOPath, raw BYTES are read from StdIn. The caller must Close() the
+*  returned reader.
+ */
+func CliStreamInputLogic(cliStdin *string, cliFileSource *string, operation *string, verbose bool) (io.ReadCloser, error) {
+
+	if len(*cliStdin) > 0 {
+		if *operation == "decrypt" {
+			return ioutil.NopCloser(hex.NewDecoder(strings.NewReader(*cliStdin))), nil
+		}
+		return ioutil.NopCloser(strings.NewReader(*cliStdin)), nil
+	} else if *cliFileSource == StdIOPath {
+		return ioutil.NopCloser(os.Stdin), nil
+	} else if len(*cliFileSource) > 0 {
+		inputFile, err := os.Open(*cliFileSource)
+		if err != nil {
+			return nil, fmt.Errorf("Error: Reading file: %s\nError: %v", *cliFileSource, err)
+		}
+		if verbose {
+			fmt.Fprintln(Messages, "CliStreamInputLogic - Streaming input from:", *cliFileSource)
+		}
+		return inputFile, nil
+	}
+
+	return nil, errors.New("Error: Unknown error occured in CliStreamInputLogic")
+}
+
+// stdOutWriter wraps writes bound for StdOut; Close() ends the line instead of closing StdOut
+type stdOutWriter struct {
+	io.Writer
+}
+
+func (h stdOutWriter) Close() error {
+	_, err := fmt.Fprintln(StdOut)
+	return err
+}
+
+// rawStdOutWriter writes raw BYTES to StdOut (--out -); Close() does nothing
+type rawStdOutWriter struct {
+	io.Writer
+}
+
+func (h rawStdOutWriter) Close() error {
+	return nil
+}
+
+//CliStreamOutputLogic is an exportable FUNCTION
+/*
+*  CliStreamOutputLogic is the streaming counterpart of CliOutputFileLogic. If the -textout
+*  flag is set, ciphertext is written to StdOut as hex and plaintext is written as is. If
+*  the output is StdIOPath, raw BYTES are written to StdOut. If an output file is given, the
+*  file is created (or truncated) with 0644 permissions. The caller must Close() the
+*  returned writer.
+ */
+func CliStreamOutputLogic(cliStdOut *bool, cliFileDestination *string, operation *string, verbose bool) (io.WriteCloser, error) {
+
+	if *cliStdOut {
+		if *operation == "encrypt" {
+			fmt.Fprint(Messages, "Output ciphertext (hex): ")
+			return stdOutWriter{hex.NewEncoder(StdOut)}, nil
+		} else if *operation == "decrypt" {
+			fmt.Fprint(Messages, "Output plaintext: ")
+			return stdOutWriter{StdOut}, nil
+		}
+		return nil, errors.New("Error: Unknown error in displaying output text to StdOut")
+	} else if *cliFileDestination == StdIOPath {
+		return rawStdOutWriter{StdOut}, nil
+	} else if len(*cliFileDestination) > 0 {
+		outputFile, err := os.OpenFile(*cliFileDestination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("Error in saving output to file: %v", err)
+		}
+		if verbose {
+			fmt.Fprintln(Messages, "CliStreamOutputLogic - Streaming output to:", *cliFileDestination)
+		}
+		return outputFile, nil
+	}
+
+	return nil, errors.New("Please specify a destination for d/encryption")
+}
+
+//CliAdataLogic is an exportable FUNCTION
+/*
+*  CliAdataLogic determines the ADATA (associated data) that the ciphertext is bound to. ADATA
+*  may be given as a string (--adata), read from a file (--adata-file), or given as hex
+*  (--adata-hex); at most one source may be used. The ADATA is returned as a string of raw
+*  BYTES; an empty string means no ADATA.
+ */
+func CliAdataLogic(cliAdata *string, cliAdataFile *string, cliAdataHex *string, verbose bool) (string, error) {
+
+	var (
+		sources   int
+		byteAdata []byte
+		err       error
+	)
+
+	for _, source := range []*string{cliAdata, cliAdataFile, cliAdataHex} {
+		if len(*source) > 0 {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", errors.New("Error: Too many sources for ADATA; select only one of --adata, --adata-file, --adata-hex")
+	}
+
+	if len(*cliAdataFile) > 0 {
+		byteAdata, err = ioutil.ReadFile(*cliAdataFile)
+		if err != nil {
+			return "", fmt.Errorf("Error: Reading ADATA file: %v", err)
+		}
+	} else if len(*cliAdataHex) > 0 {
+		byteAdata, err = hex.DecodeString(*cliAdataHex)
+		if err != nil {
+			return "", errors.New("Error: --adata-hex is not valid hex")
+		}
+	} else {
+		byteAdata = []byte(*cliAdata)
+	}
+
+	if verbose {
+		fmt.Fprintf(Messages, "CliAdataLogic - ADATA (hex): %x\n", byteAdata)
+	}
+
+	return string(byteAdata), nil
+}
+
+//ReadKeyFile is an exportable FUNCTION
+/*
+*  ReadKeyFile reads a 256-bit key from the file at path so that keys need not be given on
+*  the command line (where they leak into shell history and process listings). The file
+*  may hold the 32 raw BYTES of the key or the key as 64 hex digits (surrounding whitespace
+*  ignored). A file that decodes as hex is always read as hex, so a 32 BYTE file of hex
+*  digits is a 128-bit key and is rejected rather than read as raw BYTES.
+ */
+func ReadKeyFile(path string, verbose bool) ([]byte, error) {
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error: Reading key file: %v", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil && len(raw) == 32 {
+		return raw, nil
+	}
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("Error: The key file must hold a 256-bit key as 32 raw BYTES or as hex")
+	}
+	if verbose {
+		fmt.Fprintln(Messages, "ReadKeyFile - Read a hex key from:", path)
+	}
+
+	return key, nil
+}
+
 //CliKeyLogic is an exportable FUNCTION
 /*
 *  cliKeyLogic determines what key the d/encryptor will use; default is RANDOM key. The logic
 *  that checks to ensure mutually exclusive flags are not set exists in CliFlags(). This
 *  function is designed to have a default return of FAIL. Do not want key parsing or generation
-*  to fail silently. Note: This function does yet support salting of hashes.
+*  to fail silently. Note: Passwords are expanded with the legacy parameters (no salt and
+*  LegacyPBKDF2Iterations) so that legacy ciphertext remains decryptable. New containers
+*  store a random salt and work factor in their header; see CliHeaderKeyLogic.
  */
 func CliKeyLogic(cliPassword *string, cliKey *string, cliOperation *string, verbose bool) ([]byte, bool) {
 
@@ -163,20 +391,20 @@ func CliKeyLogic(cliPassword *string, cliKey *string, cliOperation *string, verb
 	 */
 	if len(*cliPassword) > 0 {
 		if len(*cliPassword) < minSecureKeyLength {
-			fmt.Println("Warning: The password supplied has less than 112 bits (As hard as RSA-2048) of security")
+			fmt.Fprintln(Messages, "Warning: The password supplied has less than 112 bits (As hard as RSA-2048) of security")
 		}
-		key = KeyFromPassword(cliPassword, nil, 64, verbose)
+		key = KeyFromPassword(cliPassword, nil, LegacyPBKDF2Iterations, verbose)
 		return key, true
 	} else if len(*cliKey) > 0 {
 		key, err = hex.DecodeString(*cliKey)
 		if err != nil {
-			fmt.Println("Error: There was an error in cliKeyLogic;", err)
+			fmt.Fprintln(Messages, "Error: There was an error in cliKeyLogic;", err)
 			return []byte{}, false
 		} else if len(key) < 32 {
-			fmt.Println("Error: The key provided is of length < 256 bits!")
+			fmt.Fprintln(Messages, "Error: The key provided is of length < 256 bits!")
 			return []byte{}, false
 		} else if len(key) > 32 {
-			fmt.Println("Error: The key provided is of length > 256 bits!")
+			fmt.Fprintln(Messages, "Error: The key provided is of length > 256 bits!")
 			return []byte{}, false
 		}
 		return key, true
@@ -185,16 +413,16 @@ func CliKeyLogic(cliPassword *string, cliKey *string, cliOperation *string, verb
 			key = make([]byte, 32)
 			err := GetAESRandomBytes(key, verbose)
 			if err != nil {
-				fmt.Println(err)
+				fmt.Fprintln(Messages, err)
 			}
-			fmt.Println("WARNING: Random key generated and used!")
-			fmt.Printf("SECRET - Key: %x\n", key)
+			fmt.Fprintln(Messages, "WARNING: Random key generated and used!")
+			fmt.Fprintf(Messages, "SECRET - Key: %x\n", key)
 			return key, true
 		} else if *cliOperation == "decrypt" {
-			fmt.Println("Error: No decyrption key given")
+			fmt.Fprintln(Messages, "Error: No decyrption key given")
 			return []byte{}, false
 		} else {
-			fmt.Println("Error: Unknown error in cliKeyLogic")
+			fmt.Fprintln(Messages, "Error: Unknown error in cliKeyLogic")
 			return []byte{}, false
 		}
 	}
@@ -202,3 +430,34 @@ func CliKeyLogic(cliPassword *string, cliKey *string, cliOperation *string, verb
 	// This section is intentionally logically unreachable given the if/else statements above
 	//return []byte{}, false
 }
+
+//CliHeaderKeyLogic is an exportable FUNCTION
+/*
+*  CliHeaderKeyLogic determines the key used for a foil container. If the header names
+*  a password KDF (PBKDF2, Argon2id, scrypt), the key is derived from the password with
+*  the salt and work factors stored in the header. Otherwise the key is handled by CliKeyLogic (hex key or, for
+*  encryption only, a random key). Like CliKeyLogic, the default return is FAIL.
+ */
+func CliHeaderKeyLogic(h *Header, cliPassword *string, cliKey *string, cliOperation *string, verbose bool) ([]byte, bool) {
+
+	switch h.KDF {
+	case KDFNone:
+		if len(*cliPassword) > 0 {
+			fmt.Fprintln(Messages, "Error: The container was encrypted with a key, not a password")
+			return []byte{}, false
+		}
+		return CliKeyLogic(cliPassword, cliKey, cliOperation, verbose)
+	}
+
+	if len(*cliPassword) == 0 {
+		fmt.Fprintln(Messages, "Error: The container was encrypted with a password; supply --password")
+		return []byte{}, false
+	}
+	key, err := DeriveKey(h.KDF, h.KDFParams, cliPassword, h.Salt, verbose)
+	if err != nil {
+		fmt.Fprintln(Messages, err)
+		return []byte{}, false
+	}
+
+	return key, true
+}
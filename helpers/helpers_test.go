@@ -6,9 +6,14 @@
 package helpers
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"testing"
 )
 
@@ -114,8 +119,150 @@ func TestAESCore(t *testing.T) {
 	}
 }
 
+/*
+*  Test that AESCore binds the ciphertext to ADATA: the output must match a manual GCM seal
+*  with the same ADATA and decryption with missing or different ADATA must fail.
+ */
+func TestAESCoreAdata(t *testing.T) {
+
+	var (
+		aesKey []byte
+		aesIV  []byte
+	)
+
+	password := "LegitPassword2"
+	adata := "record-id: 1999"
+	wrongAdata := "record-id: 2000"
+	emptyAdata := ""
+	plaintext := []byte("Attack at dawn!")
+	aesKey = KeyFromPassword(&password, nil, 64, false)
+	aesIV = aesKey[:12]
+
+	operationEnc := "encrypt"
+	encAESResult, err := AESCore(aesIV, aesKey, &adata, plaintext, &operationEnc, false)
+	if err != nil {
+		t.Fatalf("FAIL - AESCore encryption: %v", err)
+	}
+	aesBlock, _ := aes.NewCipher(aesKey)
+	aesGCM, _ := cipher.NewGCM(aesBlock)
+	if !bytes.Equal(encAESResult[12:], aesGCM.Seal(nil, aesIV, plaintext, []byte(adata))) {
+		t.Errorf("FAIL - AESCore ciphertext was not bound to ADATA")
+	}
+
+	operationDec := "decrypt"
+	decAESResult, err := AESCore(aesIV, aesKey, &adata, encAESResult, &operationDec, false)
+	if err != nil || !bytes.Equal(decAESResult, plaintext) {
+		t.Errorf("FAIL - AESCore decryption with the correct ADATA: %v", err)
+	}
+	for _, bad := range []*string{&wrongAdata, &emptyAdata, nil} {
+		_, err = AESCore(aesIV, aesKey, bad, encAESResult, &operationDec, false)
+		if err == nil {
+			t.Errorf("FAIL - AESCore decryption with the wrong ADATA succeeded")
+		}
+	}
+}
+
+/*
+*  Test that CliAdataLogic reads ADATA from each source and rejects multiple sources
+ */
+func TestCliAdataLogic(t *testing.T) {
+
+	empty := ""
+	text := "I love encryption"
+	hexText := hex.EncodeToString([]byte(text))
+	file := "adata_test.out"
+	err := ioutil.WriteFile(file, []byte(text), 0644)
+	if err != nil {
+		t.Fatalf("FAIL - %v", err)
+	}
+	defer os.Remove(file)
+
+	for _, sources := range [][3]*string{{&text, &empty, &empty}, {&empty, &file, &empty}, {&empty, &empty, &hexText}} {
+		adata, err := CliAdataLogic(sources[0], sources[1], sources[2], false)
+		if err != nil || adata != text {
+			t.Errorf("FAIL - CliAdataLogic returned \"%s\": %v", adata, err)
+		}
+	}
+
+	_, err = CliAdataLogic(&text, &empty, &hexText, false)
+	if err == nil {
+		t.Errorf("FAIL - CliAdataLogic accepted more than one ADATA source")
+	}
+	badHex := "not hex"
+	_, err = CliAdataLogic(&empty, &empty, &badHex, false)
+	if err == nil {
+		t.Errorf("FAIL - CliAdataLogic accepted invalid hex")
+	}
+}
+
+/*
+*  Test that StdIOPath ("-") reads raw BYTES from StdIn and writes raw BYTES to StdOut
+ */
+func TestStdIOPath(t *testing.T) {
+
+	input := []byte{0x00, 0xff, 'f', 'o', 'i', 'l', '\n'}
+	stdIn, _ := ioutil.TempFile("", "stdin")
+	stdOut, _ := ioutil.TempFile("", "stdout")
+	defer os.Remove(stdIn.Name())
+	defer os.Remove(stdOut.Name())
+	stdIn.Write(input)
+	stdIn.Seek(0, 0)
+
+	savedIn, savedOut := os.Stdin, StdOut
+	os.Stdin, StdOut = stdIn, stdOut
+	defer func() { os.Stdin, StdOut = savedIn, savedOut }()
+
+	path := StdIOPath
+	empty := ""
+	noStdOut := false
+	operation := "encrypt"
+	src, err := CliStreamInputLogic(&empty, &path, &operation, false)
+	if err != nil {
+		t.Fatalf("FAIL - CliStreamInputLogic: %v", err)
+	}
+	dst, err := CliStreamOutputLogic(&noStdOut, &path, &operation, false)
+	if err != nil {
+		t.Fatalf("FAIL - CliStreamOutputLogic: %v", err)
+	}
+	io.Copy(dst, src)
+	dst.Close()
+
+	output, _ := ioutil.ReadFile(stdOut.Name())
+	if !bytes.Equal(output, input) {
+		t.Errorf("FAIL - Expected raw BYTES %x on StdOut; received %x", input, output)
+	}
+}
+
+/*
+*  Test that PipeMessagesToStdErr moves messages off StdOut without replacing os.Stdout, so
+*  raw output on StdOut carries no messages
+ */
+func TestPipeMessagesToStdErr(t *testing.T) {
+
+	var stdOut, messages bytes.Buffer
+	savedStdout, savedOut, savedMessages := os.Stdout, StdOut, Messages
+	StdOut = &stdOut
+	defer func() { os.Stdout, StdOut, Messages = savedStdout, savedOut, savedMessages }()
+
+	PipeMessagesToStdErr()
+	if os.Stdout != savedStdout || Messages != os.Stderr {
+		t.Fatalf("FAIL - Messages should go to StdErr and os.Stdout should not change")
+	}
+
+	Messages = &messages
+	path := StdIOPath
+	noStdOut := false
+	operation := "encrypt"
+	if !CliOutputFileLogic([]byte("raw"), &noStdOut, &path, &operation, false) {
+		t.Fatalf("FAIL - CliOutputFileLogic")
+	}
+	GetAESRandomBytes(make([]byte, 12), true)
+	if stdOut.String() != "raw" || messages.Len() == 0 {
+		t.Errorf("FAIL - Expected only raw BYTES on StdOut; received %q and messages %q", stdOut.String(), messages.String())
+	}
+}
+
 // TestKeyFromPassword should be exported to another package
 //func TestKeyFromPassword () {}
 
-//TODO: Determine if it is possible to use Seal()/Open() on a file stream to give memory performance (size) optimization
 //TODO: Automated testing framework, statespace traversal, and fuzzer
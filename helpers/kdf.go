@@ -0,0 +1,219 @@
+/*
+*  Password based key derivation for foil containers. PBKDF2-SHA256 (KeyFromPassword) is
+*  kept for compatibility; Argon2id (RFC 9106) and scrypt (RFC 7914) are memory-hard and
+*  should be preferred for anything protected by a human chosen password. The KDF and its
+*  work factors are recorded in the container header (see KDFParams).
+ */
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+//DefaultPBKDF2Iterations is an exportable CONSTANT
+/*
+*  DefaultPBKDF2Iterations is the number of PBKDF2-SHA256 iterations used for new
+*  containers unless --kdf-iterations is given. 600,000 follows the 2023 OWASP guidance
+*  for PBKDF2-HMAC-SHA256.
+ */
+const DefaultPBKDF2Iterations = 600000
+
+//MinPBKDF2Iterations is an exportable CONSTANT
+// Iteration counts below MinPBKDF2Iterations are accepted but the user is warned
+const MinPBKDF2Iterations = 100000
+
+//MaxPBKDF2Iterations is an exportable CONSTANT
+/*
+*  MaxPBKDF2Iterations is the most PBKDF2 iterations a container header may ask for; the
+*  same limit applies to encrypted PKCS#8 keys. A header can not keep the decrypting host
+*  busy for hours before the password is checked.
+ */
+const MaxPBKDF2Iterations = 10000000
+
+// Default Argon2id parameters; the second recommended option of RFC 9106 sec. 4
+const (
+	DefaultArgon2Time        = 3
+	DefaultArgon2Memory      = 64 * 1024
+	DefaultArgon2Parallelism = 4
+)
+
+// Default scrypt parameters; N = 2^17, r = 8, p = 1 as recommended by OWASP
+const (
+	DefaultScryptN = 1 << 17
+	DefaultScryptR = 8
+	DefaultScryptP = 1
+)
+
+//MaxKDFMemory is an exportable CONSTANT
+/*
+*  MaxKDFMemory is the most memory (in KiB) a container header may ask Argon2id or scrypt
+*  to use: 1 GiB, the scrypt limit for encrypted PKCS#8 keys. This keeps a malicious header
+*  from exhausting the memory of the decrypting host.
+ */
+const MaxKDFMemory = 1024 * 1024
+
+/*
+*  The most passes (Argon2id time) and scrypt parallelism a container header may ask for;
+*  MaxScryptP also bounds encrypted PKCS#8 keys. Like MaxKDFMemory and MaxPBKDF2Iterations,
+*  they bound the work a header or PEM can demand before the password is checked; the
+*  defaults are far below them.
+ */
+const (
+	MaxArgon2Time = 64
+	MaxScryptP    = 16
+)
+
+//SaltSize is an exportable CONSTANT
+// The number of random BYTES of salt generated for every password protected container
+const SaltSize = 16
+
+//KDFFromName is an exportable FUNCTION
+// KDFFromName maps the value of the --kdf flag onto a KDF ID
+func KDFFromName(name string) (uint8, error) {
+
+	switch name {
+	case "pbkdf2":
+		return KDFPBKDF2SHA256, nil
+	case "argon2id":
+		return KDFArgon2id, nil
+	case "scrypt":
+		return KDFScrypt, nil
+	}
+
+	return 0, fmt.Errorf("Error: Unknown KDF \"%s\"; choose pbkdf2, argon2id, or scrypt", name)
+}
+
+/*
+*  checkKDFParams ensures the work factors are usable for the chosen KDF. It is applied
+*  both when creating a header and when reading one from untrusted input.
+ */
+func checkKDFParams(kdf uint8, params KDFParams) error {
+
+	switch kdf {
+	case KDFPBKDF2SHA256:
+		if params.Iterations == 0 || params.Iterations > MaxPBKDF2Iterations {
+			return fmt.Errorf("Error: PBKDF2 iterations must be between 1 and %d", MaxPBKDF2Iterations)
+		}
+	case KDFArgon2id:
+		if params.Iterations == 0 || params.Iterations > MaxArgon2Time {
+			return fmt.Errorf("Error: Argon2id time must be between 1 and %d", MaxArgon2Time)
+		} else if params.Parallelism == 0 || params.Parallelism > 255 {
+			return errors.New("Error: Argon2id parallelism must be between 1 and 255")
+		} else if params.Memory < 8*params.Parallelism || params.Memory > MaxKDFMemory {
+			return fmt.Errorf("Error: Argon2id memory must be between 8*parallelism and %d KiB", MaxKDFMemory)
+		}
+	case KDFScrypt:
+		if params.Iterations < 2 || params.Iterations&(params.Iterations-1) != 0 {
+			return errors.New("Error: scrypt N must be a power of two greater than 1")
+		} else if params.Memory == 0 || params.Parallelism == 0 {
+			return errors.New("Error: scrypt r and p must be at least 1")
+		} else if params.Parallelism > MaxScryptP {
+			return fmt.Errorf("Error: scrypt p must be at most %d", MaxScryptP)
+		} else if uint64(params.Iterations)*uint64(params.Memory)/8 > MaxKDFMemory {
+			return fmt.Errorf("Error: scrypt N*r requires more than %d KiB of memory", MaxKDFMemory)
+		}
+	default:
+		return fmt.Errorf("Error: Unsupported KDF: %s", KDFName(kdf))
+	}
+
+	return nil
+}
+
+//DeriveKey is an exportable FUNCTION
+/*
+*  DeriveKey expands a password into a 256-bit key using the KDF identified by kdf with
+*  the given salt and work factors.
+ */
+func DeriveKey(kdf uint8, params KDFParams, password *string, salt []byte, verbose bool) ([]byte, error) {
+
+	var (
+		key []byte
+		err error
+	)
+
+	err = checkKDFParams(kdf, params)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kdf {
+	case KDFPBKDF2SHA256:
+		return KeyFromPassword(password, salt, int(params.Iterations), verbose), nil
+	case KDFArgon2id:
+		key = argon2.IDKey([]byte(*password), salt, params.Iterations, params.Memory, uint8(params.Parallelism), 32)
+	case KDFScrypt:
+		key, err = scrypt.Key([]byte(*password), salt, int(params.Iterations), int(params.Memory), int(params.Parallelism), 32)
+		if err != nil {
+			return nil, fmt.Errorf("Error: scrypt: %v", err)
+		}
+	}
+
+	if verbose {
+		fmt.Fprintf(Messages, "DeriveKey - KDF: %s\n", KDFName(kdf))
+		fmt.Fprintf(Messages, "DeriveKey - Parameters: %d, %d, %d\n", params.Iterations, params.Memory, params.Parallelism)
+		fmt.Fprintf(Messages, "DeriveKey - Salt (hex): %x\n", salt)
+		fmt.Fprintf(Messages, "SECRET - DeriveKey - key (hex): %x\n", key)
+	}
+
+	return key, nil
+}
+
+//SetPasswordKDF is an exportable method
+/*
+*  SetPasswordKDF marks the header as password protected with the given KDF and a fresh
+*  random salt of SaltSize BYTES. Zero fields of params are replaced by the defaults of
+*  the KDF. The salt and work factors are stored in the header so that decryption never
+*  depends on the defaults of this tool.
+ */
+func (h *Header) SetPasswordKDF(kdf uint8, params KDFParams, verbose bool) error {
+
+	switch kdf {
+	case KDFPBKDF2SHA256:
+		if params.Iterations == 0 {
+			params.Iterations = DefaultPBKDF2Iterations
+		} else if params.Iterations < MinPBKDF2Iterations {
+			fmt.Fprintf(Messages, "WARNING: %d PBKDF2 iterations is below the recommended minimum of %d\n", params.Iterations, MinPBKDF2Iterations)
+		}
+		params.Memory, params.Parallelism = 0, 0
+	case KDFArgon2id:
+		if params.Iterations == 0 {
+			params.Iterations = DefaultArgon2Time
+		}
+		if params.Memory == 0 {
+			params.Memory = DefaultArgon2Memory
+		}
+		if params.Parallelism == 0 {
+			params.Parallelism = DefaultArgon2Parallelism
+		}
+	case KDFScrypt:
+		if params.Iterations == 0 {
+			params.Iterations = DefaultScryptN
+		}
+		if params.Memory == 0 {
+			params.Memory = DefaultScryptR
+		}
+		if params.Parallelism == 0 {
+			params.Parallelism = DefaultScryptP
+		}
+	}
+
+	err := checkKDFParams(kdf, params)
+	if err != nil {
+		return err
+	}
+
+	h.Salt = make([]byte, SaltSize)
+	err = GetAESRandomBytes(h.Salt, verbose)
+	if err != nil {
+		return err
+	}
+	h.KDF = kdf
+	h.KDFParams = params
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+/*
+*  Test DeriveKey. scrypt is checked against RFC 7914 sec. 12 vector #2 (truncated to 32
+*  BYTES). RFC 9106 only provides Argon2id vectors that use a secret and associated data,
+*  which x/crypto/argon2 does not expose, so Argon2id is checked for determinism and salt
+*  separation instead.
+ */
+func TestDeriveKey(t *testing.T) {
+
+	password := "password"
+	argonParams := KDFParams{Iterations: 2, Memory: 64, Parallelism: 1}
+	key1, err1 := DeriveKey(KDFArgon2id, argonParams, &password, []byte("somesalt"), false)
+	key2, err2 := DeriveKey(KDFArgon2id, argonParams, &password, []byte("somesalt"), false)
+	key3, err3 := DeriveKey(KDFArgon2id, argonParams, &password, []byte("othersalt"), false)
+	if err1 != nil || err2 != nil || err3 != nil {
+		t.Errorf("FAIL - Argon2id: %v %v %v", err1, err2, err3)
+	} else if !bytes.Equal(key1, key2) || bytes.Equal(key1, key3) || len(key1) != 32 {
+		t.Errorf("FAIL - Argon2id keys are not deterministic or do not depend on the salt")
+	}
+
+	reference, _ := hex.DecodeString("fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b373162")
+	key, err := DeriveKey(KDFScrypt, KDFParams{Iterations: 1024, Memory: 8, Parallelism: 16}, &password, []byte("NaCl"), false)
+	if err != nil {
+		t.Errorf("FAIL - scrypt: %v", err)
+	} else if !bytes.Equal(key, reference) {
+		t.Errorf("FAIL - scrypt output %x does not match reference %x", key, reference)
+	}
+
+	// Hostile headers must not be able to request unbounded memory
+	_, err = DeriveKey(KDFArgon2id, KDFParams{Iterations: 1, Memory: MaxKDFMemory + 1, Parallelism: 1}, &password, nil, false)
+	if err == nil {
+		t.Errorf("FAIL - Expected Argon2id memory above MaxKDFMemory to be rejected")
+	}
+	_, err = DeriveKey(KDFPBKDF2SHA256, KDFParams{Iterations: 0xffffffff}, &password, nil, false)
+	if err == nil {
+		t.Errorf("FAIL - Expected PBKDF2 iterations above MaxPBKDF2Iterations to be rejected")
+	}
+}
+
+/*
+*  A container header that asks for hours of work or gigabytes of memory must be rejected
+*  on decryption before any key is derived
+ */
+func TestHostileKDFHeader(t *testing.T) {
+
+	password, operation := "password", "decrypt"
+	for _, hostile := range []struct {
+		kdf    uint8
+		params KDFParams
+	}{
+		{KDFPBKDF2SHA256, KDFParams{Iterations: 0xffffffff}},
+		{KDFArgon2id, KDFParams{Iterations: 0xffffffff, Memory: 64, Parallelism: 1}},
+		{KDFArgon2id, KDFParams{Iterations: 1, Memory: 4 * 1024 * 1024, Parallelism: 1}},
+		{KDFScrypt, KDFParams{Iterations: 1 << 21, Memory: 8, Parallelism: 1}},
+		{KDFScrypt, KDFParams{Iterations: 1024, Memory: 8, Parallelism: 0xffffffff}},
+	} {
+		h, err := NewHeader(CipherAES256GCM, 0, false)
+		if err != nil {
+			t.Fatalf("FAIL - NewHeader: %v", err)
+		}
+		h.KDF, h.KDFParams, h.Salt = hostile.kdf, hostile.params, make([]byte, SaltSize)
+		raw, err := h.MarshalBinary()
+		if err != nil {
+			t.Fatalf("FAIL - MarshalBinary: %v", err)
+		}
+		read, _, err := ReadHeader(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("FAIL - ReadHeader: %v", err)
+		}
+		if _, ok := CliHeaderKeyLogic(read, &password, new(string), &operation, false); ok {
+			t.Errorf("FAIL - A header with %s %v was accepted", KDFName(hostile.kdf), hostile.params)
+		}
+	}
+}
+
+/*
+*  Test that SetPasswordKDF fills in the defaults for each KDF
+ */
+func TestSetPasswordKDF(t *testing.T) {
+
+	var (
+		h Header
+	)
+
+	err := h.SetPasswordKDF(KDFArgon2id, KDFParams{}, false)
+	if err != nil || h.KDFParams != (KDFParams{DefaultArgon2Time, DefaultArgon2Memory, DefaultArgon2Parallelism}) {
+		t.Errorf("FAIL - Argon2id defaults were not applied: %v %v", h.KDFParams, err)
+	}
+	err = h.SetPasswordKDF(KDFScrypt, KDFParams{}, false)
+	if err != nil || h.KDFParams != (KDFParams{DefaultScryptN, DefaultScryptR, DefaultScryptP}) {
+		t.Errorf("FAIL - scrypt defaults were not applied: %v %v", h.KDFParams, err)
+	}
+	err = h.SetPasswordKDF(KDFPBKDF2SHA256, KDFParams{}, false)
+	if err != nil || h.KDFParams != (KDFParams{Iterations: DefaultPBKDF2Iterations}) {
+		t.Errorf("FAIL - PBKDF2 defaults were not applied: %v %v", h.KDFParams, err)
+	}
+	if len(h.Salt) != SaltSize {
+		t.Errorf("FAIL - Expected a salt of %d bytes", SaltSize)
+	}
+}
@@ -0,0 +1,280 @@
+/*
+*  Detached digital signatures with the keys made by 'foil rsagen' and 'foil ecgen'.
+*
+*	ecdsa        : ECDSA on P-256, P-384, or P-521; SHA-256/384/512 by default to match the curve
+*	rsa-pss      : RSASSA-PSS (salt length = hash length when signing; any when verifying)
+*	rsa-pkcs1v15 : RSASSA-PKCS1-v1_5
+*	ed25519      : pure Ed25519 (RFC 8032); the whole message is signed so no hash is used
+*
+*  Signatures are produced in their natural (DER) form: an ASN.1 SEQUENCE {r, s} for ECDSA
+*  and the raw signature for RSA and Ed25519, which is what OpenSSL writes. The "raw" format
+*  is r || s for ECDSA (IEEE P1363, as used by JWS) and "base64" is the DER form in base64.
+ */
+
+package helpers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"strings"
+)
+
+// Signature schemes
+const (
+	SchemeECDSA       = "ecdsa"
+	SchemeRSAPSS      = "rsa-pss"
+	SchemeRSAPKCS1v15 = "rsa-pkcs1v15"
+	SchemeEd25519     = "ed25519"
+)
+
+// Signature encodings
+const (
+	SigFormatDER    = "der"
+	SigFormatRaw    = "raw"
+	SigFormatBase64 = "base64"
+)
+
+// ecdsaSignature is the ASN.1 form of an ECDSA signature
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+//PublicOf is an exportable function
+/*
+*  PublicOf returns the public key of a private key (RSA, ECDSA, Ed25519, or X25519) and any
+*  other key unchanged.
+ */
+func PublicOf(key interface{}) crypto.PublicKey {
+
+	if privKey, ok := key.(interface{ Public() crypto.PublicKey }); ok {
+		return privKey.Public()
+	}
+
+	return key
+}
+
+//DefaultSignatureScheme is an exportable FUNCTION
+/*
+*  DefaultSignatureScheme returns the scheme for a private or public key when --scheme is not
+*  given: ecdsa for EC keys, rsa-pss for RSA keys, and ed25519 for Ed25519 keys.
+ */
+func DefaultSignatureScheme(key interface{}) (string, error) {
+
+	switch PublicOf(key).(type) {
+	case *ecdsa.PublicKey:
+		return SchemeECDSA, nil
+	case *rsa.PublicKey:
+		return SchemeRSAPSS, nil
+	case ed25519.PublicKey:
+		return SchemeEd25519, nil
+	}
+
+	return "", fmt.Errorf("Error: %T keys cannot make signatures; use an RSA, EC, or Ed25519 key", key)
+}
+
+//SignatureHash is an exportable FUNCTION
+/*
+*  SignatureHash checks that scheme suits key and maps the --hash flag onto a hash. An empty
+*  name selects SHA-256 for RSA and the hash matching the curve for ECDSA. Ed25519 does not
+*  take a hash and returns 0.
+ */
+func SignatureHash(key interface{}, scheme string, name string) (crypto.Hash, error) {
+
+	pub := PublicOf(key)
+	switch scheme {
+	case SchemeECDSA:
+		ecKey, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return 0, errors.New("Error: The ecdsa scheme requires an EC key")
+		}
+		if len(name) == 0 {
+			switch ecKey.Curve.Params().BitSize {
+			case 384:
+				return crypto.SHA384, nil
+			case 521:
+				return crypto.SHA512, nil
+			}
+			return crypto.SHA256, nil
+		}
+	case SchemeRSAPSS, SchemeRSAPKCS1v15:
+		if _, ok := pub.(*rsa.PublicKey); !ok {
+			return 0, fmt.Errorf("Error: The %s scheme requires an RSA key", scheme)
+		}
+		if len(name) == 0 {
+			return crypto.SHA256, nil
+		}
+	case SchemeEd25519:
+		if _, ok := pub.(ed25519.PublicKey); !ok {
+			return 0, errors.New("Error: The ed25519 scheme requires an Ed25519 key")
+		} else if len(name) > 0 {
+			return 0, errors.New("Error: Ed25519 signs the message itself; omit --hash")
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("Error: Unknown signature scheme %q; use ecdsa, rsa-pss, rsa-pkcs1v15, or ed25519", scheme)
+	}
+
+	switch strings.ToLower(name) {
+	case "sha256":
+		return crypto.SHA256, nil
+	case "sha384":
+		return crypto.SHA384, nil
+	case "sha512":
+		return crypto.SHA512, nil
+	}
+
+	return 0, fmt.Errorf("Error: Unknown hash %q; use sha256, sha384, or sha512", name)
+}
+
+// digestOf hashes src with h; Ed25519 (h == 0) reads the whole message instead
+func digestOf(h crypto.Hash, src io.Reader) ([]byte, error) {
+
+	if h == 0 {
+		return ioutil.ReadAll(src)
+	}
+	hasher := h.New()
+	_, err := io.Copy(hasher, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+//Sign is an exportable FUNCTION
+/*
+*  Sign signs the message read from src with priv using scheme and the hash h (from
+*  SignatureHash). The signature is returned in DER form. Hashed schemes stream the input;
+*  Ed25519 holds the whole message in memory.
+ */
+func Sign(priv crypto.PrivateKey, scheme string, h crypto.Hash, src io.Reader, verbose bool) ([]byte, error) {
+
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("Error: %T keys cannot make signatures", priv)
+	}
+	digest, err := digestOf(h, src)
+	if err != nil {
+		return nil, fmt.Errorf("Error: Reading input: %v", err)
+	}
+	if verbose && h != 0 {
+		fmt.Fprintf(Messages, "Sign - %s digest (hex): %x\n", h, digest)
+	}
+
+	switch scheme {
+	case SchemeRSAPSS:
+		return signer.Sign(rand.Reader, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: h})
+	case SchemeEd25519:
+		return signer.Sign(rand.Reader, digest, crypto.Hash(0))
+	}
+
+	// ECDSA signers return ASN.1 and RSA signers return PKCS#1 v1.5 for a plain hash
+	return signer.Sign(rand.Reader, digest, h)
+}
+
+//Verify is an exportable FUNCTION
+/*
+*  Verify checks the DER form signature sig over the message read from src. A nil error
+*  means that the signature is valid.
+ */
+func Verify(pub crypto.PublicKey, scheme string, h crypto.Hash, src io.Reader, sig []byte, verbose bool) error {
+
+	digest, err := digestOf(h, src)
+	if err != nil {
+		return fmt.Errorf("Error: Reading input: %v", err)
+	}
+	if verbose && h != 0 {
+		fmt.Fprintf(Messages, "Verify - %s digest (hex): %x\n", h, digest)
+	}
+
+	valid := false
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		valid = scheme == SchemeECDSA && ecdsa.VerifyASN1(key, digest, sig)
+	case *rsa.PublicKey:
+		if scheme == SchemeRSAPSS {
+			valid = rsa.VerifyPSS(key, h, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}) == nil
+		} else if scheme == SchemeRSAPKCS1v15 {
+			valid = rsa.VerifyPKCS1v15(key, h, digest, sig) == nil
+		}
+	case ed25519.PublicKey:
+		valid = scheme == SchemeEd25519 && ed25519.Verify(key, digest, sig)
+	default:
+		return fmt.Errorf("Error: %T keys cannot verify signatures", pub)
+	}
+	if !valid {
+		return errors.New("Error: The signature is NOT valid")
+	}
+
+	return nil
+}
+
+//EncodeSignature is an exportable FUNCTION
+// EncodeSignature converts a DER form signature made with key into format (der, raw, base64)
+func EncodeSignature(sig []byte, key interface{}, format string) ([]byte, error) {
+
+	switch format {
+	case SigFormatDER:
+		return sig, nil
+	case SigFormatBase64:
+		return []byte(base64.StdEncoding.EncodeToString(sig)), nil
+	case SigFormatRaw:
+		ecKey, ok := PublicOf(key).(*ecdsa.PublicKey)
+		if !ok {
+			return sig, nil
+		}
+		var parsed ecdsaSignature
+		rest, err := asn1.Unmarshal(sig, &parsed)
+		if err != nil || len(rest) > 0 {
+			return nil, errors.New("Error: The ECDSA signature is not valid DER")
+		}
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		raw := make([]byte, 2*size)
+		parsed.R.FillBytes(raw[:size])
+		parsed.S.FillBytes(raw[size:])
+		return raw, nil
+	}
+
+	return nil, fmt.Errorf("Error: Unknown signature format %q; use der, raw, or base64", format)
+}
+
+//DecodeSignature is an exportable FUNCTION
+// DecodeSignature converts a signature in format (der, raw, base64) for key into DER form
+func DecodeSignature(encoded []byte, key interface{}, format string) ([]byte, error) {
+
+	switch format {
+	case SigFormatDER:
+		return encoded, nil
+	case SigFormatBase64:
+		sig, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(encoded)), ""))
+		if err != nil {
+			return nil, errors.New("Error: The signature is not valid base64")
+		}
+		return sig, nil
+	case SigFormatRaw:
+		ecKey, ok := PublicOf(key).(*ecdsa.PublicKey)
+		if !ok {
+			return encoded, nil
+		}
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(encoded) != 2*size {
+			return nil, fmt.Errorf("Error: A raw %s signature is %d BYTES", ecKey.Curve.Params().Name, 2*size)
+		}
+		return asn1.Marshal(ecdsaSignature{
+			R: new(big.Int).SetBytes(encoded[:size]),
+			S: new(big.Int).SetBytes(encoded[size:]),
+		})
+	}
+
+	return nil, fmt.Errorf("Error: Unknown signature format %q; use der, raw, or base64", format)
+}
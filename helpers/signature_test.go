@@ -0,0 +1,87 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+)
+
+/*
+*  Sign with every scheme and key type, round trip the signature through each format, and
+*  ensure that a modified message or the wrong scheme does not verify.
+ */
+func TestSignVerify(t *testing.T) {
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p521Key, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	input := []byte("Attack at dawn!")
+
+	tests := []struct {
+		key    crypto.Signer
+		scheme string
+		hash   string
+	}{
+		{rsaKey, SchemeRSAPSS, ""},
+		{rsaKey, SchemeRSAPKCS1v15, "sha512"},
+		{p256Key, SchemeECDSA, ""},
+		{p521Key, SchemeECDSA, "sha256"},
+		{edKey, SchemeEd25519, ""},
+	}
+	for _, test := range tests {
+		h, err := SignatureHash(test.key, test.scheme, test.hash)
+		if err != nil {
+			t.Fatalf("FAIL - SignatureHash (%s): %v", test.scheme, err)
+		}
+		sig, err := Sign(test.key, test.scheme, h, bytes.NewReader(input), false)
+		if err != nil {
+			t.Fatalf("FAIL - Sign (%s): %v", test.scheme, err)
+		}
+
+		for _, format := range []string{SigFormatDER, SigFormatRaw, SigFormatBase64} {
+			encoded, err := EncodeSignature(sig, test.key, format)
+			if err != nil {
+				t.Fatalf("FAIL - EncodeSignature (%s, %s): %v", test.scheme, format, err)
+			}
+			decoded, err := DecodeSignature(encoded, test.key.Public(), format)
+			if err != nil {
+				t.Fatalf("FAIL - DecodeSignature (%s, %s): %v", test.scheme, format, err)
+			}
+			err = Verify(test.key.Public(), test.scheme, h, bytes.NewReader(input), decoded, false)
+			if err != nil {
+				t.Errorf("FAIL - Verify (%s, %s): %v", test.scheme, format, err)
+			}
+		}
+
+		if Verify(test.key.Public(), test.scheme, h, bytes.NewReader([]byte("Attack at dusk!")), sig, false) == nil {
+			t.Errorf("FAIL - Verified a modified message (%s)", test.scheme)
+		}
+	}
+
+	// P-256 raw signatures are 64 BYTES regardless of the DER length
+	h, _ := SignatureHash(p256Key, SchemeECDSA, "")
+	sig, _ := Sign(p256Key, SchemeECDSA, h, bytes.NewReader(input), false)
+	raw, _ := EncodeSignature(sig, p256Key, SigFormatRaw)
+	if len(raw) != 64 {
+		t.Errorf("FAIL - Expected a 64 BYTE raw P-256 signature; got %d", len(raw))
+	}
+
+	// A PSS signature must not verify as PKCS#1 v1.5, and schemes must match their keys
+	h, _ = SignatureHash(rsaKey, SchemeRSAPSS, "")
+	sig, _ = Sign(rsaKey, SchemeRSAPSS, h, bytes.NewReader(input), false)
+	if Verify(&rsaKey.PublicKey, SchemeRSAPKCS1v15, h, bytes.NewReader(input), sig, false) == nil {
+		t.Errorf("FAIL - Verified an RSA-PSS signature as PKCS#1 v1.5")
+	}
+	if _, err := SignatureHash(p256Key, SchemeRSAPSS, ""); err == nil {
+		t.Errorf("FAIL - Accepted rsa-pss with an EC key")
+	}
+	if _, err := SignatureHash(edKey, SchemeEd25519, "sha256"); err == nil {
+		t.Errorf("FAIL - Accepted a hash with Ed25519")
+	}
+}
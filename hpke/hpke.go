@@ -0,0 +1,461 @@
+/*
+*	This package implements Hybrid Public Key Encryption (HPKE) as specified in RFC 9180 so
+*	that foil can exchange public-key encrypted messages with other HPKE implementations.
+*
+*	Modes : base and auth
+*	KEMs  : DHKEM(P-256, HKDF-SHA256), DHKEM(P-384, HKDF-SHA384), DHKEM(P-521, HKDF-SHA512),
+*	        DHKEM(X25519, HKDF-SHA256)
+*	KDFs  : HKDF-SHA256, HKDF-SHA384, HKDF-SHA512
+*	AEADs : AES-128-GCM, AES-256-GCM, ChaCha20-Poly1305, export-only
+*
+*	HPKE: https://www.rfc-editor.org/rfc/rfc9180
+ */
+
+package hpke
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// HPKE modes (RFC 9180 sec. 5); the PSK modes are not supported
+const (
+	ModeBase uint8 = 0x00
+	ModeAuth uint8 = 0x02
+)
+
+// KEM identifiers (RFC 9180 sec. 7.1)
+const (
+	KEMP256HKDFSHA256   uint16 = 0x0010
+	KEMP384HKDFSHA384   uint16 = 0x0011
+	KEMP521HKDFSHA512   uint16 = 0x0012
+	KEMX25519HKDFSHA256 uint16 = 0x0020
+)
+
+// KDF identifiers (RFC 9180 sec. 7.2)
+const (
+	KDFHKDFSHA256 uint16 = 0x0001
+	KDFHKDFSHA384 uint16 = 0x0002
+	KDFHKDFSHA512 uint16 = 0x0003
+)
+
+// AEAD identifiers (RFC 9180 sec. 7.3)
+const (
+	AEADAES128GCM        uint16 = 0x0001
+	AEADAES256GCM        uint16 = 0x0002
+	AEADChaCha20Poly1305 uint16 = 0x0003
+	AEADExportOnly       uint16 = 0xffff
+)
+
+// The version label prepended to every labeled KDF input
+const versionLabel = "HPKE-v1"
+
+//Suite is an exportable struct
+// Suite is an HPKE ciphersuite: a KEM, a KDF, and an AEAD
+type Suite struct {
+	KEM  uint16
+	KDF  uint16
+	AEAD uint16
+
+	kem  *dhKEM
+	hash func() hash.Hash
+	nK   int
+	nN   int
+}
+
+//Context is an exportable struct
+/*
+*  Context is an HPKE encryption context. A sender context seals and a recipient context
+*  opens; each message advances the sequence number so messages must be opened in the order
+*  in which they were sealed.
+ */
+type Context struct {
+	suite          *Suite
+	aead           cipher.AEAD
+	baseNonce      []byte
+	seq            uint64
+	exporterSecret []byte
+	sender         bool
+}
+
+//NewSuite is an exportable FUNCTION
+// NewSuite returns the ciphersuite with the given KEM, KDF, and AEAD identifiers
+func NewSuite(kemID, kdfID, aeadID uint16) (*Suite, error) {
+
+	kem, err := kemByID(kemID)
+	if err != nil {
+		return nil, err
+	}
+	s := &Suite{KEM: kemID, KDF: kdfID, AEAD: aeadID, kem: kem}
+
+	switch kdfID {
+	case KDFHKDFSHA256:
+		s.hash = sha256.New
+	case KDFHKDFSHA384:
+		s.hash = sha512.New384
+	case KDFHKDFSHA512:
+		s.hash = sha512.New
+	default:
+		return nil, fmt.Errorf("Error: Unsupported HPKE KDF 0x%04x", kdfID)
+	}
+
+	switch aeadID {
+	case AEADAES128GCM:
+		s.nK, s.nN = 16, 12
+	case AEADAES256GCM, AEADChaCha20Poly1305:
+		s.nK, s.nN = 32, 12
+	case AEADExportOnly:
+		s.nK, s.nN = 0, 0
+	default:
+		return nil, fmt.Errorf("Error: Unsupported HPKE AEAD 0x%04x", aeadID)
+	}
+
+	return s, nil
+}
+
+// String names the ciphersuite, e.g. "DHKEM(P-256, HKDF-SHA256), HKDF-SHA256, AES-128-GCM"
+func (s *Suite) String() string {
+	return fmt.Sprintf("%s, %s, %s", KEMName(s.KEM), KDFName(s.KDF), AEADName(s.AEAD))
+}
+
+// suiteID is the HPKE suite_id: "HPKE" || I2OSP(kem_id, 2) || I2OSP(kdf_id, 2) || I2OSP(aead_id, 2)
+func (s *Suite) suiteID() []byte {
+	return []byte{'H', 'P', 'K', 'E', byte(s.KEM >> 8), byte(s.KEM), byte(s.KDF >> 8), byte(s.KDF),
+		byte(s.AEAD >> 8), byte(s.AEAD)}
+}
+
+//EncSize is an exportable method
+// EncSize returns the length in BYTES of the encapsulated key (Nenc)
+func (s *Suite) EncSize() int {
+
+	if s.kem.curve == ecdh.X25519() {
+		return 32
+	}
+
+	return 1 + 2*s.kem.nSk
+}
+
+//Curve is an exportable method
+// Curve returns the ecdh curve of the KEM
+func (s *Suite) Curve() ecdh.Curve {
+	return s.kem.curve
+}
+
+//DeriveKeyPair is an exportable method
+// DeriveKeyPair derives a KEM key pair from the input keying material ikm (RFC 9180 sec. 7.1.3)
+func (s *Suite) DeriveKeyPair(ikm []byte) (*ecdh.PrivateKey, error) {
+
+	if len(ikm) < s.kem.nSk {
+		return nil, fmt.Errorf("Error: HPKE DeriveKeyPair requires at least %d BYTES of ikm", s.kem.nSk)
+	}
+
+	return s.kem.deriveKeyPair(ikm)
+}
+
+//SetupBaseS is an exportable method
+/*
+*  SetupBaseS encapsulates a fresh shared secret to pkR and returns the encapsulated key enc,
+*  which must be sent to the recipient, with a sender context.
+ */
+func (s *Suite) SetupBaseS(pkR *ecdh.PublicKey, info []byte) ([]byte, *Context, error) {
+	return s.setupS(ModeBase, pkR, info, nil, nil)
+}
+
+//SetupBaseR is an exportable method
+// SetupBaseR decapsulates enc with skR and returns a recipient context
+func (s *Suite) SetupBaseR(enc []byte, skR *ecdh.PrivateKey, info []byte) (*Context, error) {
+	return s.setupR(ModeBase, enc, skR, info, nil)
+}
+
+//SetupAuthS is an exportable method
+/*
+*  SetupAuthS is SetupBaseS where the sender also authenticates with skS; only a recipient
+*  that knows the matching public key can open the messages.
+ */
+func (s *Suite) SetupAuthS(pkR *ecdh.PublicKey, info []byte, skS *ecdh.PrivateKey) ([]byte, *Context, error) {
+
+	if skS == nil {
+		return nil, nil, errors.New("Error: HPKE auth mode requires a sender private key")
+	}
+
+	return s.setupS(ModeAuth, pkR, info, skS, nil)
+}
+
+//SetupAuthR is an exportable method
+// SetupAuthR is SetupBaseR for messages authenticated with the sender public key pkS
+func (s *Suite) SetupAuthR(enc []byte, skR *ecdh.PrivateKey, info []byte, pkS *ecdh.PublicKey) (*Context, error) {
+
+	if pkS == nil {
+		return nil, errors.New("Error: HPKE auth mode requires a sender public key")
+	}
+
+	return s.setupR(ModeAuth, enc, skR, info, pkS)
+}
+
+// setupS runs the KEM and the key schedule for a sender; skE is only set by the tests
+func (s *Suite) setupS(mode uint8, pkR *ecdh.PublicKey, info []byte, skS, skE *ecdh.PrivateKey) ([]byte, *Context, error) {
+
+	shared, enc, err := s.kem.encap(pkR, skS, skE)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, err := s.keySchedule(mode, shared, info, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return enc, ctx, nil
+}
+
+// setupR runs the KEM and the key schedule for a recipient
+func (s *Suite) setupR(mode uint8, enc []byte, skR *ecdh.PrivateKey, info []byte, pkS *ecdh.PublicKey) (*Context, error) {
+
+	shared, err := s.kem.decap(enc, skR, pkS)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.keySchedule(mode, shared, info, false)
+}
+
+/*
+*  keySchedule derives the AEAD key, base nonce, and exporter secret (RFC 9180 sec. 5.1).
+*  Without PSK support psk and psk_id are always empty.
+ */
+func (s *Suite) keySchedule(mode uint8, shared, info []byte, sender bool) (*Context, error) {
+
+	suiteID := s.suiteID()
+	pskIDHash := labeledExtract(s.hash, suiteID, nil, "psk_id_hash", nil)
+	infoHash := labeledExtract(s.hash, suiteID, nil, "info_hash", info)
+	keyScheduleContext := append(append([]byte{mode}, pskIDHash...), infoHash...)
+	secret := labeledExtract(s.hash, suiteID, shared, "secret", nil)
+
+	exporterSecret, err := labeledExpand(s.hash, suiteID, secret, "exp", keyScheduleContext, s.hash().Size())
+	if err != nil {
+		return nil, err
+	}
+	ctx := &Context{suite: s, exporterSecret: exporterSecret, sender: sender}
+	if s.AEAD == AEADExportOnly {
+		return ctx, nil
+	}
+
+	key, err := labeledExpand(s.hash, suiteID, secret, "key", keyScheduleContext, s.nK)
+	if err != nil {
+		return nil, err
+	}
+	ctx.baseNonce, err = labeledExpand(s.hash, suiteID, secret, "base_nonce", keyScheduleContext, s.nN)
+	if err != nil {
+		return nil, err
+	}
+	if s.AEAD == AEADChaCha20Poly1305 {
+		ctx.aead, err = chacha20poly1305.New(key)
+	} else {
+		var aesBlock cipher.Block
+		aesBlock, err = aes.NewCipher(key)
+		if err == nil {
+			ctx.aead, err = cipher.NewGCM(aesBlock)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx, nil
+}
+
+// nonce XORs the sequence number into the base nonce (RFC 9180 sec. 5.2)
+func (c *Context) nonce() []byte {
+
+	nonce := append([]byte{}, c.baseNonce...)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(c.seq >> (8 * i))
+	}
+
+	return nonce
+}
+
+//Seal is an exportable method
+// Seal encrypts and authenticates pt and aad with the next nonce of a sender context
+func (c *Context) Seal(aad, pt []byte) ([]byte, error) {
+
+	if !c.sender {
+		return nil, errors.New("Error: HPKE recipient contexts cannot seal")
+	} else if c.aead == nil {
+		return nil, errors.New("Error: HPKE export-only contexts cannot seal")
+	} else if c.seq == math.MaxUint64 {
+		return nil, errors.New("Error: HPKE message limit reached")
+	}
+	ct := c.aead.Seal(nil, c.nonce(), pt, aad)
+	c.seq++
+
+	return ct, nil
+}
+
+//Open is an exportable method
+/*
+*  Open authenticates and decrypts ct with the next nonce of a recipient context. The
+*  sequence number only advances when the message authenticates.
+ */
+func (c *Context) Open(aad, ct []byte) ([]byte, error) {
+
+	if c.sender {
+		return nil, errors.New("Error: HPKE sender contexts cannot open")
+	} else if c.aead == nil {
+		return nil, errors.New("Error: HPKE export-only contexts cannot open")
+	} else if c.seq == math.MaxUint64 {
+		return nil, errors.New("Error: HPKE message limit reached")
+	}
+	pt, err := c.aead.Open(nil, c.nonce(), ct, aad)
+	if err != nil {
+		return nil, errors.New("Error: HPKE message authentication failed")
+	}
+	c.seq++
+
+	return pt, nil
+}
+
+//Export is an exportable method
+// Export derives length BYTES of secret bound to exporterContext (RFC 9180 sec. 5.3)
+func (c *Context) Export(exporterContext []byte, length int) ([]byte, error) {
+	return labeledExpand(c.suite.hash, c.suite.suiteID(), c.exporterSecret, "sec", exporterContext, length)
+}
+
+// labeledExtract is LabeledExtract(salt, label, ikm) of RFC 9180 sec. 4
+func labeledExtract(h func() hash.Hash, suiteID, salt []byte, label string, ikm []byte) []byte {
+
+	labeledIKM := append([]byte(versionLabel), suiteID...)
+	labeledIKM = append(append(labeledIKM, label...), ikm...)
+
+	return hkdf.Extract(h, labeledIKM, salt)
+}
+
+// labeledExpand is LabeledExpand(prk, label, info, L) of RFC 9180 sec. 4
+func labeledExpand(h func() hash.Hash, suiteID, prk []byte, label string, info []byte, length int) ([]byte, error) {
+
+	if length < 0 || length > 255*h().Size() {
+		return nil, fmt.Errorf("Error: HPKE cannot expand %d BYTES", length)
+	}
+	labeledInfo := append([]byte{byte(length >> 8), byte(length)}, versionLabel...)
+	labeledInfo = append(append(append(labeledInfo, suiteID...), label...), info...)
+
+	out := make([]byte, length)
+	_, err := io.ReadFull(hkdf.Expand(h, prk, labeledInfo), out)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+//KEMName is an exportable FUNCTION
+// KEMName returns a human readable name for a KEM identifier
+func KEMName(id uint16) string {
+
+	switch id {
+	case KEMP256HKDFSHA256:
+		return "DHKEM(P-256, HKDF-SHA256)"
+	case KEMP384HKDFSHA384:
+		return "DHKEM(P-384, HKDF-SHA384)"
+	case KEMP521HKDFSHA512:
+		return "DHKEM(P-521, HKDF-SHA512)"
+	case KEMX25519HKDFSHA256:
+		return "DHKEM(X25519, HKDF-SHA256)"
+	}
+
+	return fmt.Sprintf("unknown (0x%04x)", id)
+}
+
+//KEMForCurve is an exportable FUNCTION
+// KEMForCurve returns the DHKEM identifier for the curve of a key
+func KEMForCurve(curve ecdh.Curve) (uint16, error) {
+
+	switch curve {
+	case ecdh.P256():
+		return KEMP256HKDFSHA256, nil
+	case ecdh.P384():
+		return KEMP384HKDFSHA384, nil
+	case ecdh.P521():
+		return KEMP521HKDFSHA512, nil
+	case ecdh.X25519():
+		return KEMX25519HKDFSHA256, nil
+	}
+
+	return 0, fmt.Errorf("Error: No HPKE KEM for curve %v", curve)
+}
+
+//KDFName is an exportable FUNCTION
+// KDFName returns a human readable name for a KDF identifier
+func KDFName(id uint16) string {
+
+	switch id {
+	case KDFHKDFSHA256:
+		return "HKDF-SHA256"
+	case KDFHKDFSHA384:
+		return "HKDF-SHA384"
+	case KDFHKDFSHA512:
+		return "HKDF-SHA512"
+	}
+
+	return fmt.Sprintf("unknown (0x%04x)", id)
+}
+
+//KDFFromName is an exportable FUNCTION
+// KDFFromName maps the value of the --kdf flag onto a KDF identifier
+func KDFFromName(name string) (uint16, error) {
+
+	switch name {
+	case "hkdf-sha256":
+		return KDFHKDFSHA256, nil
+	case "hkdf-sha384":
+		return KDFHKDFSHA384, nil
+	case "hkdf-sha512":
+		return KDFHKDFSHA512, nil
+	}
+
+	return 0, fmt.Errorf("Error: Unknown HPKE KDF %q; use hkdf-sha256, hkdf-sha384, or hkdf-sha512", name)
+}
+
+//AEADName is an exportable FUNCTION
+// AEADName returns a human readable name for an AEAD identifier
+func AEADName(id uint16) string {
+
+	switch id {
+	case AEADAES128GCM:
+		return "AES-128-GCM"
+	case AEADAES256GCM:
+		return "AES-256-GCM"
+	case AEADChaCha20Poly1305:
+		return "ChaCha20-Poly1305"
+	case AEADExportOnly:
+		return "Export-only"
+	}
+
+	return fmt.Sprintf("unknown (0x%04x)", id)
+}
+
+//AEADFromName is an exportable FUNCTION
+// AEADFromName maps the value of the --aead flag onto an AEAD identifier
+func AEADFromName(name string) (uint16, error) {
+
+	switch name {
+	case "aes-128-gcm":
+		return AEADAES128GCM, nil
+	case "aes-256-gcm":
+		return AEADAES256GCM, nil
+	case "chacha20-poly1305":
+		return AEADChaCha20Poly1305, nil
+	}
+
+	return 0, fmt.Errorf("Error: Unknown HPKE AEAD %q; use aes-128-gcm, aes-256-gcm, or chacha20-poly1305", name)
+}
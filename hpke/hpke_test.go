@@ -0,0 +1,359 @@
+package hpke
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+/*
+*  testdata/rfc9180.json holds two kinds of vectors. The base mode (mode 0) entries are the
+*  vectors accumulated by the Go project for every KEM, KDF, and AEAD it supports, including
+*  suites that are not in RFC 9180 Appendix A (such as the export-only AEAD 0xffff). Rather
+*  than listing every encryption and export, 1000 random inputs are drawn from SHAKE128 and
+*  the outputs are absorbed into a second SHAKE128 whose first 16 BYTES are recorded as the
+*  accumulated value. The auth mode (mode 2) entries are the RFC 9180 Appendix A.1.3, A.3.3,
+*  and A.6.3 vectors (X25519, P-256, and P-521) with their first encryption and exports.
+ */
+type rfc9180Vector struct {
+	Mode           uint8  `json:"mode"`
+	KEM            uint16 `json:"kem_id"`
+	KDF            uint16 `json:"kdf_id"`
+	AEAD           uint16 `json:"aead_id"`
+	Info           string `json:"info"`
+	IkmE           string `json:"ikmE"`
+	IkmR           string `json:"ikmR"`
+	IkmS           string `json:"ikmS"`
+	SkRm           string `json:"skRm"`
+	SkSm           string `json:"skSm"`
+	PkRm           string `json:"pkRm"`
+	PkSm           string `json:"pkSm"`
+	Enc            string `json:"enc"`
+	AccEncryptions string `json:"encryptions_accumulated"`
+	AccExports     string `json:"exports_accumulated"`
+
+	SharedSecret   string `json:"shared_secret"`
+	BaseNonce      string `json:"base_nonce"`
+	ExporterSecret string `json:"exporter_secret"`
+	Encryptions    []struct {
+		Aad   string `json:"aad"`
+		Ct    string `json:"ct"`
+		Nonce string `json:"nonce"`
+		Pt    string `json:"pt"`
+	} `json:"encryptions"`
+	Exports []struct {
+		ExporterContext string `json:"exporter_context"`
+		L               int    `json:"L"`
+		ExportedValue   string `json:"exported_value"`
+	} `json:"exports"`
+}
+
+func mustHex(t *testing.T, s string) []byte {
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+// drawInput reads a length BYTE and then that many BYTES from r
+func drawInput(t *testing.T, r io.Reader) []byte {
+
+	l := make([]byte, 1)
+	if _, err := io.ReadFull(r, l); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, int(l[0]))
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+// readRFC9180Vectors returns the vectors of testdata/rfc9180.json with the given mode
+func readRFC9180Vectors(t *testing.T, mode uint8) []rfc9180Vector {
+
+	var vectors, selected []rfc9180Vector
+
+	raw, err := ioutil.ReadFile("testdata/rfc9180.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(raw, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		if v.Mode != ModeBase && v.Mode != ModeAuth {
+			t.Errorf("FAIL - Unexpected mode %d in testdata/rfc9180.json", v.Mode)
+		} else if v.Mode == mode {
+			selected = append(selected, v)
+		}
+	}
+	if len(selected) == 0 {
+		t.Fatalf("FAIL - No mode %d vectors in testdata/rfc9180.json", mode)
+	}
+
+	return selected
+}
+
+func TestRFC9180Vectors(t *testing.T) {
+
+	for _, v := range readRFC9180Vectors(t, ModeBase) {
+		suite, err := NewSuite(v.KEM, v.KDF, v.AEAD)
+		if err != nil {
+			t.Errorf("FAIL - NewSuite(0x%04x, 0x%04x, 0x%04x): %v", v.KEM, v.KDF, v.AEAD, err)
+			continue
+		}
+
+		skR, err := suite.DeriveKeyPair(mustHex(t, v.IkmR))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(skR.Bytes(), mustHex(t, v.SkRm)) || !bytes.Equal(skR.PublicKey().Bytes(), mustHex(t, v.PkRm)) {
+			t.Errorf("FAIL - DeriveKeyPair(ikmR) does not match skRm/pkRm for %s", suite)
+		}
+		skE, err := suite.DeriveKeyPair(mustHex(t, v.IkmE))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		info := mustHex(t, v.Info)
+		enc, sender, err := suite.setupS(ModeBase, skR.PublicKey(), info, nil, skE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(enc, mustHex(t, v.Enc)) || len(enc) != suite.EncSize() {
+			t.Errorf("FAIL - enc %x does not match %s for %s", enc, v.Enc, suite)
+		}
+		recipient, err := suite.SetupBaseR(enc, skR, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.AEAD != AEADExportOnly {
+			source, sink := sha3.NewShake128(), sha3.NewShake128()
+			for i := 0; i < 1000; i++ {
+				aad, pt := drawInput(t, source), drawInput(t, source)
+				ct, err := sender.Seal(aad, pt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sink.Write(ct)
+				opened, err := recipient.Open(aad, ct)
+				if err != nil || !bytes.Equal(opened, pt) {
+					t.Fatalf("FAIL - Open of message %d failed for %s: %v", i, suite, err)
+				}
+			}
+			accumulated := make([]byte, 16)
+			sink.Read(accumulated)
+			if !bytes.Equal(accumulated, mustHex(t, v.AccEncryptions)) {
+				t.Errorf("FAIL - Accumulated encryptions %x do not match %s for %s", accumulated, v.AccEncryptions, suite)
+			}
+		} else if _, err = sender.Seal(nil, nil); err == nil {
+			t.Errorf("FAIL - Export-only context sealed a message")
+		}
+
+		source, sink := sha3.NewShake128(), sha3.NewShake128()
+		for l := 0; l < 1000; l++ {
+			exporterContext := drawInput(t, source)
+			value, err := sender.Export(exporterContext, l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sink.Write(value)
+			got, err := recipient.Export(exporterContext, l)
+			if err != nil || !bytes.Equal(got, value) {
+				t.Fatalf("FAIL - Recipient export of length %d differs for %s", l, suite)
+			}
+		}
+		accumulated := make([]byte, 16)
+		sink.Read(accumulated)
+		if !bytes.Equal(accumulated, mustHex(t, v.AccExports)) {
+			t.Errorf("FAIL - Accumulated exports %x do not match %s for %s", accumulated, v.AccExports, suite)
+		}
+	}
+}
+
+/*
+*  Test SetupAuthS and SetupAuthR against the RFC 9180 auth mode vectors: the sender's keys,
+*  the shared secret, the key schedule, the first encryption, and the exports must all match.
+ */
+func TestRFC9180AuthVectors(t *testing.T) {
+
+	for _, v := range readRFC9180Vectors(t, ModeAuth) {
+		suite, err := NewSuite(v.KEM, v.KDF, v.AEAD)
+		if err != nil {
+			t.Fatal(err)
+		}
+		skR, err := suite.DeriveKeyPair(mustHex(t, v.IkmR))
+		if err != nil {
+			t.Fatal(err)
+		}
+		skS, err := suite.DeriveKeyPair(mustHex(t, v.IkmS))
+		if err != nil {
+			t.Fatal(err)
+		}
+		skE, err := suite.DeriveKeyPair(mustHex(t, v.IkmE))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(skR.Bytes(), mustHex(t, v.SkRm)) || !bytes.Equal(skR.PublicKey().Bytes(), mustHex(t, v.PkRm)) {
+			t.Errorf("FAIL - DeriveKeyPair(ikmR) does not match skRm/pkRm for %s", suite)
+		}
+		if !bytes.Equal(skS.Bytes(), mustHex(t, v.SkSm)) || !bytes.Equal(skS.PublicKey().Bytes(), mustHex(t, v.PkSm)) {
+			t.Errorf("FAIL - DeriveKeyPair(ikmS) does not match skSm/pkSm for %s", suite)
+		}
+
+		shared, enc, err := suite.kem.encap(skR.PublicKey(), skS, skE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(shared, mustHex(t, v.SharedSecret)) || !bytes.Equal(enc, mustHex(t, v.Enc)) {
+			t.Errorf("FAIL - AuthEncap does not match shared_secret/enc for %s", suite)
+		}
+
+		info := mustHex(t, v.Info)
+		enc, sender, err := suite.setupS(ModeAuth, skR.PublicKey(), info, skS, skE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recipient, err := suite.SetupAuthR(enc, skR, info, skS.PublicKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ctx := range []*Context{sender, recipient} {
+			if !bytes.Equal(ctx.baseNonce, mustHex(t, v.BaseNonce)) || !bytes.Equal(ctx.exporterSecret, mustHex(t, v.ExporterSecret)) {
+				t.Errorf("FAIL - Key schedule does not match base_nonce/exporter_secret for %s", suite)
+			}
+		}
+
+		for i, e := range v.Encryptions {
+			if !bytes.Equal(sender.nonce(), mustHex(t, e.Nonce)) {
+				t.Errorf("FAIL - Nonce of encryption %d does not match for %s", i, suite)
+			}
+			ct, err := sender.Seal(mustHex(t, e.Aad), mustHex(t, e.Pt))
+			if err != nil || !bytes.Equal(ct, mustHex(t, e.Ct)) {
+				t.Errorf("FAIL - Encryption %d does not match for %s: %v", i, suite, err)
+			}
+			pt, err := recipient.Open(mustHex(t, e.Aad), mustHex(t, e.Ct))
+			if err != nil || !bytes.Equal(pt, mustHex(t, e.Pt)) {
+				t.Errorf("FAIL - Decryption %d does not match for %s: %v", i, suite, err)
+			}
+		}
+		for i, e := range v.Exports {
+			for _, ctx := range []*Context{sender, recipient} {
+				value, err := ctx.Export(mustHex(t, e.ExporterContext), e.L)
+				if err != nil || !bytes.Equal(value, mustHex(t, e.ExportedValue)) {
+					t.Errorf("FAIL - Export %d does not match for %s: %v", i, suite, err)
+				}
+			}
+		}
+	}
+}
+
+func TestAuthMode(t *testing.T) {
+
+	var (
+		aad = []byte("header")
+		msg = []byte("An authenticated message")
+	)
+
+	for _, kemID := range []uint16{KEMP256HKDFSHA256, KEMP384HKDFSHA384, KEMP521HKDFSHA512, KEMX25519HKDFSHA256} {
+		for _, aeadID := range []uint16{AEADAES128GCM, AEADAES256GCM, AEADChaCha20Poly1305} {
+			suite, err := NewSuite(kemID, KDFHKDFSHA256, aeadID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			skR, _ := suite.Curve().GenerateKey(rand.Reader)
+			skS, _ := suite.Curve().GenerateKey(rand.Reader)
+			other, _ := suite.Curve().GenerateKey(rand.Reader)
+
+			enc, sender, err := suite.SetupAuthS(skR.PublicKey(), []byte("info"), skS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ct, err := sender.Seal(aad, msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recipient, err := suite.SetupAuthR(enc, skR, []byte("info"), skS.PublicKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			pt, err := recipient.Open(aad, ct)
+			if err != nil || !bytes.Equal(pt, msg) {
+				t.Errorf("FAIL - Auth mode round trip failed for %s: %v", suite, err)
+			}
+
+			// The wrong sender, base mode, the wrong info, or the wrong aad must all fail
+			wrongSender, _ := suite.SetupAuthR(enc, skR, []byte("info"), other.PublicKey())
+			base, _ := suite.SetupBaseR(enc, skR, []byte("info"))
+			wrongInfo, _ := suite.SetupAuthR(enc, skR, []byte("other"), skS.PublicKey())
+			for name, ctx := range map[string]*Context{"sender": wrongSender, "mode": base, "info": wrongInfo} {
+				if _, err = ctx.Open(aad, ct); err == nil {
+					t.Errorf("FAIL - Opened an auth mode message with the wrong %s for %s", name, suite)
+				}
+			}
+			retry, _ := suite.SetupAuthR(enc, skR, []byte("info"), skS.PublicKey())
+			if _, err = retry.Open([]byte("other"), ct); err == nil {
+				t.Errorf("FAIL - Opened an auth mode message with the wrong aad for %s", suite)
+			}
+		}
+	}
+}
+
+func TestContextMisuse(t *testing.T) {
+
+	suite, err := NewSuite(KEMX25519HKDFSHA256, KDFHKDFSHA256, AEADAES128GCM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	skR, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	enc, sender, err := suite.SetupBaseS(skR.PublicKey(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := suite.SetupBaseR(enc, skR, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Messages must be opened in order and the roles cannot be swapped
+	first, _ := sender.Seal(nil, []byte("first"))
+	second, _ := sender.Seal(nil, []byte("second"))
+	if _, err = recipient.Open(nil, second); err == nil {
+		t.Errorf("FAIL - Opened the second message first")
+	}
+	if pt, err := recipient.Open(nil, first); err != nil || string(pt) != "first" {
+		t.Errorf("FAIL - A failed Open advanced the sequence number")
+	}
+	if _, err = sender.Open(nil, first); err == nil {
+		t.Errorf("FAIL - A sender context opened a message")
+	}
+	if _, err = recipient.Seal(nil, first); err == nil {
+		t.Errorf("FAIL - A recipient context sealed a message")
+	}
+
+	// Keys on the wrong curve and malformed encapsulated keys are rejected
+	p256, _ := ecdh.P256().GenerateKey(rand.Reader)
+	if _, _, err = suite.SetupBaseS(p256.PublicKey(), nil); err == nil {
+		t.Errorf("FAIL - Encapsulated to a P-256 key with an X25519 suite")
+	}
+	if _, err = suite.SetupBaseR(enc[:31], skR, nil); err == nil {
+		t.Errorf("FAIL - Accepted a truncated encapsulated key")
+	}
+	if _, err = NewSuite(0x0021, KDFHKDFSHA256, AEADAES128GCM); err == nil {
+		t.Errorf("FAIL - Accepted the unsupported X448 KEM")
+	}
+}
@@ -0,0 +1,157 @@
+package hpke
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// dhKEM is a DHKEM (RFC 9180 sec. 4.1) over one of the crypto/ecdh curves
+type dhKEM struct {
+	id      uint16
+	curve   ecdh.Curve
+	hash    func() hash.Hash
+	nSecret int
+	nSk     int
+	bitmask byte
+}
+
+// kemByID returns the DHKEM for a KEM identifier (RFC 9180 sec. 7.1)
+func kemByID(id uint16) (*dhKEM, error) {
+
+	switch id {
+	case KEMP256HKDFSHA256:
+		return &dhKEM{id: id, curve: ecdh.P256(), hash: sha256.New, nSecret: 32, nSk: 32, bitmask: 0xff}, nil
+	case KEMP384HKDFSHA384:
+		return &dhKEM{id: id, curve: ecdh.P384(), hash: sha512.New384, nSecret: 48, nSk: 48, bitmask: 0xff}, nil
+	case KEMP521HKDFSHA512:
+		return &dhKEM{id: id, curve: ecdh.P521(), hash: sha512.New, nSecret: 64, nSk: 66, bitmask: 0x01}, nil
+	case KEMX25519HKDFSHA256:
+		return &dhKEM{id: id, curve: ecdh.X25519(), hash: sha256.New, nSecret: 32, nSk: 32}, nil
+	}
+
+	return nil, fmt.Errorf("Error: Unsupported HPKE KEM 0x%04x", id)
+}
+
+// suiteID is the KEM suite_id: "KEM" || I2OSP(kem_id, 2)
+func (k *dhKEM) suiteID() []byte {
+	return []byte{'K', 'E', 'M', byte(k.id >> 8), byte(k.id)}
+}
+
+/*
+*  deriveKeyPair deterministically derives a key pair from ikm (RFC 9180 sec. 7.1.3). NIST
+*  curve scalars are found by rejection sampling; ecdh rejects zero and scalars >= order.
+ */
+func (k *dhKEM) deriveKeyPair(ikm []byte) (*ecdh.PrivateKey, error) {
+
+	suiteID := k.suiteID()
+	dkpPRK := labeledExtract(k.hash, suiteID, nil, "dkp_prk", ikm)
+	if k.curve == ecdh.X25519() {
+		sk, err := labeledExpand(k.hash, suiteID, dkpPRK, "sk", nil, k.nSk)
+		if err != nil {
+			return nil, err
+		}
+		return k.curve.NewPrivateKey(sk)
+	}
+	for counter := 0; counter < 256; counter++ {
+		candidate, err := labeledExpand(k.hash, suiteID, dkpPRK, "candidate", []byte{byte(counter)}, k.nSk)
+		if err != nil {
+			return nil, err
+		}
+		candidate[0] &= k.bitmask
+		sk, err := k.curve.NewPrivateKey(candidate)
+		if err == nil {
+			return sk, nil
+		}
+	}
+
+	return nil, errors.New("Error: HPKE DeriveKeyPair found no valid scalar")
+}
+
+// extractAndExpand turns the DH output(s) into the KEM shared secret
+func (k *dhKEM) extractAndExpand(dh, kemContext []byte) ([]byte, error) {
+
+	suiteID := k.suiteID()
+	eaePRK := labeledExtract(k.hash, suiteID, nil, "eae_prk", dh)
+
+	return labeledExpand(k.hash, suiteID, eaePRK, "shared_secret", kemContext, k.nSecret)
+}
+
+/*
+*  encap is Encap (skS == nil) or AuthEncap (skS != nil). A random ephemeral key is used
+*  unless skE is given; the tests pass skE to reproduce the RFC 9180 vectors.
+ */
+func (k *dhKEM) encap(pkR *ecdh.PublicKey, skS, skE *ecdh.PrivateKey) ([]byte, []byte, error) {
+
+	var err error
+
+	if pkR == nil || pkR.Curve() != k.curve {
+		return nil, nil, errors.New("Error: The HPKE recipient key is not on the curve of the KEM")
+	}
+	if skS != nil && skS.Curve() != k.curve {
+		return nil, nil, errors.New("Error: The HPKE sender key is not on the curve of the KEM")
+	}
+	if skE == nil {
+		skE, err = k.curve.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	dh, err := skE.ECDH(pkR)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc := skE.PublicKey().Bytes()
+	kemContext := append(append([]byte{}, enc...), pkR.Bytes()...)
+	if skS != nil {
+		dhS, err := skS.ECDH(pkR)
+		if err != nil {
+			return nil, nil, err
+		}
+		dh = append(dh, dhS...)
+		kemContext = append(kemContext, skS.PublicKey().Bytes()...)
+	}
+
+	shared, err := k.extractAndExpand(dh, kemContext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return shared, enc, nil
+}
+
+// decap is Decap (pkS == nil) or AuthDecap (pkS != nil)
+func (k *dhKEM) decap(enc []byte, skR *ecdh.PrivateKey, pkS *ecdh.PublicKey) ([]byte, error) {
+
+	if skR == nil || skR.Curve() != k.curve {
+		return nil, errors.New("Error: The HPKE recipient key is not on the curve of the KEM")
+	}
+	if pkS != nil && pkS.Curve() != k.curve {
+		return nil, errors.New("Error: The HPKE sender key is not on the curve of the KEM")
+	}
+	pkE, err := k.curve.NewPublicKey(enc)
+	if err != nil {
+		return nil, errors.New("Error: The HPKE encapsulated key is not a valid public key")
+	}
+
+	dh, err := skR.ECDH(pkE)
+	if err != nil {
+		return nil, err
+	}
+	kemContext := append(append([]byte{}, enc...), skR.PublicKey().Bytes()...)
+	if pkS != nil {
+		dhS, err := skR.ECDH(pkS)
+		if err != nil {
+			return nil, err
+		}
+		dh = append(dh, dhS...)
+		kemContext = append(kemContext, pkS.Bytes()...)
+	}
+
+	return k.extractAndExpand(dh, kemContext)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"foil/commands"
+	"foil/helpers"
 )
 
 //
@@ -12,7 +13,7 @@ func main() {
 
 	//
 	if err := commands.FoilCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(helpers.Messages, err)
 		os.Exit(1)
 	}
 }